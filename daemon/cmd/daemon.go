@@ -341,7 +341,12 @@ func NewDaemon(ctx context.Context, epMgr *endpointmanager.EndpointManager, dp d
 	d.endpointManager = epMgr
 	d.endpointManager.InitMetrics()
 
-	d.redirectPolicyManager = redirectpolicy.NewRedirectPolicyManager(d.svc)
+	if k8s.IsEnabled() {
+		d.redirectPolicyManager = redirectpolicy.NewRedirectPolicyManager(d.svc,
+			redirectpolicy.NewK8sEventRecorder(k8s.Client().CoreV1(), "cilium-agent"))
+	} else {
+		d.redirectPolicyManager = redirectpolicy.NewRedirectPolicyManager(d.svc, nil)
+	}
 
 	d.k8sWatcher = watchers.NewK8sWatcher(
 		d.endpointManager,
@@ -374,6 +374,19 @@ func (d *Daemon) initRestore(restoredEndpoints *endpointRestoreState) chan struc
 						log.WithError(err).Fatal("timeout while waiting for all clusters to be locally synchronized")
 					}
 				}
+				// Wait for the pod and local redirect policy caches to be
+				// synchronized before declaring the sync with k8s finished.
+				// Local redirect policies are reconciled against the pod
+				// cache, so letting SyncWithK8sFinished() run ahead of that
+				// reconciliation would delete the restored local-redirect
+				// service entries before the redirect policy manager had a
+				// chance to confirm they're still needed, reintroducing the
+				// startup gap this restore/reconcile handshake avoids.
+				d.k8sWatcher.WaitForCacheSync(
+					watchers.K8sAPIGroupPodV1Core,
+					watchers.K8sAPIGroupCiliumLocalRedirectPolicyV2,
+				)
+
 				// Start controller which removes any leftover Kubernetes
 				// services that may have been deleted while Cilium was not
 				// running. Once this controller succeeds, because it has no
@@ -82,7 +82,7 @@ func (c *Configuration) NodeObserver() store.Observer {
 		return c.nodeObserver
 	}
 
-	return nodeStore.NewNodeObserver(c.NodeManager)
+	return nodeStore.NewNodeObserver(c.NodeManager, c.NodeKeyCreator)
 }
 
 // ClusterMesh is a cache of multiple remote clusters
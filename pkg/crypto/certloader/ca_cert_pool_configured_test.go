@@ -0,0 +1,98 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCACertPoolConfiguredCAOnly verifies that a CA-only watcher reports
+// CACertPoolConfigured true while KeypairConfigured stays false.
+func TestCACertPoolConfiguredCAOnly(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.crt")
+	caKey := filepath.Join(dir, "ca.key")
+	writeSelfSignedCert(t, caFile, caKey, 1)
+
+	w, err := NewWatcher(Config{CAFiles: []string{caFile}})
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	if !w.CACertPoolConfigured() {
+		t.Error("expected CACertPoolConfigured to be true with a loaded CA file")
+	}
+	if w.KeypairConfigured() {
+		t.Error("expected KeypairConfigured to be false with no keypair configured")
+	}
+}
+
+// TestCACertPoolConfiguredUnconfigured verifies that CACertPoolConfigured is
+// false when no CA files are configured at all.
+func TestCACertPoolConfiguredUnconfigured(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	w, err := NewWatcher(Config{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	if w.CACertPoolConfigured() {
+		t.Error("expected CACertPoolConfigured to be false with no CA files configured")
+	}
+}
+
+// TestCACertPoolConfiguredOnWatchedClientConfig verifies that
+// CACertPoolConfigured is reachable through a WatchedClientConfig, which
+// embeds Watcher.
+func TestCACertPoolConfiguredOnWatchedClientConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.crt")
+	caKey := filepath.Join(dir, "ca.key")
+	writeSelfSignedCert(t, caFile, caKey, 1)
+
+	wcc, err := NewWatchedClientConfig(Config{CAFiles: []string{caFile}})
+	if err != nil {
+		t.Fatalf("failed to create watched client config: %v", err)
+	}
+	defer wcc.Stop()
+
+	if !wcc.CACertPoolConfigured() {
+		t.Error("expected CACertPoolConfigured to be true on a WatchedClientConfig with CA files configured")
+	}
+}
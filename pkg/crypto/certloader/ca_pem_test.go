@@ -0,0 +1,85 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWatcherCAPEMRoundTrips verifies that CAPEM returns the concatenated PEM
+// of every configured CA file, and that it parses back to a pool trusting
+// the same certificates as CertPool.
+func TestWatcherCAPEMRoundTrips(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca1File := filepath.Join(dir, "ca1.crt")
+	ca1Key := filepath.Join(dir, "ca1.key")
+	writeSelfSignedCert(t, ca1File, ca1Key, 1)
+
+	ca2File := filepath.Join(dir, "ca2.crt")
+	ca2Key := filepath.Join(dir, "ca2.key")
+	writeSelfSignedCert(t, ca2File, ca2Key, 2)
+
+	w, err := NewWatcher(Config{CAFiles: []string{ca1File, ca2File}})
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	pem := w.CAPEM()
+	if len(pem) == 0 {
+		t.Fatal("expected non-empty CA PEM")
+	}
+
+	parsed := x509.NewCertPool()
+	if !parsed.AppendCertsFromPEM(pem) {
+		t.Fatal("CAPEM did not parse back into a cert pool")
+	}
+	if !parsed.Equal(w.CertPool()) {
+		t.Fatal("expected CAPEM to parse back to the same trust set as CertPool")
+	}
+}
+
+// TestWatcherCAPEMUnconfigured verifies that CAPEM returns nil when no CA
+// files are configured.
+func TestWatcherCAPEMUnconfigured(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	w, err := NewWatcher(Config{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	if pem := w.CAPEM(); pem != nil {
+		t.Fatalf("expected nil CA PEM when no CA files are configured, got %d bytes", len(pem))
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWatcherCARefreshIntervalPicksUpOutOfBandChange verifies that a Watcher
+// configured with WithCARefreshInterval notices a CA file changed on disk
+// even without relying on fsnotify, by polling for a rotation notification
+// rather than an fsnotify event.
+func TestWatcherCARefreshIntervalPicksUpOutOfBandChange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.crt")
+	caKeyFile := filepath.Join(dir, "ca.key")
+	writeSelfSignedCert(t, caFile, caKeyFile, 1)
+
+	w, err := NewWatcher(Config{CAFiles: []string{caFile}}, WithCARefreshInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	initialPEM := w.CAPEM()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rotations := w.Subscribe(ctx)
+
+	// Deregister the watcher's directories from fsnotify, so that the
+	// rewrite below can only be noticed by the CA refresh timer.
+	w.pool.removeWatcher(w, w.watchedDirs)
+
+	// Rewrite the CA file out from under the watcher.
+	writeSelfSignedCert(t, caFile, caKeyFile, 2)
+
+	select {
+	case <-rotations:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the timer-driven CA refresh to notice the out-of-band change")
+	}
+
+	if string(w.CAPEM()) == string(initialPEM) {
+		t.Fatal("expected CAPEM to reflect the out-of-band CA change")
+	}
+}
+
+// TestWatcherCARefreshIntervalSkipsUnchangedContent verifies that refreshing
+// unchanged CA content doesn't trigger a rotation notification.
+func TestWatcherCARefreshIntervalSkipsUnchangedContent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.crt")
+	caKeyFile := filepath.Join(dir, "ca.key")
+	writeSelfSignedCert(t, caFile, caKeyFile, 1)
+
+	w, err := NewWatcher(Config{CAFiles: []string{caFile}})
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rotations := w.Subscribe(ctx)
+
+	for i := 0; i < 5; i++ {
+		if err := w.refreshCAFiles(); err != nil {
+			t.Fatalf("refreshCAFiles failed: %v", err)
+		}
+	}
+
+	select {
+	case <-rotations:
+		t.Fatal("expected no rotation notification when refreshed CA content is unchanged")
+	default:
+	}
+}
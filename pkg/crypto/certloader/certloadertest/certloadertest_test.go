@@ -0,0 +1,115 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloadertest
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"testing"
+)
+
+// TestNewCAIsSelfSignedAndCA verifies that NewCA produces a self-signed
+// certificate with the CA basic constraint set.
+func TestNewCAIsSelfSignedAndCA(t *testing.T) {
+	ca, err := NewCA()
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(mustDecodePEM(t, ca.PEM()))
+	if err != nil {
+		t.Fatalf("failed to parse CA certificate: %v", err)
+	}
+	if !cert.IsCA {
+		t.Fatal("expected the generated certificate to be a CA")
+	}
+	if err := cert.CheckSignatureFrom(cert); err != nil {
+		t.Fatalf("expected the CA certificate to be self-signed: %v", err)
+	}
+}
+
+// TestIssueLeafChainsToCA verifies that a leaf issued by a CA verifies
+// against a pool containing only that CA.
+func TestIssueLeafChainsToCA(t *testing.T) {
+	ca, err := NewCA()
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+
+	certPEM, keyPEM, err := ca.IssueLeaf(1, []string{"example.com"}, []net.IP{net.ParseIP("127.0.0.1")})
+	if err != nil {
+		t.Fatalf("failed to issue leaf: %v", err)
+	}
+
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		t.Fatalf("issued leaf cert/key do not form a valid pair: %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(mustDecodePEM(t, certPEM))
+	if err != nil {
+		t.Fatalf("failed to parse leaf certificate: %v", err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca.PEM()) {
+		t.Fatal("failed to add CA to pool")
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny}}); err != nil {
+		t.Fatalf("leaf did not verify against its issuing CA: %v", err)
+	}
+}
+
+// TestIssueLeafRotation verifies that issuing two leaves with different
+// serials from the same CA produces two distinct, both-valid certificates,
+// as a rotation would.
+func TestIssueLeafRotation(t *testing.T) {
+	ca, err := NewCA()
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+
+	firstPEM, _, err := ca.IssueLeaf(1, []string{"example.com"}, nil)
+	if err != nil {
+		t.Fatalf("failed to issue first leaf: %v", err)
+	}
+	secondPEM, _, err := ca.IssueLeaf(2, []string{"example.com"}, nil)
+	if err != nil {
+		t.Fatalf("failed to issue second leaf: %v", err)
+	}
+
+	first, err := x509.ParseCertificate(mustDecodePEM(t, firstPEM))
+	if err != nil {
+		t.Fatalf("failed to parse first leaf: %v", err)
+	}
+	second, err := x509.ParseCertificate(mustDecodePEM(t, secondPEM))
+	if err != nil {
+		t.Fatalf("failed to parse second leaf: %v", err)
+	}
+
+	if first.SerialNumber.Cmp(second.SerialNumber) == 0 {
+		t.Fatal("expected the two issued leaves to have different serial numbers")
+	}
+}
+
+func mustDecodePEM(t *testing.T, certPEM []byte) []byte {
+	t.Helper()
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		t.Fatal("failed to decode PEM block")
+	}
+	return block.Bytes
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cilium/cilium/pkg/crypto/certloader/certloadertest"
+)
+
+// TestWatcherWithCAChainedLeafRotation verifies that a Watcher configured
+// with an ephemeral CA from certloadertest loads a leaf that chains to it,
+// and picks up a rotated leaf signed by the same CA.
+func TestWatcherWithCAChainedLeafRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca, err := certloadertest.NewCA()
+	if err != nil {
+		t.Fatalf("failed to create CA: %v", err)
+	}
+	caFile := filepath.Join(dir, "ca.crt")
+	if err := ca.WriteFile(caFile); err != nil {
+		t.Fatalf("failed to write CA file: %v", err)
+	}
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	if err := ca.WriteLeafFiles(certFile, keyFile, 1, []string{"example.com"}, []net.IP{net.ParseIP("127.0.0.1")}); err != nil {
+		t.Fatalf("failed to write leaf files: %v", err)
+	}
+
+	w, err := NewWatcher(Config{CAFiles: []string{caFile}, CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	pair, err := w.KeyPair()
+	if err != nil {
+		t.Fatalf("failed to get keypair: %v", err)
+	}
+	pool := w.CertPool()
+	if pool == nil {
+		t.Fatal("expected a non-nil CA pool")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rotations := w.Subscribe(ctx)
+
+	if err := ca.WriteLeafFiles(certFile, keyFile, 2, []string{"example.com"}, []net.IP{net.ParseIP("127.0.0.1")}); err != nil {
+		t.Fatalf("failed to write rotated leaf files: %v", err)
+	}
+
+	select {
+	case <-rotations:
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the rotation notification")
+	}
+
+	rotatedPair, err := w.KeyPair()
+	if err != nil {
+		t.Fatalf("failed to get rotated keypair: %v", err)
+	}
+	if rotatedPair == pair {
+		t.Fatal("expected the keypair to have changed after rotation")
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// TLSParams carries stdlib tls.Config knobs that aren't backed by watched
+// files, layered onto a WatchedClientConfig's produced configuration. The
+// zero value preserves the previous, inherit-from-base behavior for every
+// field.
+type TLSParams struct {
+	// MinVersion, if non-zero, overrides the minimum TLS version the
+	// produced configuration will negotiate, e.g. tls.VersionTLS12.
+	MinVersion uint16
+	// CipherSuites, if non-empty, restricts the produced configuration to
+	// this set of cipher suites. Ignored for TLS 1.3, which always uses its
+	// own fixed suite set.
+	CipherSuites []uint16
+}
+
+// WatchedClientConfig produces stdlib TLS configuration backed by a
+// Watcher, for use both by TLS clients presenting a mutual-TLS client
+// certificate and by stdlib TLS servers that need to serve rotated
+// certificates without restarting.
+type WatchedClientConfig struct {
+	*Watcher
+
+	// tofu, if set, pins the peer certificate on first use instead of
+	// verifying it against the CA pool.
+	tofu *TOFUVerifier
+
+	// spiffe, if set, additionally requires the peer certificate to carry a
+	// URI SAN matching an expected SPIFFE ID, layered on top of normal chain
+	// verification.
+	spiffe *SPIFFEVerifier
+
+	// tlsParams carries the MinVersion/CipherSuites applied to every
+	// *tls.Config this produces.
+	tlsParams TLSParams
+
+	// sniKeypairs, if set by WithSNIKeypairs, maps a server name to the
+	// Config used to load that server's dedicated client keypair.
+	sniKeypairs map[string]Config
+
+	// sniWatchers holds one Watcher per sniKeypairs entry, used to select a
+	// keypair by CertificateRequestInfo.ServerName at handshake time.
+	sniWatchers map[string]*Watcher
+}
+
+// ClientConfigOption customizes a WatchedClientConfig at construction time.
+type ClientConfigOption func(*WatchedClientConfig)
+
+// WithTLSParams overrides the minimum TLS version and/or cipher suites of
+// every *tls.Config the WatchedClientConfig produces. Omitting it inherits
+// stdlib defaults.
+func WithTLSParams(params TLSParams) ClientConfigOption {
+	return func(c *WatchedClientConfig) {
+		c.tlsParams = params
+	}
+}
+
+// WithSPIFFEVerification requires every peer certificate to carry a URI SAN
+// matching expectedID, in addition to passing normal chain verification. See
+// SPIFFEVerifier for the exact-match vs. trust-domain-prefix semantics of
+// expectedID.
+func WithSPIFFEVerification(expectedID string) ClientConfigOption {
+	return func(c *WatchedClientConfig) {
+		c.spiffe = NewSPIFFEVerifier(expectedID)
+	}
+}
+
+// WithSNIKeypairs registers an additional watched keypair per server name,
+// presented instead of the WatchedClientConfig's own keypair by
+// TLSConfigForServerName when dialing a server whose name has a matching
+// entry. Configuring none of these preserves the previous single-keypair
+// behavior.
+func WithSNIKeypairs(keypairs map[string]Config) ClientConfigOption {
+	return func(c *WatchedClientConfig) {
+		c.sniKeypairs = keypairs
+	}
+}
+
+// NewWatchedClientConfig creates a WatchedClientConfig backed by the given
+// file paths, customized by opts.
+func NewWatchedClientConfig(config Config, opts ...ClientConfigOption) (*WatchedClientConfig, error) {
+	if config.TOFUPinFile != "" && len(config.CAFiles) > 0 {
+		return nil, fmt.Errorf("certloader: TOFUPinFile and CAFiles are mutually exclusive")
+	}
+
+	w, err := NewWatcher(config)
+	if err != nil {
+		return nil, err
+	}
+
+	wcc := &WatchedClientConfig{Watcher: w}
+	if config.TOFUPinFile != "" {
+		wcc.tofu = NewTOFUVerifier(config.TOFUPinFile)
+	}
+	for _, opt := range opts {
+		opt(wcc)
+	}
+
+	if len(wcc.sniKeypairs) > 0 {
+		wcc.sniWatchers = make(map[string]*Watcher, len(wcc.sniKeypairs))
+		for serverName, keypairConfig := range wcc.sniKeypairs {
+			sw, err := NewWatcher(keypairConfig)
+			if err != nil {
+				wcc.Stop()
+				return nil, fmt.Errorf("certloader: failed to load SNI keypair for server name %s: %w", serverName, err)
+			}
+			wcc.sniWatchers[serverName] = sw
+		}
+	}
+
+	return wcc, nil
+}
+
+// keyPairFor selects the keypair to present for a given dial server name,
+// preferring the Watcher registered for it via WithSNIKeypairs and falling
+// back to the WatchedClientConfig's own keypair when no SNI-specific entry
+// matches, or none were configured.
+func (c *WatchedClientConfig) keyPairFor(serverName string) (*tls.Certificate, error) {
+	if sw, ok := c.sniWatchers[serverName]; ok {
+		return sw.KeyPair()
+	}
+	return c.KeyPair()
+}
+
+// Stop stops watching the underlying files for changes, including every
+// per-server-name keypair registered through WithSNIKeypairs.
+func (c *WatchedClientConfig) Stop() {
+	for _, sw := range c.sniWatchers {
+		sw.Stop()
+	}
+	c.Watcher.Stop()
+}
+
+// TLSConfig returns a *tls.Config whose GetCertificate and
+// GetClientCertificate callbacks always resolve to the watcher's current
+// keypair, and whose GetConfigForClient hook refreshes the CA pool on every
+// handshake. Stdlib consumers (http.Server, http.Transport, ...) can hold
+// on to the returned config indefinitely and will transparently pick up
+// rotated certificates, without needing to rebuild the server or client.
+func (c *WatchedClientConfig) TLSConfig() *tls.Config {
+	return c.TLSConfigForServerName("")
+}
+
+// TLSConfigForServerName is like TLSConfig, except that GetClientCertificate
+// presents the keypair registered for serverName via WithSNIKeypairs
+// instead of the WatchedClientConfig's own keypair, if one was registered.
+// Dialing a server name with no registered entry, or passing an empty
+// serverName, behaves exactly like TLSConfig.
+func (c *WatchedClientConfig) TLSConfigForServerName(serverName string) *tls.Config {
+	cfg := c.snapshot(serverName)
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		return c.snapshot(serverName), nil
+	}
+	return cfg
+}
+
+// snapshot returns a *tls.Config reflecting the watcher's state at the time
+// of the call, presenting the keypair registered for serverName if any.
+func (c *WatchedClientConfig) snapshot(serverName string) *tls.Config {
+	if c.tofu != nil {
+		cfg := tofuTLSConfig(c.tofu)
+		cfg.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return c.KeyPair()
+		}
+		cfg.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return c.keyPairFor(serverName)
+		}
+		c.applyTLSParams(cfg)
+		return cfg
+	}
+
+	pool := c.CertPool()
+	cfg := &tls.Config{
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return c.KeyPair()
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return c.keyPairFor(serverName)
+		},
+		RootCAs:   pool,
+		ClientCAs: pool,
+	}
+	if c.spiffe != nil {
+		cfg.VerifyPeerCertificate = c.spiffe.VerifyPeerCertificate
+	}
+	c.applyTLSParams(cfg)
+	return cfg
+}
+
+// applyTLSParams overrides cfg's MinVersion/CipherSuites with c.tlsParams,
+// leaving a field untouched when the corresponding tlsParams field is unset.
+func (c *WatchedClientConfig) applyTLSParams(cfg *tls.Config) {
+	if c.tlsParams.MinVersion != 0 {
+		cfg.MinVersion = c.tlsParams.MinVersion
+	}
+	if len(c.tlsParams.CipherSuites) > 0 {
+		cfg.CipherSuites = c.tlsParams.CipherSuites
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedCert generates a self-signed certificate/key pair with the
+// given serial number and writes it out as PEM to certFile/keyFile.
+func writeSelfSignedCert(t *testing.T, certFile, keyFile string, serial int64) {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "certloader-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	certOut, err := os.Create(certFile)
+	if err != nil {
+		t.Fatalf("failed to create cert file: %v", err)
+	}
+	defer certOut.Close()
+	if err := pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: der}); err != nil {
+		t.Fatalf("failed to encode certificate: %v", err)
+	}
+
+	keyBytes, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	keyOut, err := os.Create(keyFile)
+	if err != nil {
+		t.Fatalf("failed to create key file: %v", err)
+	}
+	defer keyOut.Close()
+	if err := pem.Encode(keyOut, &pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}); err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+}
+
+// serverCertSerial dials the given address over TLS and returns the serial
+// number of the certificate it was served.
+func serverCertSerial(t *testing.T, addr string) *big.Int {
+	t.Helper()
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("failed to dial %s: %v", addr, err)
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		t.Fatal("no peer certificates presented")
+	}
+	return certs[0].SerialNumber
+}
+
+func TestWatchedClientConfigRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	wcc, err := NewWatchedClientConfig(Config{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("failed to create watched client config: %v", err)
+	}
+	defer wcc.Stop()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", wcc.TLSConfig())
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if tlsConn, ok := conn.(*tls.Conn); ok {
+				tlsConn.Handshake()
+			}
+			conn.Close()
+		}
+	}()
+
+	serial := serverCertSerial(t, ln.Addr().String())
+	if serial.Int64() != 1 {
+		t.Fatalf("expected initial serial 1, got %v", serial)
+	}
+
+	// Rotate the cert on disk and give the fsnotify watcher time to react.
+	writeSelfSignedCert(t, certFile, keyFile, 2)
+	deadline := time.Now().Add(5 * time.Second)
+	var rotated bool
+	for time.Now().Before(deadline) {
+		if pair, err := wcc.KeyPair(); err == nil {
+			leaf, err := x509.ParseCertificate(pair.Certificate[0])
+			if err == nil && leaf.SerialNumber.Int64() == 2 {
+				rotated = true
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !rotated {
+		t.Fatal("watcher did not pick up the rotated certificate in time")
+	}
+
+	serial = serverCertSerial(t, ln.Addr().String())
+	if serial.Int64() != 2 {
+		t.Fatalf("expected rotated serial 2 on a new connection, got %v", serial)
+	}
+}
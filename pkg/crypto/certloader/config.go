@@ -0,0 +1,124 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// Config holds the file paths backing a Watcher: an optional certificate
+// and private key pair, and a set of CA certificate bundles to trust.
+type Config struct {
+	// CAFiles is a set of PEM-encoded CA certificate bundle files to trust.
+	CAFiles []string
+	// CertFile is a PEM-encoded certificate file for the identity served or
+	// presented by the resulting TLS configuration.
+	CertFile string
+	// KeyFile is the PEM-encoded private key matching CertFile.
+	KeyFile string
+	// RenewalMetadataFile is an optional path to a JSON file, typically
+	// projected by a sidecar such as cert-manager-csi, carrying a
+	// RenewalMetadata describing when the certificate is due for renewal.
+	// When set, the Watcher logs a warning once the renewal window is
+	// reached, independent of whether CertFile/KeyFile have actually been
+	// replaced yet.
+	RenewalMetadataFile string
+	// TOFUPinFile, if set, enables trust-on-first-use pinning of the peer
+	// certificate instead of verifying it against CAFiles: the first peer
+	// certificate observed is fingerprinted and persisted to this file, and
+	// every later connection is rejected unless it presents a matching
+	// certificate. This is meant for bootstrap scenarios that lack a
+	// pre-shared CA, such as a dev clustermesh, and must not be relied on in
+	// production. Mutually exclusive with CAFiles.
+	TOFUPinFile string
+}
+
+// Empty reports whether the configuration carries no certificate material
+// at all.
+func (c Config) Empty() bool {
+	return len(c.CAFiles) == 0 && c.CertFile == "" && c.KeyFile == ""
+}
+
+// HasKeyPair reports whether the configuration specifies a certificate and
+// private key pair.
+func (c Config) HasKeyPair() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// ValidateFiles loads and validates the CA bundle and certificate/key pair
+// described by caFiles, certFile and keyFile, without creating a Watcher or
+// starting a goroutine. It is meant for preflight checks in CLIs that want
+// to validate TLS configuration before committing to it.
+//
+// It reports an error if any file cannot be parsed, if certFile and keyFile
+// don't form a matching pair, or if certFile's chain does not build to one
+// of caFiles.
+func ValidateFiles(caFiles []string, certFile, keyFile string) error {
+	config := Config{CAFiles: caFiles, CertFile: certFile, KeyFile: keyFile}
+
+	var caPool *x509.CertPool
+	if len(config.CAFiles) > 0 {
+		caPool = x509.NewCertPool()
+		for _, f := range config.CAFiles {
+			pem, err := ioutil.ReadFile(f)
+			if err != nil {
+				return fmt.Errorf("failed to read CA file %s: %w", f, err)
+			}
+			if !caPool.AppendCertsFromPEM(pem) {
+				return fmt.Errorf("no certificates found in CA file %s", f)
+			}
+		}
+	}
+
+	if !config.HasKeyPair() {
+		return nil
+	}
+
+	pair, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load keypair: %w", err)
+	}
+
+	if caPool == nil {
+		return nil
+	}
+
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("failed to parse certificate %s: %w", certFile, err)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, der := range pair.Certificate[1:] {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("failed to parse intermediate certificate in %s: %w", certFile, err)
+		}
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         caPool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return fmt.Errorf("certificate %s does not chain to a trusted CA: %w", certFile, err)
+	}
+
+	return nil
+}
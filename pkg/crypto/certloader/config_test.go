@@ -0,0 +1,209 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCA generates a self-signed CA certificate and writes it out as PEM to
+// caFile, returning the CA certificate and key for signing leaf certificates.
+func writeCA(t *testing.T, caFile string, serial int64) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate CA key: %v", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "certloader-test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("failed to create CA certificate: %v", err)
+	}
+	caCert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse CA certificate: %v", err)
+	}
+
+	writePEM(t, caFile, "CERTIFICATE", der)
+
+	return caCert, priv
+}
+
+// writeSignedCert generates a certificate/key pair signed by the given CA
+// and writes it out as PEM to certFile/keyFile.
+func writeSignedCert(t *testing.T, certFile, keyFile string, ca *x509.Certificate, caKey *ecdsa.PrivateKey, serial int64) {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "certloader-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, ca, &priv.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	writePEM(t, certFile, "CERTIFICATE", der)
+
+	keyBytes, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		t.Fatalf("failed to marshal key: %v", err)
+	}
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyBytes)
+}
+
+func writePEM(t *testing.T, file, blockType string, bytes []byte) {
+	t.Helper()
+
+	out, err := os.Create(file)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", file, err)
+	}
+	defer out.Close()
+	if err := pem.Encode(out, &pem.Block{Type: blockType, Bytes: bytes}); err != nil {
+		t.Fatalf("failed to encode %s: %v", file, err)
+	}
+}
+
+func TestValidateFilesValidConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-validate-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.crt")
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	ca, caKey := writeCA(t, caFile, 1)
+	writeSignedCert(t, certFile, keyFile, ca, caKey, 2)
+
+	if err := ValidateFiles([]string{caFile}, certFile, keyFile); err != nil {
+		t.Fatalf("expected a valid configuration to pass validation, got: %v", err)
+	}
+}
+
+func TestValidateFilesNoKeyPair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-validate-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.crt")
+	writeCA(t, caFile, 1)
+
+	if err := ValidateFiles([]string{caFile}, "", ""); err != nil {
+		t.Fatalf("expected a CA-only configuration to pass validation, got: %v", err)
+	}
+}
+
+func TestValidateFilesBadPEM(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-validate-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.crt")
+	ca, caKey := writeCA(t, caFile, 1)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSignedCert(t, certFile, keyFile, ca, caKey, 2)
+
+	if err := ioutil.WriteFile(certFile, []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("failed to corrupt cert file: %v", err)
+	}
+
+	if err := ValidateFiles([]string{caFile}, certFile, keyFile); err == nil {
+		t.Fatal("expected validation to fail for a malformed certificate file")
+	}
+}
+
+func TestValidateFilesMismatchedPair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-validate-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.crt")
+	ca, caKey := writeCA(t, caFile, 1)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	otherKeyFile := filepath.Join(dir, "other.key")
+	writeSignedCert(t, certFile, keyFile, ca, caKey, 2)
+	writeSignedCert(t, filepath.Join(dir, "unused.crt"), otherKeyFile, ca, caKey, 3)
+
+	if err := ValidateFiles([]string{caFile}, certFile, otherKeyFile); err == nil {
+		t.Fatal("expected validation to fail for a certificate/key mismatch")
+	}
+}
+
+func TestValidateFilesBrokenChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-validate-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	untrustedCAFile := filepath.Join(dir, "untrusted-ca.crt")
+	untrustedCA, untrustedCAKey := writeCA(t, untrustedCAFile, 1)
+
+	trustedCAFile := filepath.Join(dir, "trusted-ca.crt")
+	writeCA(t, trustedCAFile, 2)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSignedCert(t, certFile, keyFile, untrustedCA, untrustedCAKey, 3)
+
+	if err := ValidateFiles([]string{trustedCAFile}, certFile, keyFile); err == nil {
+		t.Fatal("expected validation to fail for a certificate that doesn't chain to the given CA")
+	}
+}
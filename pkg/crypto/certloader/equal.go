@@ -0,0 +1,79 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"sort"
+)
+
+// Equal reports whether w and other currently have the same keypair and CA
+// pool loaded, by comparing the keypair's leaf fingerprint and the set of CA
+// subjects rather than deep-comparing the parsed certificates. Callers can
+// use this to decide whether a newly-loaded Watcher's material actually
+// differs from the one currently in use, e.g. to avoid resetting
+// connections on a no-op reload.
+func (w *Watcher) Equal(other *Watcher) bool {
+	if w == nil || other == nil {
+		return w == other
+	}
+
+	w.mutex.RLock()
+	wKeyPair, wCAPool := w.keyPair, w.caPool
+	w.mutex.RUnlock()
+
+	other.mutex.RLock()
+	otherKeyPair, otherCAPool := other.keyPair, other.caPool
+	other.mutex.RUnlock()
+
+	return keyPairFingerprint(wKeyPair) == keyPairFingerprint(otherKeyPair) &&
+		caPoolEqual(wCAPool, otherCAPool)
+}
+
+// keyPairFingerprint returns the SHA-256 digest of the keypair's leaf
+// certificate, or the zero value if no keypair is loaded.
+func keyPairFingerprint(keyPair *tls.Certificate) [sha256.Size]byte {
+	if keyPair == nil || len(keyPair.Certificate) == 0 {
+		return [sha256.Size]byte{}
+	}
+	return sha256.Sum256(keyPair.Certificate[0])
+}
+
+// caPoolEqual reports whether two CA pools contain the same set of subjects.
+func caPoolEqual(a, b *x509.CertPool) bool {
+	if (a == nil) != (b == nil) {
+		return false
+	}
+	if a == nil {
+		return true
+	}
+
+	aSubjects, bSubjects := a.Subjects(), b.Subjects()
+	if len(aSubjects) != len(bSubjects) {
+		return false
+	}
+
+	sort.Slice(aSubjects, func(i, j int) bool { return bytes.Compare(aSubjects[i], aSubjects[j]) < 0 })
+	sort.Slice(bSubjects, func(i, j int) bool { return bytes.Compare(bSubjects[i], bSubjects[j]) < 0 })
+	for i := range aSubjects {
+		if !bytes.Equal(aSubjects[i], bSubjects[i]) {
+			return false
+		}
+	}
+	return true
+}
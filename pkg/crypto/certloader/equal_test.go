@@ -0,0 +1,72 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// copyFile copies src to dst so that two Watchers can load byte-identical
+// certificate material without sharing a watched file.
+func copyFile(t *testing.T, src, dst string) {
+	t.Helper()
+	data, err := ioutil.ReadFile(src)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", src, err)
+	}
+	if err := ioutil.WriteFile(dst, data, 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", dst, err)
+	}
+}
+
+func TestWatcherEqual(t *testing.T) {
+	// w1 and w2 watch separate copies of the same keypair so that rotating
+	// w2's files doesn't also trigger a reload of w1 via fsnotify.
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	certFile1, keyFile1 := filepath.Join(dir1, "tls.crt"), filepath.Join(dir1, "tls.key")
+	certFile2, keyFile2 := filepath.Join(dir2, "tls.crt"), filepath.Join(dir2, "tls.key")
+	writeSelfSignedCert(t, certFile1, keyFile1, 1)
+	copyFile(t, certFile1, certFile2)
+	copyFile(t, keyFile1, keyFile2)
+
+	w1, err := NewWatcher(Config{CertFile: certFile1, KeyFile: keyFile1})
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	defer w1.Stop()
+
+	w2, err := NewWatcher(Config{CertFile: certFile2, KeyFile: keyFile2})
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	defer w2.Stop()
+
+	if !w1.Equal(w2) {
+		t.Error("expected two watchers loaded from the same keypair to be equal")
+	}
+
+	// Rotate w2's certificate to a new serial and reload it; w1 still holds
+	// the original material.
+	writeSelfSignedCert(t, certFile2, keyFile2, 2)
+	if err := w2.reload(false); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+
+	if w1.Equal(w2) {
+		t.Error("expected watchers to differ after one of them rotated its keypair")
+	}
+}
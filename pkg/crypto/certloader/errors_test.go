@@ -0,0 +1,74 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestNewWatcherMissingKeypairReturnsErrNoKeypair verifies that NewWatcher
+// distinguishes a configured-but-missing keypair from a missing CA file.
+func TestNewWatcherMissingKeypairReturnsErrNoKeypair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = NewWatcher(Config{
+		CertFile: filepath.Join(dir, "tls.crt"),
+		KeyFile:  filepath.Join(dir, "tls.key"),
+	})
+	if !errors.Is(err, ErrNoKeypair) {
+		t.Fatalf("expected ErrNoKeypair, got: %v", err)
+	}
+	if errors.Is(err, ErrNoCAFiles) {
+		t.Fatalf("expected a missing keypair not to also match ErrNoCAFiles, got: %v", err)
+	}
+}
+
+// TestNewWatcherMissingCAFileReturnsErrNoCAFiles verifies that NewWatcher
+// distinguishes a configured-but-missing CA file from a missing keypair.
+func TestNewWatcherMissingCAFileReturnsErrNoCAFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, err = NewWatcher(Config{CAFiles: []string{filepath.Join(dir, "ca.crt")}})
+	if !errors.Is(err, ErrNoCAFiles) {
+		t.Fatalf("expected ErrNoCAFiles, got: %v", err)
+	}
+	if errors.Is(err, ErrNoKeypair) {
+		t.Fatalf("expected a missing CA file not to also match ErrNoKeypair, got: %v", err)
+	}
+}
+
+// TestNewWatcherMissingFilesDoesNotAffectUnconfiguredMaterial verifies that
+// an empty Config, which configures neither a keypair nor CA files, still
+// constructs successfully: the new typed errors only apply when the
+// corresponding material was actually configured.
+func TestNewWatcherMissingFilesDoesNotAffectUnconfiguredMaterial(t *testing.T) {
+	w, err := NewWatcher(Config{})
+	if err != nil {
+		t.Fatalf("expected an empty Config to construct successfully, got: %v", err)
+	}
+	defer w.Stop()
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+)
+
+// tlsFeatureExtensionOID identifies the RFC 7633 TLS Feature ("must-staple")
+// certificate extension.
+var tlsFeatureExtensionOID = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}
+
+// statusRequestTLSFeature is the TLSFeature value (RFC 6066's
+// status_request extension type) that marks a certificate as requiring
+// OCSP stapling.
+const statusRequestTLSFeature = 5
+
+// ocspResponse is the subset of RFC 6960's OCSPResponse ASN.1 structure
+// needed to confirm that a stapled response is well-formed and reports a
+// successful lookup. It deliberately doesn't parse into ResponseBytes: this
+// package has no vendored OCSP response/signature verification library, so
+// verifyOCSPMustStaple can only confirm a syntactically valid, successful
+// response was stapled, not that it actually asserts the leaf is Good.
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+// ocspResponseStatusSuccessful is RFC 6960's OCSPResponseStatus value
+// indicating the responder was able to process the request.
+const ocspResponseStatusSuccessful = 0
+
+// certRequiresOCSPStaple reports whether cert carries the must-staple TLS
+// Feature extension requesting the status_request extension.
+func certRequiresOCSPStaple(cert *x509.Certificate) bool {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(tlsFeatureExtensionOID) {
+			continue
+		}
+		var features []int
+		if _, err := asn1.Unmarshal(ext.Value, &features); err != nil {
+			continue
+		}
+		for _, f := range features {
+			if f == statusRequestTLSFeature {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verifyOCSPMustStaple implements tls.Config.VerifyConnection, rejecting
+// the handshake if the peer's leaf certificate requires OCSP stapling via
+// the must-staple extension but didn't present a well-formed, successful
+// stapled OCSP response.
+func verifyOCSPMustStaple(cs tls.ConnectionState) error {
+	if len(cs.PeerCertificates) == 0 {
+		return nil
+	}
+
+	leaf := cs.PeerCertificates[0]
+	if !certRequiresOCSPStaple(leaf) {
+		return nil
+	}
+
+	if len(cs.OCSPResponse) == 0 {
+		return fmt.Errorf("certloader: peer certificate requires OCSP stapling (must-staple) but presented no stapled response")
+	}
+
+	var resp ocspResponse
+	if _, err := asn1.Unmarshal(cs.OCSPResponse, &resp); err != nil {
+		return fmt.Errorf("certloader: peer's stapled OCSP response is malformed: %w", err)
+	}
+	if resp.Status != ocspResponseStatusSuccessful {
+		return fmt.Errorf("certloader: peer's stapled OCSP response reported non-successful status %d", resp.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// mustStapleCert generates a self-signed certificate, optionally carrying
+// the must-staple TLS Feature extension.
+func mustStapleCert(t *testing.T, mustStaple bool) *x509.Certificate {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "certloader-ocsp-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	if mustStaple {
+		tlsFeature, err := asn1.Marshal([]int{statusRequestTLSFeature})
+		if err != nil {
+			t.Fatalf("failed to marshal TLS Feature extension: %v", err)
+		}
+		template.ExtraExtensions = append(template.ExtraExtensions, pkix.Extension{
+			Id:    tlsFeatureExtensionOID,
+			Value: tlsFeature,
+		})
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return cert
+}
+
+// marshalOCSPResponse returns a minimal well-formed OCSPResponse carrying
+// the given status.
+func marshalOCSPResponse(t *testing.T, status asn1.Enumerated) []byte {
+	t.Helper()
+	der, err := asn1.Marshal(ocspResponse{Status: status})
+	if err != nil {
+		t.Fatalf("failed to marshal OCSP response: %v", err)
+	}
+	return der
+}
+
+func TestCertRequiresOCSPStaple(t *testing.T) {
+	if !certRequiresOCSPStaple(mustStapleCert(t, true)) {
+		t.Error("expected a certificate carrying the must-staple extension to require stapling")
+	}
+	if certRequiresOCSPStaple(mustStapleCert(t, false)) {
+		t.Error("expected a certificate without the must-staple extension not to require stapling")
+	}
+}
+
+func TestVerifyOCSPMustStapleNoPeerCertificate(t *testing.T) {
+	if err := verifyOCSPMustStaple(tls.ConnectionState{}); err != nil {
+		t.Errorf("expected no error with no peer certificate presented, got: %v", err)
+	}
+}
+
+func TestVerifyOCSPMustStapleNotRequested(t *testing.T) {
+	cs := tls.ConnectionState{PeerCertificates: []*x509.Certificate{mustStapleCert(t, false)}}
+	if err := verifyOCSPMustStaple(cs); err != nil {
+		t.Errorf("expected no error for a certificate that doesn't request stapling, got: %v", err)
+	}
+}
+
+func TestVerifyOCSPMustStapleMissingResponse(t *testing.T) {
+	cs := tls.ConnectionState{PeerCertificates: []*x509.Certificate{mustStapleCert(t, true)}}
+	if err := verifyOCSPMustStaple(cs); err == nil {
+		t.Error("expected an error when a must-staple certificate has no stapled response")
+	}
+}
+
+func TestVerifyOCSPMustStapleSuccessfulResponse(t *testing.T) {
+	cs := tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{mustStapleCert(t, true)},
+		OCSPResponse:     marshalOCSPResponse(t, ocspResponseStatusSuccessful),
+	}
+	if err := verifyOCSPMustStaple(cs); err != nil {
+		t.Errorf("expected a well-formed, successful stapled response to be accepted, got: %v", err)
+	}
+}
+
+func TestVerifyOCSPMustStapleNonSuccessfulResponse(t *testing.T) {
+	cs := tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{mustStapleCert(t, true)},
+		OCSPResponse:     marshalOCSPResponse(t, 1), // malformedRequest
+	}
+	if err := verifyOCSPMustStaple(cs); err == nil {
+		t.Error("expected a non-successful stapled response to be rejected")
+	}
+}
+
+func TestVerifyOCSPMustStapleMalformedResponse(t *testing.T) {
+	cs := tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{mustStapleCert(t, true)},
+		OCSPResponse:     []byte("not an OCSP response"),
+	}
+	if err := verifyOCSPMustStaple(cs); err == nil {
+		t.Error("expected a malformed stapled response to be rejected")
+	}
+}
+
+// TestWithOCSPMustStapleVerificationWiresVerifyConnection verifies that
+// WithOCSPMustStapleVerification installs VerifyConnection on the produced
+// *tls.Config, and that omitting it leaves VerifyConnection unset.
+func TestWithOCSPMustStapleVerificationWiresVerifyConnection(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	wsc, err := NewWatchedServerConfig(Config{CertFile: certFile, KeyFile: keyFile}, WithOCSPMustStapleVerification())
+	if err != nil {
+		t.Fatalf("failed to create watched server config: %v", err)
+	}
+	defer wsc.Stop()
+
+	if wsc.ServerConfig(nil).VerifyConnection == nil {
+		t.Error("expected VerifyConnection to be set when WithOCSPMustStapleVerification is passed")
+	}
+
+	plainWsc, err := NewWatchedServerConfig(Config{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("failed to create watched server config: %v", err)
+	}
+	defer plainWsc.Stop()
+
+	if plainWsc.ServerConfig(nil).VerifyConnection != nil {
+		t.Error("expected VerifyConnection to stay unset without the option")
+	}
+}
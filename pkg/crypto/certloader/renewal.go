@@ -0,0 +1,58 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+// RenewalMetadata mirrors the subset of cert-manager's Certificate status
+// that's useful for warning about an upcoming renewal: the certificate's
+// expiry, and the start of the window in which cert-manager intends to
+// renew it. A sidecar that projects this status as JSON alongside the
+// certificate and key files lets a Watcher warn ahead of expiry independent
+// of what's actually on disk, since the metadata is refreshed as soon as
+// cert-manager decides to renew, not only once the new certificate
+// material lands.
+type RenewalMetadata struct {
+	// NotAfter is the expiry of the currently issued certificate.
+	NotAfter time.Time `json:"notAfter"`
+	// RenewBefore is the start of the renewal window: once reached, the
+	// certificate is due for renewal even though it hasn't expired yet.
+	RenewBefore time.Time `json:"renewBefore"`
+}
+
+// readRenewalMetadata reads and parses the renewal metadata file at path.
+func readRenewalMetadata(path string) (*RenewalMetadata, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read renewal metadata file %s: %w", path, err)
+	}
+	var m RenewalMetadata
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse renewal metadata file %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// inRenewalWindow reports whether now falls within the metadata's renewal
+// window, i.e. cert-manager intends to renew the certificate but it hasn't
+// expired yet.
+func (m *RenewalMetadata) inRenewalWindow(now time.Time) bool {
+	return m != nil && !m.RenewBefore.IsZero() && !now.Before(m.RenewBefore) && now.Before(m.NotAfter)
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeRenewalMetadata(t *testing.T, path string, m RenewalMetadata) {
+	t.Helper()
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("failed to marshal renewal metadata: %v", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write renewal metadata file: %v", err)
+	}
+}
+
+func TestWatcherRenewalWindowWarning(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	metadataFile := filepath.Join(dir, "renewal.json")
+
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+	writeRenewalMetadata(t, metadataFile, RenewalMetadata{
+		NotAfter:    time.Now().Add(time.Hour),
+		RenewBefore: time.Now().Add(-time.Minute),
+	})
+
+	w, err := NewWatcher(Config{
+		CertFile:            certFile,
+		KeyFile:             keyFile,
+		RenewalMetadataFile: metadataFile,
+	})
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	defer w.Stop()
+
+	if !w.InRenewalWindow() {
+		t.Error("expected the watcher to report an imminent renewal window")
+	}
+}
+
+func TestWatcherRenewalWindowNotReached(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	metadataFile := filepath.Join(dir, "renewal.json")
+
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+	writeRenewalMetadata(t, metadataFile, RenewalMetadata{
+		NotAfter:    time.Now().Add(24 * time.Hour),
+		RenewBefore: time.Now().Add(23 * time.Hour),
+	})
+
+	w, err := NewWatcher(Config{
+		CertFile:            certFile,
+		KeyFile:             keyFile,
+		RenewalMetadataFile: metadataFile,
+	})
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	defer w.Stop()
+
+	if w.InRenewalWindow() {
+		t.Error("expected the watcher to not yet be in its renewal window")
+	}
+}
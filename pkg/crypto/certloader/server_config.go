@@ -0,0 +1,102 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/tls"
+)
+
+// WatchedServerConfig produces stdlib TLS server configuration backed by a
+// Watcher, with the served certificate kept up to date as it rotates on
+// disk, and, when CAFiles are configured, client certificate verification
+// enabled for mTLS.
+type WatchedServerConfig struct {
+	*Watcher
+
+	// requireOCSPMustStaple, if set by WithOCSPMustStapleVerification,
+	// rejects a peer whose leaf certificate requests OCSP stapling but
+	// didn't staple a well-formed, successful response.
+	requireOCSPMustStaple bool
+}
+
+// ServerConfigOption customizes a WatchedServerConfig at construction time.
+type ServerConfigOption func(*WatchedServerConfig)
+
+// WithOCSPMustStapleVerification rejects a peer connection whenever the
+// peer's leaf certificate carries the must-staple TLS Feature extension but
+// the connection didn't staple a well-formed, successful OCSP response for
+// it. Connections from certificates that don't request stapling are
+// unaffected. Off by default.
+func WithOCSPMustStapleVerification() ServerConfigOption {
+	return func(c *WatchedServerConfig) {
+		c.requireOCSPMustStaple = true
+	}
+}
+
+// NewWatchedServerConfig creates a WatchedServerConfig backed by the given
+// file paths, customized by opts.
+func NewWatchedServerConfig(config Config, opts ...ServerConfigOption) (*WatchedServerConfig, error) {
+	w, err := NewWatcher(config)
+	if err != nil {
+		return nil, err
+	}
+	wsc := &WatchedServerConfig{Watcher: w}
+	for _, opt := range opts {
+		opt(wsc)
+	}
+	return wsc, nil
+}
+
+// ServerConfig returns a clone of base with GetCertificate wired to the
+// watcher's current keypair, and GetConfigForClient refreshing that clone on
+// every handshake so rotated certificates and CA pools take effect without
+// rebuilding the server. If CAFiles were configured, ClientCAs is set to the
+// watched CA pool and ClientAuth is set to require and verify a client
+// certificate on every connection. base is not modified; pass nil to start
+// from an empty *tls.Config.
+func (c *WatchedServerConfig) ServerConfig(base *tls.Config) *tls.Config {
+	cfg := c.snapshot(base)
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		return c.snapshot(base), nil
+	}
+	return cfg
+}
+
+// snapshot returns a clone of base, with GetCertificate and, if configured,
+// mTLS client verification wired to the watcher's state at the time of the
+// call.
+func (c *WatchedServerConfig) snapshot(base *tls.Config) *tls.Config {
+	var cfg *tls.Config
+	if base != nil {
+		cfg = base.Clone()
+	} else {
+		cfg = &tls.Config{}
+	}
+
+	cfg.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return c.KeyPair()
+	}
+
+	if len(c.config.CAFiles) > 0 {
+		cfg.ClientCAs = c.CertPool()
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	if c.requireOCSPMustStaple {
+		cfg.VerifyConnection = verifyOCSPMustStaple
+	}
+
+	return cfg
+}
@@ -0,0 +1,140 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchedServerConfigRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	wsc, err := NewWatchedServerConfig(Config{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("failed to create watched server config: %v", err)
+	}
+	defer wsc.Stop()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", wsc.ServerConfig(nil))
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if tlsConn, ok := conn.(*tls.Conn); ok {
+				tlsConn.Handshake()
+			}
+			conn.Close()
+		}
+	}()
+
+	serial := serverCertSerial(t, ln.Addr().String())
+	if serial.Int64() != 1 {
+		t.Fatalf("expected initial serial 1, got %v", serial)
+	}
+
+	// Rotate the cert on disk and give the fsnotify watcher time to react.
+	writeSelfSignedCert(t, certFile, keyFile, 2)
+	deadline := time.Now().Add(5 * time.Second)
+	var rotated bool
+	for time.Now().Before(deadline) {
+		if pair, err := wsc.KeyPair(); err == nil {
+			leaf, err := x509.ParseCertificate(pair.Certificate[0])
+			if err == nil && leaf.SerialNumber.Int64() == 2 {
+				rotated = true
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if !rotated {
+		t.Fatal("watcher did not pick up the rotated certificate in time")
+	}
+
+	serial = serverCertSerial(t, ln.Addr().String())
+	if serial.Int64() != 2 {
+		t.Fatalf("expected rotated serial 2 on a new connection, got %v", serial)
+	}
+}
+
+// TestWatchedServerConfigClientAuth verifies that ServerConfig only enables
+// mTLS client certificate verification when CAFiles are configured, and
+// preserves the fields of the base *tls.Config it's given otherwise.
+func TestWatchedServerConfigClientAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	wsc, err := NewWatchedServerConfig(Config{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("failed to create watched server config: %v", err)
+	}
+	defer wsc.Stop()
+
+	base := &tls.Config{ServerName: "should-be-preserved"}
+	cfg := wsc.ServerConfig(base)
+	if cfg.ClientAuth != tls.NoClientCert {
+		t.Fatalf("expected no client cert requirement without CAFiles, got %v", cfg.ClientAuth)
+	}
+	if cfg.ServerName != "should-be-preserved" {
+		t.Fatalf("expected base config fields to be preserved, got ServerName %q", cfg.ServerName)
+	}
+	if base.GetCertificate != nil {
+		t.Fatal("expected base config to not be mutated")
+	}
+
+	caFile := filepath.Join(dir, "ca.crt")
+	writeSelfSignedCert(t, caFile, filepath.Join(dir, "ca.key"), 3)
+
+	wscWithCA, err := NewWatchedServerConfig(Config{CertFile: certFile, KeyFile: keyFile, CAFiles: []string{caFile}})
+	if err != nil {
+		t.Fatalf("failed to create watched server config with CAFiles: %v", err)
+	}
+	defer wscWithCA.Stop()
+
+	cfgWithCA := wscWithCA.ServerConfig(nil)
+	if cfgWithCA.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatalf("expected client cert verification to be required with CAFiles, got %v", cfgWithCA.ClientAuth)
+	}
+	if cfgWithCA.ClientCAs == nil {
+		t.Fatal("expected ClientCAs to be set with CAFiles")
+	}
+}
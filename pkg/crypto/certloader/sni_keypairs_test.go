@@ -0,0 +1,111 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWatchedClientConfigSNIKeypairs verifies that TLSConfigForServerName
+// presents the keypair registered for a given server name via
+// WithSNIKeypairs, and falls back to the WatchedClientConfig's own keypair
+// for any other server name.
+func TestWatchedClientConfigSNIKeypairs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	defaultCert, defaultKey := filepath.Join(dir, "default.crt"), filepath.Join(dir, "default.key")
+	fooCert, fooKey := filepath.Join(dir, "foo.crt"), filepath.Join(dir, "foo.key")
+	writeSelfSignedCert(t, defaultCert, defaultKey, 1)
+	writeSelfSignedCert(t, fooCert, fooKey, 2)
+
+	wcc, err := NewWatchedClientConfig(
+		Config{CertFile: defaultCert, KeyFile: defaultKey},
+		WithSNIKeypairs(map[string]Config{
+			"foo.example.com": {CertFile: fooCert, KeyFile: fooKey},
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create watched client config: %v", err)
+	}
+	defer wcc.Stop()
+
+	fooPair, err := wcc.TLSConfigForServerName("foo.example.com").GetClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fooLeaf, err := x509.ParseCertificate(fooPair.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse returned certificate: %v", err)
+	}
+	if fooLeaf.SerialNumber.Int64() != 2 {
+		t.Errorf("got serial %v, want the foo.example.com keypair's serial 2", fooLeaf.SerialNumber)
+	}
+
+	defaultPair, err := wcc.TLSConfigForServerName("bar.example.com").GetClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defaultLeaf, err := x509.ParseCertificate(defaultPair.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse returned certificate: %v", err)
+	}
+	if defaultLeaf.SerialNumber.Int64() != 1 {
+		t.Errorf("got serial %v, want the fallback default keypair's serial 1", defaultLeaf.SerialNumber)
+	}
+
+	plainPair, err := wcc.TLSConfig().GetClientCertificate(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	plainLeaf, err := x509.ParseCertificate(plainPair.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse returned certificate: %v", err)
+	}
+	if plainLeaf.SerialNumber.Int64() != 1 {
+		t.Errorf("got serial %v, want TLSConfig() to behave exactly like the fallback default", plainLeaf.SerialNumber)
+	}
+}
+
+// TestWatchedClientConfigSNIKeypairLoadFailure verifies that a failure to
+// load one of the SNI keypairs fails the constructor and stops the watchers
+// already created, rather than leaving the WatchedClientConfig half-built.
+func TestWatchedClientConfigSNIKeypairLoadFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	defaultCert, defaultKey := filepath.Join(dir, "default.crt"), filepath.Join(dir, "default.key")
+	writeSelfSignedCert(t, defaultCert, defaultKey, 1)
+
+	_, err = NewWatchedClientConfig(
+		Config{CertFile: defaultCert, KeyFile: defaultKey},
+		WithSNIKeypairs(map[string]Config{
+			"foo.example.com": {CertFile: filepath.Join(dir, "missing.crt"), KeyFile: filepath.Join(dir, "missing.key")},
+		}),
+	)
+	if err == nil {
+		t.Fatal("expected an error from a missing SNI keypair")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// SPIFFEVerifier checks that a peer certificate's URI SAN carries an
+// expected SPIFFE ID, for zero-trust setups that authenticate peers by
+// identity rather than by DNS name. It is meant to be layered on top of
+// normal chain verification, not to replace it.
+type SPIFFEVerifier struct {
+	// expectedID is either a full SPIFFE ID (e.g.
+	// "spiffe://example.org/ns/default/sa/foo"), matched exactly, or a
+	// trust-domain/path prefix ending in "/" (e.g. "spiffe://example.org/"),
+	// matched as a prefix of the peer's URI SAN.
+	expectedID string
+}
+
+// NewSPIFFEVerifier returns a SPIFFEVerifier that accepts peer certificates
+// carrying expectedID as a URI SAN. If expectedID ends in "/", it is treated
+// as a trust-domain (or path) prefix rather than a single exact ID.
+func NewSPIFFEVerifier(expectedID string) *SPIFFEVerifier {
+	return &SPIFFEVerifier{expectedID: expectedID}
+}
+
+// VerifyPeerCertificate is meant to be used as a tls.Config.VerifyPeerCertificate
+// callback layered on top of normal chain verification. It rejects the
+// handshake unless the leaf certificate carries a URI SAN matching the
+// verifier's expected SPIFFE ID or trust-domain prefix.
+func (v *SPIFFEVerifier) VerifyPeerCertificate(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("certloader: no peer certificate presented for SPIFFE verification")
+	}
+
+	leaf, err := spiffeLeaf(rawCerts, verifiedChains)
+	if err != nil {
+		return err
+	}
+
+	for _, uri := range leaf.URIs {
+		if v.matches(uri.String()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("certloader: peer certificate does not carry the expected SPIFFE ID %s", v.expectedID)
+}
+
+// matches reports whether uri satisfies the verifier's expected SPIFFE ID,
+// either by exact match or, when the expected ID ends in "/", by prefix.
+func (v *SPIFFEVerifier) matches(uri string) bool {
+	if strings.HasSuffix(v.expectedID, "/") {
+		return strings.HasPrefix(uri, v.expectedID)
+	}
+	return uri == v.expectedID
+}
+
+// spiffeLeaf returns the leaf certificate to check URI SANs against,
+// preferring the already-parsed leaf from a verified chain over reparsing
+// rawCerts[0].
+func spiffeLeaf(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) (*x509.Certificate, error) {
+	if len(verifiedChains) > 0 && len(verifiedChains[0]) > 0 {
+		return verifiedChains[0][0], nil
+	}
+	leaf, err := x509.ParseCertificate(rawCerts[0])
+	if err != nil {
+		return nil, fmt.Errorf("certloader: failed to parse peer certificate for SPIFFE verification: %w", err)
+	}
+	return leaf, nil
+}
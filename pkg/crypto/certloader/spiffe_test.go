@@ -0,0 +1,170 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+)
+
+// certWithURISAN returns a self-signed leaf certificate carrying uri as its
+// only URI SAN, in the shape VerifyPeerCertificate receives it (DER-encoded
+// rawCerts).
+func certWithURISAN(t *testing.T, uri string) [][]byte {
+	t.Helper()
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	parsedURI, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("failed to parse URI %s: %v", uri, err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "certloader-spiffe-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		URIs:         []*url.URL{parsedURI},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return [][]byte{der}
+}
+
+// TestSPIFFEVerifierExactMatch verifies that a peer certificate carrying
+// exactly the expected SPIFFE ID passes verification.
+func TestSPIFFEVerifierExactMatch(t *testing.T) {
+	v := NewSPIFFEVerifier("spiffe://example.org/ns/default/sa/foo")
+	rawCerts := certWithURISAN(t, "spiffe://example.org/ns/default/sa/foo")
+
+	if err := v.VerifyPeerCertificate(rawCerts, nil); err != nil {
+		t.Fatalf("expected verification to succeed for a matching SPIFFE ID, got: %v", err)
+	}
+}
+
+// TestSPIFFEVerifierExactMismatch verifies that a peer certificate carrying
+// a different SPIFFE ID than expected is rejected.
+func TestSPIFFEVerifierExactMismatch(t *testing.T) {
+	v := NewSPIFFEVerifier("spiffe://example.org/ns/default/sa/foo")
+	rawCerts := certWithURISAN(t, "spiffe://example.org/ns/default/sa/bar")
+
+	if err := v.VerifyPeerCertificate(rawCerts, nil); err == nil {
+		t.Fatal("expected verification to fail for a mismatched SPIFFE ID")
+	}
+}
+
+// TestSPIFFEVerifierTrustDomainPrefix verifies that an expected ID ending in
+// "/" matches any peer ID sharing that trust-domain (or path) prefix.
+func TestSPIFFEVerifierTrustDomainPrefix(t *testing.T) {
+	v := NewSPIFFEVerifier("spiffe://example.org/")
+	rawCerts := certWithURISAN(t, "spiffe://example.org/ns/default/sa/foo")
+
+	if err := v.VerifyPeerCertificate(rawCerts, nil); err != nil {
+		t.Fatalf("expected verification to succeed under the trust-domain prefix, got: %v", err)
+	}
+}
+
+// TestSPIFFEVerifierTrustDomainMismatch verifies that a trust-domain prefix
+// rejects a peer ID from a different trust domain.
+func TestSPIFFEVerifierTrustDomainMismatch(t *testing.T) {
+	v := NewSPIFFEVerifier("spiffe://example.org/")
+	rawCerts := certWithURISAN(t, "spiffe://other.org/ns/default/sa/foo")
+
+	if err := v.VerifyPeerCertificate(rawCerts, nil); err == nil {
+		t.Fatal("expected verification to fail for a different trust domain")
+	}
+}
+
+// TestSPIFFEVerifierNoURISAN verifies that a certificate without any URI SAN
+// is rejected rather than spuriously matching.
+func TestSPIFFEVerifierNoURISAN(t *testing.T) {
+	v := NewSPIFFEVerifier("spiffe://example.org/ns/default/sa/foo")
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "certloader-spiffe-test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+
+	if err := v.VerifyPeerCertificate([][]byte{der}, nil); err == nil {
+		t.Fatal("expected verification to fail for a certificate without a URI SAN")
+	}
+}
+
+// TestWatchedClientConfigSPIFFEVerification verifies that
+// WithSPIFFEVerification wires the SPIFFEVerifier into the produced
+// *tls.Config's VerifyPeerCertificate callback.
+func TestWatchedClientConfigSPIFFEVerification(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := dir + "/tls.crt"
+	keyFile := dir + "/tls.key"
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	wcc, err := NewWatchedClientConfig(Config{CertFile: certFile, KeyFile: keyFile},
+		WithSPIFFEVerification("spiffe://example.org/ns/default/sa/foo"))
+	if err != nil {
+		t.Fatalf("failed to create watched client config: %v", err)
+	}
+	defer wcc.Stop()
+
+	cfg := wcc.TLSConfig()
+	if cfg.VerifyPeerCertificate == nil {
+		t.Fatal("expected VerifyPeerCertificate to be set when SPIFFE verification is configured")
+	}
+
+	matching := certWithURISAN(t, "spiffe://example.org/ns/default/sa/foo")
+	if err := cfg.VerifyPeerCertificate(matching, nil); err != nil {
+		t.Fatalf("expected a matching SPIFFE ID to pass, got: %v", err)
+	}
+
+	mismatched := certWithURISAN(t, "spiffe://example.org/ns/default/sa/bar")
+	if err := cfg.VerifyPeerCertificate(mismatched, nil); err == nil {
+		t.Fatal("expected a mismatched SPIFFE ID to fail")
+	}
+}
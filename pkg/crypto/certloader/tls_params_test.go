@@ -0,0 +1,94 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWatchedClientConfigDefaultTLSParamsInheritsFromBase verifies that a
+// WatchedClientConfig constructed without a TLSParams override leaves
+// MinVersion and CipherSuites at the stdlib zero value, preserving the
+// behavior from before TLSParams existed.
+func TestWatchedClientConfigDefaultTLSParamsInheritsFromBase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	wcc, err := NewWatchedClientConfig(Config{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("failed to create watched client config: %v", err)
+	}
+	defer wcc.Stop()
+
+	cfg := wcc.TLSConfig()
+	if cfg.MinVersion != 0 {
+		t.Fatalf("expected MinVersion to default to 0 (inherit), got %v", cfg.MinVersion)
+	}
+	if cfg.CipherSuites != nil {
+		t.Fatalf("expected CipherSuites to default to nil (inherit), got %v", cfg.CipherSuites)
+	}
+}
+
+// TestWatchedClientConfigTLSParamsOverride verifies that a TLSParams passed
+// to NewWatchedClientConfig is applied to every *tls.Config TLSConfig
+// produces, including ones refreshed via GetConfigForClient.
+func TestWatchedClientConfigTLSParamsOverride(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	params := TLSParams{
+		MinVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+	}
+	wcc, err := NewWatchedClientConfig(Config{CertFile: certFile, KeyFile: keyFile}, WithTLSParams(params))
+	if err != nil {
+		t.Fatalf("failed to create watched client config: %v", err)
+	}
+	defer wcc.Stop()
+
+	cfg := wcc.TLSConfig()
+	if cfg.MinVersion != tls.VersionTLS12 {
+		t.Fatalf("expected MinVersion %v, got %v", tls.VersionTLS12, cfg.MinVersion)
+	}
+	if len(cfg.CipherSuites) != 1 || cfg.CipherSuites[0] != tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 {
+		t.Fatalf("expected configured CipherSuites, got %v", cfg.CipherSuites)
+	}
+
+	refreshed, err := cfg.GetConfigForClient(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("GetConfigForClient failed: %v", err)
+	}
+	if refreshed.MinVersion != tls.VersionTLS12 {
+		t.Fatalf("expected refreshed MinVersion %v, got %v", tls.VersionTLS12, refreshed.MinVersion)
+	}
+}
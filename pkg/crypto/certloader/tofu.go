@@ -0,0 +1,101 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/cilium/cilium/pkg/lock"
+)
+
+// TOFUVerifier implements trust-on-first-use pinning of a peer certificate,
+// for bootstrap scenarios that lack a pre-shared CA, such as a dev
+// clustermesh. The first peer certificate it observes is fingerprinted and
+// persisted to a file; every subsequent connection is rejected unless it
+// presents a certificate with that exact fingerprint.
+//
+// TOFU pinning trusts whichever peer happens to connect first, so it must
+// only be used where that first connection is known to be trustworthy, e.g.
+// an isolated dev environment. It is not a substitute for a real CA in
+// production.
+type TOFUVerifier struct {
+	pinFile string
+
+	mutex lock.Mutex
+}
+
+// NewTOFUVerifier returns a TOFUVerifier that persists its pin to pinFile.
+func NewTOFUVerifier(pinFile string) *TOFUVerifier {
+	return &TOFUVerifier{pinFile: pinFile}
+}
+
+// VerifyPeerCertificate is meant to be used directly as a
+// tls.Config.VerifyPeerCertificate callback, with tls.Config.InsecureSkipVerify
+// set so that the stdlib's own chain verification is skipped in favor of this
+// pin check. On the first call it pins rawCerts[0]'s fingerprint to disk and
+// logs loudly that it has done so; on every later call it rejects any
+// certificate that doesn't match the pinned fingerprint.
+func (v *TOFUVerifier) VerifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("certloader: no peer certificate presented for TOFU verification")
+	}
+	fingerprint := sha256Fingerprint(rawCerts[0])
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	pinned, err := ioutil.ReadFile(v.pinFile)
+	switch {
+	case os.IsNotExist(err):
+		if err := ioutil.WriteFile(v.pinFile, []byte(fingerprint), 0o600); err != nil {
+			return fmt.Errorf("certloader: failed to persist TOFU pin to %s: %w", v.pinFile, err)
+		}
+		log.Warnf("certloader: trust-on-first-use pinning peer certificate %s to %s; "+
+			"this must only be logged once per peer", fingerprint, v.pinFile)
+		return nil
+	case err != nil:
+		return fmt.Errorf("certloader: failed to read TOFU pin file %s: %w", v.pinFile, err)
+	}
+
+	if string(pinned) != fingerprint {
+		log.Errorf("certloader: peer presented certificate %s, which does not match "+
+			"the trust-on-first-use pin %s recorded in %s", fingerprint, pinned, v.pinFile)
+		return fmt.Errorf("certloader: peer certificate %s does not match pinned fingerprint %s",
+			fingerprint, pinned)
+	}
+	return nil
+}
+
+// sha256Fingerprint returns the hex-encoded SHA256 digest of a DER-encoded
+// certificate.
+func sha256Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:])
+}
+
+// tofuTLSConfig returns a *tls.Config that trusts peers via TOFU pinning
+// instead of chain verification.
+func tofuTLSConfig(verifier *TOFUVerifier) *tls.Config {
+	return &tls.Config{
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: verifier.VerifyPeerCertificate,
+	}
+}
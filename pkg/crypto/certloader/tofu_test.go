@@ -0,0 +1,105 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTOFUVerifierPinsOnFirstUse(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-tofu-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+	pair, err := readRawCert(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("failed to read test certificate: %v", err)
+	}
+
+	pinFile := filepath.Join(dir, "pin")
+	v := NewTOFUVerifier(pinFile)
+
+	if err := v.VerifyPeerCertificate([][]byte{pair}, nil); err != nil {
+		t.Fatalf("expected the first connection to be pinned, got: %v", err)
+	}
+
+	pinned, err := ioutil.ReadFile(pinFile)
+	if err != nil {
+		t.Fatalf("expected a pin file to be written: %v", err)
+	}
+	if string(pinned) != sha256Fingerprint(pair) {
+		t.Fatalf("expected the pin file to hold the certificate's fingerprint, got %s", pinned)
+	}
+
+	// A subsequent connection presenting the same certificate must be
+	// accepted without rewriting the pin.
+	if err := v.VerifyPeerCertificate([][]byte{pair}, nil); err != nil {
+		t.Fatalf("expected the pinned certificate to be accepted again, got: %v", err)
+	}
+}
+
+func TestTOFUVerifierRejectsMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-tofu-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+	first, err := readRawCert(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("failed to read test certificate: %v", err)
+	}
+
+	otherCertFile := filepath.Join(dir, "other.crt")
+	otherKeyFile := filepath.Join(dir, "other.key")
+	writeSelfSignedCert(t, otherCertFile, otherKeyFile, 2)
+	second, err := readRawCert(otherCertFile, otherKeyFile)
+	if err != nil {
+		t.Fatalf("failed to read test certificate: %v", err)
+	}
+
+	pinFile := filepath.Join(dir, "pin")
+	v := NewTOFUVerifier(pinFile)
+
+	if err := v.VerifyPeerCertificate([][]byte{first}, nil); err != nil {
+		t.Fatalf("expected the first connection to be pinned, got: %v", err)
+	}
+
+	if err := v.VerifyPeerCertificate([][]byte{second}, nil); err == nil {
+		t.Fatal("expected a differing certificate to be rejected")
+	}
+}
+
+// readRawCert reads the DER-encoded leaf certificate from a keypair written
+// by writeSelfSignedCert.
+func readRawCert(certFile, keyFile string) ([]byte, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	return pair.Certificate[0], nil
+}
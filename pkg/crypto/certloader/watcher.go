@@ -0,0 +1,516 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/logging"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+)
+
+var log = logging.DefaultLogger.WithField(logfields.LogSubsys, "certloader")
+
+// ErrNoKeypair indicates that a Config's CertFile/KeyFile were set, but
+// couldn't be found on disk during a Watcher's initial load. Callers can
+// check for it with errors.Is to decide whether to proceed without a
+// client/server certificate (e.g. CA-only verification) instead of failing
+// outright.
+var ErrNoKeypair = errors.New("certloader: configured keypair files not found")
+
+// ErrNoCAFiles indicates that a Config's CAFiles were set, but one or more
+// of them couldn't be found on disk during a Watcher's initial load. Callers
+// can check for it with errors.Is to decide whether to proceed without CA
+// verification instead of failing outright.
+var ErrNoCAFiles = errors.New("certloader: configured CA files not found")
+
+// Watcher loads a keypair and/or CA bundle from the files described by a
+// Config and keeps them up to date by watching the underlying files for
+// changes.
+type Watcher struct {
+	config Config
+
+	mutex   lock.RWMutex
+	keyPair *tls.Certificate
+	caPool  *x509.CertPool
+	caPEM   []byte
+	renewal *RenewalMetadata
+
+	pool        *WatcherPool
+	ownsPool    bool
+	watchedDirs map[string]struct{}
+	stop        chan struct{}
+
+	subscribersMutex lock.Mutex
+	subscribers      []chan bool
+
+	rotationSubscribersMutex lock.Mutex
+	rotationSubscribers      []chan struct{}
+
+	// reloadVeto, if set, is consulted with the freshly read keypair and CA
+	// pool on every reload, before they are swapped in; see WithReloadVeto.
+	reloadVeto ReloadVetoFunc
+
+	// caRefreshInterval, if non-zero, makes the Watcher periodically re-read
+	// CAFiles independent of fsnotify; see WithCARefreshInterval.
+	caRefreshInterval time.Duration
+}
+
+// ReloadVetoFunc is consulted by a Watcher with the keypair and CA pool it
+// just read from disk, before they replace the previously loaded material.
+// Returning a non-nil error keeps the previously loaded material active
+// instead of swapping in the new one, e.g. because the new certificate's
+// SANs would no longer satisfy a peer the caller is currently connected to.
+// Either argument may be nil if the corresponding material isn't
+// configured.
+type ReloadVetoFunc func(keyPair *tls.Certificate, caPool *x509.CertPool) error
+
+// WatcherOption configures optional Watcher behavior that most callers
+// don't need to override, so that adding a new one doesn't require changing
+// NewWatcher's signature.
+type WatcherOption func(*Watcher)
+
+// WithReloadVeto sets the ReloadVetoFunc consulted on every reload; see
+// ReloadVetoFunc.
+func WithReloadVeto(veto ReloadVetoFunc) WatcherOption {
+	return func(w *Watcher) {
+		w.reloadVeto = veto
+	}
+}
+
+// WithWatcherPool makes the Watcher register its watched directories with
+// pool instead of creating its own private fsnotify watcher and goroutine,
+// so that several Watchers -- e.g. one each for a client, server and relay
+// TLS config in the same agent -- can share a single fsnotify instance. The
+// caller owns pool, created via NewWatcherPool, and is responsible for
+// closing it once every Watcher registered with it has been stopped; this
+// Watcher's Stop does not close pool, since other Watchers may still be
+// registered with it.
+func WithWatcherPool(pool *WatcherPool) WatcherOption {
+	return func(w *Watcher) {
+		w.pool = pool
+	}
+}
+
+// WithCARefreshInterval makes the Watcher re-read CAFiles every interval,
+// independent of fsnotify. This is meant for filesystems where events are
+// sometimes missed, e.g. some network filesystems used to project
+// externally-rotated CA bundles. The refresh is skipped whenever the
+// re-read content is unchanged, so it never causes a spurious rotation
+// notification. It does not affect the keypair, which keeps reloading only
+// on fsnotify events.
+func WithCARefreshInterval(interval time.Duration) WatcherOption {
+	return func(w *Watcher) {
+		w.caRefreshInterval = interval
+	}
+}
+
+// NewWatcher creates a Watcher for the given configuration, loads the
+// initial certificate material, and starts watching the underlying files
+// for changes. Callers must call Stop() once done with the watcher.
+func NewWatcher(config Config, opts ...WatcherOption) (*Watcher, error) {
+	w := &Watcher{config: config}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	if err := w.reload(true); err != nil {
+		return nil, err
+	}
+
+	if w.pool == nil {
+		w.pool = NewWatcherPool()
+		w.ownsPool = true
+	}
+
+	watchedDirs := make(map[string]struct{})
+	for _, f := range w.watchedFiles() {
+		watchedDirs[filepath.Dir(f)] = struct{}{}
+	}
+	if err := w.pool.addWatcher(w, watchedDirs); err != nil {
+		if w.ownsPool {
+			w.pool.Close()
+		}
+		return nil, err
+	}
+	w.watchedDirs = watchedDirs
+
+	w.stop = make(chan struct{})
+	if w.caRefreshInterval > 0 {
+		go w.caRefreshLoop()
+	}
+
+	return w, nil
+}
+
+// watchedFiles returns the list of files whose parent directories need to
+// be watched for changes.
+func (w *Watcher) watchedFiles() []string {
+	var files []string
+	if w.config.CertFile != "" {
+		files = append(files, w.config.CertFile)
+	}
+	if w.config.KeyFile != "" {
+		files = append(files, w.config.KeyFile)
+	}
+	files = append(files, w.config.CAFiles...)
+	if w.config.RenewalMetadataFile != "" {
+		files = append(files, w.config.RenewalMetadataFile)
+	}
+	return files
+}
+
+// caRefreshLoop periodically re-reads CAFiles on caRefreshInterval, skipping
+// the swap whenever the content is unchanged, independent of fsnotify.
+func (w *Watcher) caRefreshLoop() {
+	ticker := time.NewTicker(w.caRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.refreshCAFiles(); err != nil {
+				log.WithError(err).Warn("Failed to refresh CA files on timer")
+			}
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// refreshCAFiles re-reads CAFiles and swaps in the result if its content
+// differs from what's currently loaded, independent of fsnotify. It leaves
+// the keypair untouched, and doesn't consult reloadVeto: unlike a keypair
+// swap, a CA-only refresh doesn't affect connections already relying on
+// currently loaded certificate material.
+func (w *Watcher) refreshCAFiles() error {
+	if len(w.config.CAFiles) == 0 {
+		return nil
+	}
+
+	caPool := x509.NewCertPool()
+	var caPEM []byte
+	for _, f := range w.config.CAFiles {
+		pem, err := ioutil.ReadFile(f)
+		if err != nil {
+			return fmt.Errorf("failed to read CA file %s: %w", f, err)
+		}
+		if !caPool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in CA file %s", f)
+		}
+		caPEM = append(caPEM, pem...)
+	}
+
+	w.mutex.Lock()
+	unchanged := bytes.Equal(caPEM, w.caPEM)
+	if !unchanged {
+		w.caPool = caPool
+		w.caPEM = caPEM
+	}
+	w.mutex.Unlock()
+
+	if !unchanged {
+		w.notifyRotated()
+	}
+
+	return nil
+}
+
+// Stop stops watching the underlying files for changes. If this Watcher was
+// created with WithWatcherPool, it only deregisters from the shared pool,
+// which keeps running for any other Watcher still registered with it.
+func (w *Watcher) Stop() {
+	if w.pool == nil {
+		return
+	}
+	w.pool.removeWatcher(w, w.watchedDirs)
+	if w.ownsPool {
+		w.pool.Close()
+	}
+	if w.caRefreshInterval > 0 {
+		close(w.stop)
+	}
+}
+
+// reload reads the keypair and CA bundle from disk and atomically swaps
+// them in. initial indicates this is the load performed by NewWatcher:
+// missing keypair or CA files are reported as a typed ErrNoKeypair/
+// ErrNoCAFiles error instead of being silently tolerated, since at that
+// point the caller hasn't yet committed to a watcher it can react to
+// later. Later reloads tolerate missing keypair files, logging a warning and
+// treating the keypair as unconfigured, since the files may simply be
+// mid-rotation. A CA file missing on a later reload fails that reload, and
+// the previously loaded material stays active.
+func (w *Watcher) reload(initial bool) error {
+	var (
+		keyPair *tls.Certificate
+		caPool  *x509.CertPool
+		caPEM   []byte
+		renewal *RenewalMetadata
+	)
+
+	if w.config.HasKeyPair() {
+		pair, err := tls.LoadX509KeyPair(w.config.CertFile, w.config.KeyFile)
+		switch {
+		case initial && os.IsNotExist(err):
+			return fmt.Errorf("%w: %s", ErrNoKeypair, err)
+		case os.IsNotExist(err):
+			// The keypair files were removed at runtime, e.g. by the sidecar
+			// that projects them. Treat this the same as an unconfigured
+			// keypair rather than failing reload outright.
+			log.Warn("Keypair files no longer exist, mTLS handshakes using them will fail until they are restored")
+		case err != nil:
+			return fmt.Errorf("failed to load keypair: %w", err)
+		default:
+			keyPair = &pair
+		}
+	}
+
+	if len(w.config.CAFiles) > 0 {
+		caPool = x509.NewCertPool()
+		for _, f := range w.config.CAFiles {
+			pem, err := ioutil.ReadFile(f)
+			if err != nil {
+				if initial && os.IsNotExist(err) {
+					return fmt.Errorf("%w: %s", ErrNoCAFiles, err)
+				}
+				return fmt.Errorf("failed to read CA file %s: %w", f, err)
+			}
+			if !caPool.AppendCertsFromPEM(pem) {
+				return fmt.Errorf("no certificates found in CA file %s", f)
+			}
+			caPEM = append(caPEM, pem...)
+		}
+	}
+
+	if w.config.RenewalMetadataFile != "" {
+		var err error
+		renewal, err = readRenewalMetadata(w.config.RenewalMetadataFile)
+		if err != nil {
+			return err
+		}
+		if renewal.inRenewalWindow(time.Now()) {
+			log.WithFields(logrus.Fields{
+				"notAfter":    renewal.NotAfter,
+				"renewBefore": renewal.RenewBefore,
+			}).Warning("Certificate is within its renewal window")
+		}
+	}
+
+	if w.reloadVeto != nil {
+		if err := w.reloadVeto(keyPair, caPool); err != nil {
+			log.WithError(err).Warn("Vetoed reloading certificate material, keeping the previously loaded material active")
+			return nil
+		}
+	}
+
+	w.mutex.Lock()
+	wasConfigured := w.keyPair != nil
+	w.keyPair = keyPair
+	w.caPool = caPool
+	w.caPEM = caPEM
+	w.renewal = renewal
+	w.mutex.Unlock()
+
+	if isConfigured := keyPair != nil; isConfigured != wasConfigured {
+		w.notifyKeypairConfigured(isConfigured)
+	}
+	w.notifyRotated()
+
+	return nil
+}
+
+// KeyPair returns the current keypair. It is meant to be used directly as a
+// tls.Config.GetCertificate or GetClientCertificate callback so that TLS
+// handshakes always use the latest certificate material.
+func (w *Watcher) KeyPair() (*tls.Certificate, error) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	if w.keyPair == nil {
+		return nil, fmt.Errorf("certloader: no keypair configured")
+	}
+	return w.keyPair, nil
+}
+
+// KeyInfo returns the algorithm and key size in bits of the currently loaded
+// private key, for example ("RSA", 2048), ("ECDSA-P-256", 256), or
+// ("Ed25519", 256). It returns ("", 0) if no keypair is configured. This is
+// meant for compliance reporting, not for making handshake decisions.
+func (w *Watcher) KeyInfo() (algo string, bits int) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	if w.keyPair == nil {
+		return "", 0
+	}
+
+	switch key := w.keyPair.PrivateKey.(type) {
+	case *rsa.PrivateKey:
+		return "RSA", key.N.BitLen()
+	case *ecdsa.PrivateKey:
+		return fmt.Sprintf("ECDSA-%s", key.Curve.Params().Name), key.Curve.Params().BitSize
+	case ed25519.PrivateKey:
+		return "Ed25519", ed25519.SeedSize * 8
+	default:
+		return fmt.Sprintf("%T", key), 0
+	}
+}
+
+// CertPool returns the current CA certificate pool, or nil if none is
+// configured.
+func (w *Watcher) CertPool() *x509.CertPool {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.caPool
+}
+
+// CAPEM returns the current CA bundle as concatenated PEM bytes, or nil if
+// no CA files are configured. This is meant for downstream tools that need
+// the raw bundle rather than an *x509.CertPool, e.g. to hand off to an
+// external library.
+func (w *Watcher) CAPEM() []byte {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.caPEM
+}
+
+// InRenewalWindow reports whether the certificate's renewal metadata, if
+// configured, indicates that cert-manager intends to renew it now.
+func (w *Watcher) InRenewalWindow() bool {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.renewal.inRenewalWindow(time.Now())
+}
+
+// KeypairConfigured reports whether the watcher currently holds a loaded
+// keypair, i.e. whether mTLS handshakes using it are currently possible.
+func (w *Watcher) KeypairConfigured() bool {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.keyPair != nil
+}
+
+// CACertPoolConfigured reports whether the watcher currently holds a
+// non-empty CA certificate pool, independent of whether a keypair is also
+// configured. Servers can use this to decide whether requiring client
+// certificate verification is possible right now, since KeypairConfigured
+// alone only answers that question for the watcher's own client/server
+// certificate. Reflects the watcher's state live across reloads.
+func (w *Watcher) CACertPoolConfigured() bool {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.caPool != nil
+}
+
+// SubscribeKeypairConfigured returns a channel that receives
+// KeypairConfigured's value every time it changes, for example because the
+// keypair files were removed or restored on disk at runtime. The channel is
+// closed once ctx is done.
+func (w *Watcher) SubscribeKeypairConfigured(ctx context.Context) <-chan bool {
+	ch := make(chan bool, 1)
+
+	w.subscribersMutex.Lock()
+	w.subscribers = append(w.subscribers, ch)
+	w.subscribersMutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		w.subscribersMutex.Lock()
+		defer w.subscribersMutex.Unlock()
+		for i, c := range w.subscribers {
+			if c == ch {
+				w.subscribers = append(w.subscribers[:i], w.subscribers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+
+	return ch
+}
+
+// notifyKeypairConfigured delivers configured to every subscriber
+// registered through SubscribeKeypairConfigured, dropping the update for any
+// subscriber that isn't ready to receive it since only the latest state
+// matters.
+func (w *Watcher) notifyKeypairConfigured(configured bool) {
+	w.subscribersMutex.Lock()
+	defer w.subscribersMutex.Unlock()
+	for _, ch := range w.subscribers {
+		select {
+		case ch <- configured:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel that receives a value every time a reload of
+// the keypair and/or CA pool completes successfully, regardless of whether
+// the reloaded material actually differs from what was previously loaded.
+// This is meant for consumers that need to react to a rotation itself, e.g.
+// to drain connections established under a certificate that may no longer
+// be the one they'd now negotiate, as opposed to SubscribeKeypairConfigured,
+// which only fires when whether a keypair is configured at all flips. The
+// channel is closed once ctx is done.
+func (w *Watcher) Subscribe(ctx context.Context) <-chan struct{} {
+	ch := make(chan struct{}, 1)
+
+	w.rotationSubscribersMutex.Lock()
+	w.rotationSubscribers = append(w.rotationSubscribers, ch)
+	w.rotationSubscribersMutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		w.rotationSubscribersMutex.Lock()
+		defer w.rotationSubscribersMutex.Unlock()
+		for i, c := range w.rotationSubscribers {
+			if c == ch {
+				w.rotationSubscribers = append(w.rotationSubscribers[:i], w.rotationSubscribers[i+1:]...)
+				break
+			}
+		}
+		close(ch)
+	}()
+
+	return ch
+}
+
+// notifyRotated wakes every subscriber registered through Subscribe,
+// dropping the notification for any subscriber that isn't ready to receive
+// it since only the fact that a rotation happened matters, not how many.
+func (w *Watcher) notifyRotated() {
+	w.rotationSubscribersMutex.Lock()
+	defer w.rotationSubscribersMutex.Unlock()
+	for _, ch := range w.rotationSubscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/cilium/cilium/pkg/lock"
+)
+
+// WatcherPool lets multiple Watchers share a single underlying fsnotify
+// watcher and dispatch goroutine, instead of each opening its own. Pass the
+// same pool to several NewWatcher calls via WithWatcherPool, e.g. for the
+// client, server and relay TLS configs of one agent, to avoid accumulating
+// one extra file descriptor and goroutine per config.
+//
+// The underlying fsnotify watcher is created lazily, on the first Watcher
+// registered with the pool, and kept open for the pool's lifetime; call
+// Close once every Watcher registered with it has been stopped.
+type WatcherPool struct {
+	mutex        lock.Mutex
+	fsWatcher    *fsnotify.Watcher
+	createdCount int
+	dirWatchers  map[string]map[*Watcher]struct{}
+
+	stop            chan struct{}
+	handlerFinished chan struct{}
+}
+
+// NewWatcherPool creates an empty WatcherPool. It does not open an
+// underlying fsnotify watcher until a Watcher is registered with it.
+func NewWatcherPool() *WatcherPool {
+	return &WatcherPool{
+		dirWatchers: make(map[string]map[*Watcher]struct{}),
+	}
+}
+
+// ensureStarted creates the pool's underlying fsnotify watcher and starts
+// its dispatch goroutine, if this is the first call to reach it.
+func (p *WatcherPool) ensureStarted() error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.fsWatcher != nil {
+		return nil
+	}
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create fsnotify watcher: %w", err)
+	}
+
+	p.fsWatcher = fsWatcher
+	p.createdCount++
+	p.stop = make(chan struct{})
+	p.handlerFinished = make(chan struct{})
+
+	go p.watchLoop()
+
+	return nil
+}
+
+// addWatcher registers w as interested in changes under dirs, adding each
+// not already watched by another registered Watcher to the underlying
+// fsnotify watcher.
+func (p *WatcherPool) addWatcher(w *Watcher, dirs map[string]struct{}) error {
+	if err := p.ensureStarted(); err != nil {
+		return err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for dir := range dirs {
+		watchers, ok := p.dirWatchers[dir]
+		if !ok {
+			if err := p.fsWatcher.Add(dir); err != nil {
+				return fmt.Errorf("failed to watch %s: %w", dir, err)
+			}
+			watchers = make(map[*Watcher]struct{})
+			p.dirWatchers[dir] = watchers
+		}
+		watchers[w] = struct{}{}
+	}
+
+	return nil
+}
+
+// removeWatcher deregisters w from dirs, removing any directory from the
+// underlying fsnotify watcher that no other registered Watcher still cares
+// about.
+func (p *WatcherPool) removeWatcher(w *Watcher, dirs map[string]struct{}) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	for dir := range dirs {
+		watchers, ok := p.dirWatchers[dir]
+		if !ok {
+			continue
+		}
+		delete(watchers, w)
+		if len(watchers) == 0 {
+			delete(p.dirWatchers, dir)
+			p.fsWatcher.Remove(dir)
+		}
+	}
+}
+
+// watchLoop dispatches events and errors from the underlying fsnotify
+// watcher until the pool is closed.
+func (p *WatcherPool) watchLoop() {
+	for {
+		select {
+		case event := <-p.fsWatcher.Events:
+			switch event.Op {
+			case fsnotify.Create, fsnotify.Write, fsnotify.Chmod, fsnotify.Remove, fsnotify.Rename:
+				p.dispatch(event)
+			}
+		case err := <-p.fsWatcher.Errors:
+			log.WithError(err).Warn("certloader watcher pool received an error")
+		case <-p.stop:
+			close(p.handlerFinished)
+			return
+		}
+	}
+}
+
+// dispatch reloads every Watcher currently registered for event's directory.
+func (p *WatcherPool) dispatch(event fsnotify.Event) {
+	dir := filepath.Dir(event.Name)
+
+	p.mutex.Lock()
+	watchers := make([]*Watcher, 0, len(p.dirWatchers[dir]))
+	for w := range p.dirWatchers[dir] {
+		watchers = append(watchers, w)
+	}
+	p.mutex.Unlock()
+
+	for _, w := range watchers {
+		if err := w.reload(false); err != nil {
+			log.WithError(err).Warn("Failed to reload certificate material")
+		}
+	}
+}
+
+// Close stops the pool's dispatch goroutine and closes its underlying
+// fsnotify watcher, if one was ever created. Callers must only do this once
+// every Watcher registered with the pool has been stopped.
+func (p *WatcherPool) Close() {
+	p.mutex.Lock()
+	fsWatcher := p.fsWatcher
+	stop := p.stop
+	handlerFinished := p.handlerFinished
+	p.mutex.Unlock()
+
+	if fsWatcher == nil {
+		return
+	}
+
+	close(stop)
+	<-handlerFinished
+	fsWatcher.Close()
+}
+
+// createdWatcherCount returns the number of underlying fsnotify watchers the
+// pool has ever created, for use by tests asserting that sharing a pool
+// across several Watchers doesn't create one per Watcher.
+func (p *WatcherPool) createdWatcherCount() int {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.createdCount
+}
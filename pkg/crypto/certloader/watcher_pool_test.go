@@ -0,0 +1,156 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"crypto/x509"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWatcherPoolSharesUnderlyingWatcher verifies that three Watchers
+// created with WithWatcherPool against the same pool share a single
+// underlying fsnotify watcher instead of each creating their own.
+func TestWatcherPoolSharesUnderlyingWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	pool := NewWatcherPool()
+
+	var watchers []*Watcher
+	for i := 0; i < 3; i++ {
+		certFile := filepath.Join(dir, "tls.crt")
+		keyFile := filepath.Join(dir, "tls.key")
+		writeSelfSignedCert(t, certFile, keyFile, int64(i+1))
+
+		w, err := NewWatcher(Config{CertFile: certFile, KeyFile: keyFile}, WithWatcherPool(pool))
+		if err != nil {
+			t.Fatalf("failed to create watcher %d: %v", i, err)
+		}
+		watchers = append(watchers, w)
+	}
+
+	if got := pool.createdWatcherCount(); got != 1 {
+		t.Fatalf("expected the pool to have created exactly 1 underlying fsnotify watcher, got %d", got)
+	}
+
+	for _, w := range watchers {
+		w.Stop()
+	}
+	pool.Close()
+}
+
+// TestWatcherPoolDispatchesReloadToRegisteredWatcher verifies that a file
+// change under a directory shared by a WatcherPool still triggers a reload
+// on the Watcher registered for it.
+func TestWatcherPoolDispatchesReloadToRegisteredWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	pool := NewWatcherPool()
+	defer pool.Close()
+
+	w, err := NewWatcher(Config{CertFile: certFile, KeyFile: keyFile}, WithWatcherPool(pool))
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	writeSelfSignedCert(t, certFile, keyFile, 2)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		pair, err := w.KeyPair()
+		if err != nil {
+			t.Fatalf("failed to get keypair: %v", err)
+		}
+		leaf, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			t.Fatalf("failed to parse leaf certificate: %v", err)
+		}
+		if leaf.SerialNumber.Int64() == 2 {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for the pool to dispatch the rotation to the watcher")
+}
+
+// TestWatcherPoolKeepsRunningAfterOneWatcherStops verifies that stopping one
+// Watcher registered with a shared pool doesn't close the pool's underlying
+// fsnotify watcher out from under the other Watchers still registered with
+// it.
+func TestWatcherPoolKeepsRunningAfterOneWatcherStops(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFileA := filepath.Join(dir, "a.crt")
+	keyFileA := filepath.Join(dir, "a.key")
+	writeSelfSignedCert(t, certFileA, keyFileA, 1)
+
+	certFileB := filepath.Join(dir, "b.crt")
+	keyFileB := filepath.Join(dir, "b.key")
+	writeSelfSignedCert(t, certFileB, keyFileB, 1)
+
+	pool := NewWatcherPool()
+	defer pool.Close()
+
+	wa, err := NewWatcher(Config{CertFile: certFileA, KeyFile: keyFileA}, WithWatcherPool(pool))
+	if err != nil {
+		t.Fatalf("failed to create watcher a: %v", err)
+	}
+	wb, err := NewWatcher(Config{CertFile: certFileB, KeyFile: keyFileB}, WithWatcherPool(pool))
+	if err != nil {
+		t.Fatalf("failed to create watcher b: %v", err)
+	}
+	defer wb.Stop()
+
+	wa.Stop()
+
+	writeSelfSignedCert(t, certFileB, keyFileB, 2)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		pair, err := wb.KeyPair()
+		if err != nil {
+			t.Fatalf("failed to get keypair: %v", err)
+		}
+		leaf, err := x509.ParseCertificate(pair.Certificate[0])
+		if err != nil {
+			t.Fatalf("failed to parse leaf certificate: %v", err)
+		}
+		if leaf.SerialNumber.Int64() == 2 {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for watcher b to pick up the rotation after watcher a stopped")
+}
@@ -0,0 +1,271 @@
+// Copyright 2020 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package certloader
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestWatcherKeypairConfiguredTransition verifies that removing the keypair
+// files at runtime flips KeypairConfigured to false and delivers that
+// transition over a subscription, and that restoring the files flips it
+// back.
+func TestWatcherKeypairConfiguredTransition(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	w, err := NewWatcher(Config{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	if !w.KeypairConfigured() {
+		t.Fatal("expected the keypair to be configured initially")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events := w.SubscribeKeypairConfigured(ctx)
+
+	if err := os.Remove(certFile); err != nil {
+		t.Fatalf("failed to remove cert file: %v", err)
+	}
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatalf("failed to remove key file: %v", err)
+	}
+
+	select {
+	case configured := <-events:
+		if configured {
+			t.Fatal("expected the transition event to report not configured")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the not-configured transition event")
+	}
+	if w.KeypairConfigured() {
+		t.Fatal("expected KeypairConfigured to be false after the keypair files were removed")
+	}
+
+	writeSelfSignedCert(t, certFile, keyFile, 2)
+
+	select {
+	case configured := <-events:
+		if !configured {
+			t.Fatal("expected the transition event to report configured again")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the configured-again transition event")
+	}
+	if !w.KeypairConfigured() {
+		t.Fatal("expected KeypairConfigured to be true after the keypair files were restored")
+	}
+}
+
+// TestWatcherSubscribeNotifiesOnRotation verifies that touching the
+// certificate file on disk delivers a notification over a Subscribe
+// channel, even though KeypairConfigured itself never changes.
+func TestWatcherSubscribeNotifiesOnRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	w, err := NewWatcher(Config{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rotations := w.Subscribe(ctx)
+
+	writeSelfSignedCert(t, certFile, keyFile, 2)
+
+	select {
+	case <-rotations:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a rotation notification")
+	}
+
+	pair, err := w.KeyPair()
+	if err != nil {
+		t.Fatalf("failed to get keypair: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse leaf certificate: %v", err)
+	}
+	if leaf.SerialNumber.Int64() != 2 {
+		t.Fatalf("expected the rotated serial 2 to be loaded, got %v", leaf.SerialNumber)
+	}
+}
+
+// TestWatcherSubscribeClosesOnContextDone verifies that a Subscribe channel
+// is closed once its context is cancelled, so callers can range over it
+// without leaking a goroutine.
+func TestWatcherSubscribeClosesOnContextDone(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	w, err := NewWatcher(Config{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rotations := w.Subscribe(ctx)
+	cancel()
+
+	select {
+	case _, ok := <-rotations:
+		if ok {
+			t.Fatal("expected the channel to be closed, not to deliver a value")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the channel to close")
+	}
+}
+
+// TestWatcherReloadVetoKeepsPreviousMaterial verifies that a ReloadVetoFunc
+// returning an error prevents a reload from swapping in the newly read
+// keypair, leaving the previously loaded one active.
+func TestWatcherReloadVetoKeepsPreviousMaterial(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	veto := func(keyPair *tls.Certificate, caPool *x509.CertPool) error {
+		leaf, err := x509.ParseCertificate(keyPair.Certificate[0])
+		if err != nil {
+			return err
+		}
+		if leaf.SerialNumber.Int64() == 2 {
+			return fmt.Errorf("serial 2 is vetoed")
+		}
+		return nil
+	}
+
+	w, err := NewWatcher(Config{CertFile: certFile, KeyFile: keyFile}, WithReloadVeto(veto))
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	writeSelfSignedCert(t, certFile, keyFile, 2)
+
+	// There's no notification for a vetoed reload, so poll briefly and
+	// assert the previous material is still in place once the watcher has
+	// had a chance to react to the file change.
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	pair, err := w.KeyPair()
+	if err != nil {
+		t.Fatalf("failed to get keypair: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse leaf certificate: %v", err)
+	}
+	if leaf.SerialNumber.Int64() != 1 {
+		t.Fatalf("expected the vetoed reload to leave serial 1 active, got %v", leaf.SerialNumber)
+	}
+}
+
+// TestWatcherReloadVetoAllowsMatchingMaterial verifies that a ReloadVetoFunc
+// which doesn't object lets a reload proceed normally.
+func TestWatcherReloadVetoAllowsMatchingMaterial(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certloader-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	writeSelfSignedCert(t, certFile, keyFile, 1)
+
+	veto := func(keyPair *tls.Certificate, caPool *x509.CertPool) error {
+		return nil
+	}
+
+	w, err := NewWatcher(Config{CertFile: certFile, KeyFile: keyFile}, WithReloadVeto(veto))
+	if err != nil {
+		t.Fatalf("failed to create watcher: %v", err)
+	}
+	defer w.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rotations := w.Subscribe(ctx)
+
+	writeSelfSignedCert(t, certFile, keyFile, 2)
+
+	select {
+	case <-rotations:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a rotation notification")
+	}
+
+	pair, err := w.KeyPair()
+	if err != nil {
+		t.Fatalf("failed to get keypair: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse leaf certificate: %v", err)
+	}
+	if leaf.SerialNumber.Int64() != 2 {
+		t.Fatalf("expected the approved reload to apply serial 2, got %v", leaf.SerialNumber)
+	}
+}
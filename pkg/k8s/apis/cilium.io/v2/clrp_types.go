@@ -61,8 +61,27 @@ type Frontend struct {
 	// "169.254.169.254" is redirected.
 	//
 	// +kubebuilder:validation:Pattern=`((^\s*((([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5]))\s*$)|(^\s*((([0-9A-Fa-f]{1,4}:){7}([0-9A-Fa-f]{1,4}|:))|(([0-9A-Fa-f]{1,4}:){6}(:[0-9A-Fa-f]{1,4}|((25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)(\.(25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)){3})|:))|(([0-9A-Fa-f]{1,4}:){5}(((:[0-9A-Fa-f]{1,4}){1,2})|:((25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)(\.(25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)){3})|:))|(([0-9A-Fa-f]{1,4}:){4}(((:[0-9A-Fa-f]{1,4}){1,3})|((:[0-9A-Fa-f]{1,4})?:((25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)(\.(25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)){3}))|:))|(([0-9A-Fa-f]{1,4}:){3}(((:[0-9A-Fa-f]{1,4}){1,4})|((:[0-9A-Fa-f]{1,4}){0,2}:((25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)(\.(25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)){3}))|:))|(([0-9A-Fa-f]{1,4}:){2}(((:[0-9A-Fa-f]{1,4}){1,5})|((:[0-9A-Fa-f]{1,4}){0,3}:((25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)(\.(25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)){3}))|:))|(([0-9A-Fa-f]{1,4}:){1}(((:[0-9A-Fa-f]{1,4}){1,6})|((:[0-9A-Fa-f]{1,4}){0,4}:((25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)(\.(25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)){3}))|:))|(:(((:[0-9A-Fa-f]{1,4}){1,7})|((:[0-9A-Fa-f]{1,4}){0,5}:((25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)(\.(25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)){3}))|:)))(%.+)?\s*$))`
-	// +kubebuilder:validation:Required
-	IP string `json:"ip"`
+	// +kubebuilder:validation:OneOf
+	IP string `json:"ip,omitempty"`
+
+	// Interface is the name of a local interface whose primary address is
+	// used as the destination ip address for traffic to be redirected.
+	// Mutually exclusive with IP. The policy is re-resolved if the
+	// interface's address changes, and deferred while the interface does
+	// not exist.
+	//
+	// +kubebuilder:validation:OneOf
+	Interface string `json:"interface,omitempty"`
+
+	// CIDR is a destination address range for traffic to be redirected.
+	// It expands into one frontend per address contained in the range, so
+	// that e.g. a metadata service CIDR can be redirected in one policy
+	// instead of one address matcher per address. Mutually exclusive with
+	// IP and Interface. Rejected if it would expand into too many
+	// addresses.
+	//
+	// +kubebuilder:validation:OneOf
+	CIDR string `json:"cidr,omitempty"`
 
 	// ToPorts is a list of destination L4 ports with protocol for traffic
 	// to be redirected.
@@ -101,9 +120,12 @@ type PortInfo struct {
 	Port string `json:"port"`
 
 	// Protocol is the L4 protocol.
-	// Accepted values: "TCP", "UDP"
+	// Accepted values: "TCP", "UDP", "ANY"
+	// "ANY" is only valid for a frontend port, where the datapath supports
+	// intercepting traffic regardless of L4 protocol. It requires the veth
+	// datapath mode.
 	//
-	// +kubebuilder:validation:Enum=TCP;UDP
+	// +kubebuilder:validation:Enum=TCP;UDP;ANY
 	// +kubebuilder:validation:Required
 	Protocol api.L4Proto `json:"protocol"`
 
@@ -143,6 +165,18 @@ type ServiceInfo struct {
 	//
 	// +kubebuilder:validation:Optional
 	ToPorts []PortInfo `json:"toPorts,omitempty"`
+
+	// RedirectEndpoints, if true, allows this policy to redirect traffic for
+	// a headless service (one with no ClusterIP): instead of the usual
+	// single service-wide frontend, one frontend is created per endpoint, so
+	// that traffic a client sends directly to an endpoint's DNS-resolved pod
+	// IP is still locally redirected. It has no effect on a service that
+	// does have a ClusterIP, and requires ToPorts to be set, since a
+	// headless service has no concrete frontend port to enumerate
+	// otherwise.
+	//
+	// +kubebuilder:validation:Optional
+	RedirectEndpoints bool `json:"redirectEndpoints,omitempty"`
 }
 
 // RedirectBackend is a backend configuration that determines where traffic needs to be redirected to.
@@ -152,12 +186,90 @@ type RedirectBackend struct {
 	// +kubebuilder:validation:Required
 	LocalEndpointSelector slim_metav1.LabelSelector `json:"localEndpointSelector"`
 
+	// BackendExcludeSelector, if set, excludes pods that match it from backend
+	// selection, even if they match LocalEndpointSelector. This is useful to
+	// redirect to all node local pods of a deployment except, for example,
+	// canary pods carrying a distinguishing label. It is rejected if it fully
+	// overlaps with LocalEndpointSelector, since that would select no
+	// backends at all.
+	//
+	// +kubebuilder:validation:Optional
+	BackendExcludeSelector *slim_metav1.LabelSelector `json:"backendExcludeSelector,omitempty"`
+
+	// BackendAnnotationSelector, if set, additionally restricts backend
+	// selection to pods whose annotations (rather than labels) match it, in
+	// addition to LocalEndpointSelector. This is useful for teams that key
+	// backend identity off annotations instead of labels, e.g. so the
+	// selector has no effect on the pod's scheduling.
+	//
+	// +kubebuilder:validation:Optional
+	BackendAnnotationSelector *slim_metav1.LabelSelector `json:"backendAnnotationSelector,omitempty"`
+
 	// ToPorts is a list of L4 ports with protocol of node local pod(s) where traffic
 	// is redirected to.
 	// When multiple ports are specified, the ports must be named.
 	//
 	// +kubebuilder:validation:Required
 	ToPorts []PortInfo `json:"toPorts"`
+
+	// FieldSelector restricts backend pod selection using a Kubernetes field
+	// selector expression (e.g. "spec.hostNetwork=true"), in addition to
+	// LocalEndpointSelector. Only a small, fixed set of pod spec fields is
+	// supported; an unsupported field is rejected at policy creation time.
+	//
+	// +kubebuilder:validation:Optional
+	FieldSelector string `json:"fieldSelector,omitempty"`
+
+	// AllowHostNetworkBackends allows hostNetwork pods to be selected as
+	// backends. By default hostNetwork pods are excluded: their
+	// containerPort is the host port, and their pod IP is the node's own
+	// address, which may already be a frontend for this or another policy,
+	// creating a redirect loop. Set this only when that's known not to be
+	// the case for the ports in question.
+	//
+	// +kubebuilder:validation:Optional
+	AllowHostNetworkBackends bool `json:"allowHostNetworkBackends,omitempty"`
+
+	// BackendNamespace, if set, selects backend pods from the given
+	// namespace instead of the policy's own namespace. It is only valid for
+	// address-matcher (RedirectFrontend.AddressMatcher) policies; it's
+	// rejected for service-matcher policies, whose backends must stay in
+	// the namespace of the service being redirected.
+	//
+	// +kubebuilder:validation:Optional
+	BackendNamespace string `json:"backendNamespace,omitempty"`
+
+	// MaxBackends, if set to a value greater than zero, caps the number of
+	// backend pods the policy installs, to bound the load-balancing map
+	// entries a single policy can consume. Backends are selected
+	// deterministically, ordered by namespace and then by name, so the same
+	// subset of pods is chosen across restarts.
+	//
+	// +kubebuilder:validation:Optional
+	MaxBackends int `json:"maxBackends,omitempty"`
+
+	// RestartGracePeriodSeconds, if set to a value greater than zero, holds a
+	// backend pod's entries in place for that many seconds after it's
+	// deleted, instead of removing them immediately. This avoids tearing
+	// down and immediately rebuilding the redirected service when a
+	// single-backend pod is recreated under a new UID with the same name,
+	// e.g. during a crash restart. Zero disables the grace period, which is
+	// the original, immediate-removal behavior.
+	//
+	// +kubebuilder:validation:Optional
+	RestartGracePeriodSeconds int `json:"restartGracePeriodSeconds,omitempty"`
+
+	// DeleteDrainPeriodSeconds, if set to a value greater than zero, holds
+	// the policy's local redirect service in place for that many seconds
+	// after the policy itself is deleted, with its backends' weight reset to
+	// zero, the default weight, so that they keep serving until the service
+	// is removed, instead of removing the service immediately.
+	// This gives in-flight connections to a stateful local service a chance
+	// to finish before the frontend disappears. Zero disables the drain
+	// period, which is the original, immediate-removal behavior.
+	//
+	// +kubebuilder:validation:Optional
+	DeleteDrainPeriodSeconds int `json:"deleteDrainPeriodSeconds,omitempty"`
 }
 
 // CiliumLocalRedirectPolicySpec specifies the configurations for redirecting traffic
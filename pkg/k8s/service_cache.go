@@ -124,16 +124,36 @@ func (s *ServiceCache) GetServiceIP(svcID ServiceID) *loadbalancer.L3n4Addr {
 	return nil
 }
 
-// GetServiceFrontendIP returns the frontend IP (aka clusterIP) for the given service with type.
-func (s *ServiceCache) GetServiceFrontendIP(svcID ServiceID, svcType loadbalancer.SVCType) net.IP {
+// GetServiceFrontendIPs returns the frontend IP (aka clusterIP) for the
+// given service with type, as a one-element slice, or nil if the service is
+// unknown, of another type, or has no frontend IP. The cache holds a single
+// frontend IP per service, so for a dual-stack service only that one IP is
+// returned, not one per IP family.
+func (s *ServiceCache) GetServiceFrontendIPs(svcID ServiceID, svcType loadbalancer.SVCType) []net.IP {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 	svc := s.services[svcID]
-	if svc == nil || svc.Type != svcType {
+	if svc == nil || svc.Type != svcType || svc.FrontendIP == nil {
 		return nil
 	}
 
-	return svc.FrontendIP
+	return []net.IP{svc.FrontendIP}
+}
+
+// GetServiceByID returns a copy of the service with the given ID, and
+// whether it was found in the cache at all. This is meant for diagnostics
+// callers that need to tell apart "the service doesn't exist" from "the
+// service exists but isn't of the type being looked for", which the
+// frontend-only accessors above can't distinguish since they return nothing
+// in both cases.
+func (s *ServiceCache) GetServiceByID(svcID ServiceID) (Service, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	svc := s.services[svcID]
+	if svc == nil {
+		return Service{}, false
+	}
+	return *svc, true
 }
 
 // GetServiceAddrWithPortsAndType returns a slice of all the L3n4Addr that are backing the
@@ -154,6 +174,29 @@ func (s *ServiceCache) GetServiceAddrsWithType(svcID ServiceID, svcType loadbala
 	return addrsByPort
 }
 
+// GetServiceAffinity returns whether the given service has ClientIP session
+// affinity enabled and, if so, the configured affinity timeout in seconds.
+func (s *ServiceCache) GetServiceAffinity(svcID ServiceID) (affinity bool, timeoutSec uint32) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	svc := s.services[svcID]
+	if svc == nil {
+		return false, 0
+	}
+	return svc.SessionAffinity, svc.SessionAffinityTimeoutSec
+}
+
+// GetEndpointsOfService returns the full set of endpoints backing the given
+// service, correlated from both local and (if enabled) remote cluster
+// endpoints, for callers that need to compare against a subset of those
+// endpoints, such as a node-local redirect policy.
+func (s *ServiceCache) GetEndpointsOfService(svcID ServiceID) *Endpoints {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	endpoints, _ := s.correlateEndpoints(svcID)
+	return endpoints
+}
+
 // GetNodeAddressing returns the registered node addresses to this service cache.
 func (s *ServiceCache) GetNodeAddressing() datapath.NodeAddressing {
 	return s.nodeAddressing
@@ -378,7 +421,9 @@ func (s *ServiceCache) UniqueServiceFrontends() FrontendList {
 // returns a boolean that indicates whether the service is ready to be plumbed,
 // this is true if:
 // IF If ta local endpoints resource is present. Regardless whether the
-//    endpoints resource contains actual backends or not.
+//
+//	endpoints resource contains actual backends or not.
+//
 // OR Remote endpoints exist which correlate to the service.
 func (s *ServiceCache) correlateEndpoints(id ServiceID) (*Endpoints, bool) {
 	endpoints := newEndpoints()
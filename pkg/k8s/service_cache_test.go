@@ -330,6 +330,27 @@ func testServiceCache(c *check.C,
 	}, 2*time.Second), check.IsNil)
 }
 
+// TestGetServiceFrontendIPs verifies that GetServiceFrontendIPs returns the
+// service's clusterIP for a matching type, and nil for an unknown service or
+// a type mismatch.
+func (s *K8sSuite) TestGetServiceFrontendIPs(c *check.C) {
+	svcID := ServiceID{Name: "svc1", Namespace: "default"}
+
+	cache := NewServiceCache(fakeDatapath.NewNodeAddressing())
+	cache.services = map[ServiceID]*Service{
+		svcID: {
+			FrontendIP: net.ParseIP("1.1.1.1"),
+			Type:       loadbalancer.SVCTypeClusterIP,
+		},
+	}
+
+	ips := cache.GetServiceFrontendIPs(svcID, loadbalancer.SVCTypeClusterIP)
+	c.Assert(ips, checker.DeepEquals, []net.IP{net.ParseIP("1.1.1.1")})
+
+	c.Assert(cache.GetServiceFrontendIPs(svcID, loadbalancer.SVCTypeNodePort), check.IsNil)
+	c.Assert(cache.GetServiceFrontendIPs(ServiceID{Name: "missing"}, loadbalancer.SVCTypeClusterIP), check.IsNil)
+}
+
 func (s *K8sSuite) TestCacheActionString(c *check.C) {
 	c.Assert(UpdateService.String(), check.Equals, "service-updated")
 	c.Assert(DeleteService.String(), check.Equals, "service-deleted")
@@ -144,6 +144,12 @@ type ObjectMeta struct {
 	// More info: http://kubernetes.io/docs/user-guide/annotations
 	// +optional
 	Annotations map[string]string `json:"annotations,omitempty" protobuf:"bytes,12,rep,name=annotations"`
+
+	// DeletionTimestamp is RFC 3339 date and time at which this resource will be
+	// deleted. This field is set by the server when a graceful deletion is
+	// requested by the user, and is not directly settable by a client.
+	// +optional
+	DeletionTimestamp *Time `json:"deletionTimestamp,omitempty" protobuf:"bytes,13,opt,name=deletionTimestamp"`
 }
 
 const (
@@ -107,6 +107,10 @@ func (in *ObjectMeta) DeepCopyInto(out *ObjectMeta) {
 			(*out)[key] = val
 		}
 	}
+	if in.DeletionTimestamp != nil {
+		in, out := &in.DeletionTimestamp, &out.DeletionTimestamp
+		*out = (*in).DeepCopy()
+	}
 	return
 }
 
@@ -68,7 +68,7 @@ func (k *K8sWatcher) ciliumLocalRedirectPolicyInit(ciliumLRPClient *k8s.K8sCiliu
 		wait.NeverStop,
 		nil,
 		lrpController,
-		k8sAPIGroupCiliumLocalRedirectPolicyV2,
+		K8sAPIGroupCiliumLocalRedirectPolicyV2,
 	)
 
 	go lrpController.Run(wait.NeverStop)
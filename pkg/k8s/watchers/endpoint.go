@@ -88,12 +88,14 @@ func (k *K8sWatcher) endpointsInit(k8sClient kubernetes.Interface, swgEps *lock.
 }
 
 func (k *K8sWatcher) addK8sEndpointV1(ep *slim_corev1.Endpoints, swg *lock.StoppableWaitGroup) error {
-	k.K8sSvcCache.UpdateEndpoints(ep, swg)
+	svcID, _ := k.K8sSvcCache.UpdateEndpoints(ep, swg)
+	k.redirectPolicyManager.OnUpdateEndpoints(svcID, &k.K8sSvcCache, k.podStore)
 	return nil
 }
 
 func (k *K8sWatcher) updateK8sEndpointV1(oldEP, newEP *slim_corev1.Endpoints, swg *lock.StoppableWaitGroup) error {
-	k.K8sSvcCache.UpdateEndpoints(newEP, swg)
+	svcID, _ := k.K8sSvcCache.UpdateEndpoints(newEP, swg)
+	k.redirectPolicyManager.OnUpdateEndpoints(svcID, &k.K8sSvcCache, k.podStore)
 	return nil
 }
 
@@ -51,7 +51,8 @@ func (k *K8sWatcher) endpointSlicesInit(k8sClient kubernetes.Interface, swgEps *
 				defer func() { k.K8sEventReceived(metricEndpointSlice, metricCreate, valid, equal) }()
 				if k8sEP := k8s.ObjToV1EndpointSlice(obj); k8sEP != nil {
 					valid = true
-					k.K8sSvcCache.UpdateEndpointSlices(k8sEP, swgEps)
+					svcID, _ := k.K8sSvcCache.UpdateEndpointSlices(k8sEP, swgEps)
+					k.redirectPolicyManager.OnUpdateEndpoints(svcID, &k.K8sSvcCache, k.podStore)
 					k.K8sEventProcessed(metricEndpointSlice, metricCreate, true)
 				}
 			},
@@ -66,7 +67,8 @@ func (k *K8sWatcher) endpointSlicesInit(k8sClient kubernetes.Interface, swgEps *
 							return
 						}
 
-						k.K8sSvcCache.UpdateEndpointSlices(newk8sEP, swgEps)
+						svcID, _ := k.K8sSvcCache.UpdateEndpointSlices(newk8sEP, swgEps)
+						k.redirectPolicyManager.OnUpdateEndpoints(svcID, &k.K8sSvcCache, k.podStore)
 						k.K8sEventProcessed(metricEndpointSlice, metricUpdate, true)
 					}
 				}
@@ -62,7 +62,7 @@ const (
 	k8sAPIGroupCiliumClusterwideNetworkPolicyV2 = "cilium/v2::CiliumClusterwideNetworkPolicy"
 	k8sAPIGroupCiliumNodeV2                     = "cilium/v2::CiliumNode"
 	k8sAPIGroupCiliumEndpointV2                 = "cilium/v2::CiliumEndpoint"
-	k8sAPIGroupCiliumLocalRedirectPolicyV2      = "cilium/v2::CiliumLocalRedirectPolicy"
+	K8sAPIGroupCiliumLocalRedirectPolicyV2      = "cilium/v2::CiliumLocalRedirectPolicy"
 	K8sAPIGroupEndpointSliceV1Beta1Discovery    = "discovery/v1beta1::EndpointSlice"
 
 	metricCNP            = "CiliumNetworkPolicy"
@@ -144,6 +144,7 @@ type redirectPolicyManager interface {
 	DeleteRedirectPolicy(config redirectpolicy.LRPConfig) error
 	OnAddService(svcID k8s.ServiceID, svcCache *k8s.ServiceCache, podStore cache.Store)
 	OnDeleteService(svcID k8s.ServiceID)
+	OnUpdateEndpoints(svcID k8s.ServiceID, svcCache *k8s.ServiceCache, podStore cache.Store)
 	OnUpdatePod(pod *slim_corev1.Pod)
 	OnDeletePod(pod *slim_corev1.Pod)
 	OnAddPod(pod *slim_corev1.Pod)
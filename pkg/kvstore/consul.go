@@ -347,17 +347,19 @@ func (c *consulClient) Watch(ctx context.Context, w *Watcher) {
 
 				queueStart := spanstat.Start()
 				w.Events <- KeyValueEvent{
-					Typ:   EventTypeCreate,
-					Key:   newPair.Key,
-					Value: newPair.Value,
+					Typ:         EventTypeCreate,
+					Key:         newPair.Key,
+					Value:       newPair.Value,
+					ModRevision: newPair.ModifyIndex,
 				}
 				trackEventQueued(newPair.Key, EventTypeCreate, queueStart.End(true).Total())
 			} else if oldPair.ModifyIndex != newPair.ModifyIndex {
 				queueStart := spanstat.Start()
 				w.Events <- KeyValueEvent{
-					Typ:   EventTypeModify,
-					Key:   newPair.Key,
-					Value: newPair.Value,
+					Typ:         EventTypeModify,
+					Key:         newPair.Key,
+					Value:       newPair.Value,
+					ModRevision: newPair.ModifyIndex,
 				}
 				trackEventQueued(newPair.Key, EventTypeModify, queueStart.End(true).Total())
 			}
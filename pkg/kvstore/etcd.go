@@ -969,9 +969,10 @@ reList:
 
 				queueStart := spanstat.Start()
 				w.Events <- KeyValueEvent{
-					Key:   string(key.Key),
-					Value: key.Value,
-					Typ:   t,
+					Key:         string(key.Key),
+					Value:       key.Value,
+					Typ:         t,
+					ModRevision: uint64(key.ModRevision),
 				}
 				trackEventQueued(string(key.Key), t, queueStart.End(true).Total())
 			}
@@ -1050,8 +1051,9 @@ reList:
 
 				for _, ev := range r.Events {
 					event := KeyValueEvent{
-						Key:   string(ev.Kv.Key),
-						Value: ev.Kv.Value,
+						Key:         string(ev.Kv.Key),
+						Value:       ev.Kv.Value,
+						ModRevision: uint64(ev.Kv.ModRevision),
 					}
 
 					switch {
@@ -59,6 +59,14 @@ type KeyValueEvent struct {
 
 	// Value is the kvstore value associated with the key
 	Value []byte
+
+	// ModRevision is the backend's modification revision for this change,
+	// when available. It is monotonically increasing across all keys in the
+	// backend, so consumers can use it to detect gaps or order events across
+	// keys. It is zero when the backend could not determine a revision for
+	// the change, e.g. a deletion inferred from a key that disappeared
+	// between two listings during resynchronization.
+	ModRevision uint64
 }
 
 // EventChan is a channel to receive events on
@@ -159,6 +159,24 @@ type Observer interface {
 	OnUpdate(k Key)
 }
 
+// RevisionObserver is an optional Observer extension. An Observer that also
+// implements it receives the backend's modification revision for a change
+// alongside the key, in place of the plain OnUpdate/OnDelete calls, letting
+// consumers doing incremental reconciliation detect gaps and order events
+// across keys. rev is zero when the backend could not determine a revision
+// for the change; see KeyValueEvent.ModRevision.
+type RevisionObserver interface {
+	Observer
+
+	// OnDeleteRevision is called instead of OnDelete when the Observer
+	// implements RevisionObserver.
+	OnDeleteRevision(k NamedKey, rev uint64)
+
+	// OnUpdateRevision is called instead of OnUpdate when the Observer
+	// implements RevisionObserver.
+	OnUpdateRevision(k Key, rev uint64)
+}
+
 // NamedKey is an interface that a data structure must implement in order to
 // be deleted from a SharedStore.
 type NamedKey interface {
@@ -233,16 +251,26 @@ func JoinSharedStore(c Configuration) (*SharedStore, error) {
 	return s, nil
 }
 
-func (s *SharedStore) onDelete(k NamedKey) {
-	if s.conf.Observer != nil {
-		s.conf.Observer.OnDelete(k)
+func (s *SharedStore) onDelete(k NamedKey, rev uint64) {
+	if s.conf.Observer == nil {
+		return
 	}
+	if ro, ok := s.conf.Observer.(RevisionObserver); ok {
+		ro.OnDeleteRevision(k, rev)
+		return
+	}
+	s.conf.Observer.OnDelete(k)
 }
 
-func (s *SharedStore) onUpdate(k Key) {
-	if s.conf.Observer != nil {
-		s.conf.Observer.OnUpdate(k)
+func (s *SharedStore) onUpdate(k Key, rev uint64) {
+	if s.conf.Observer == nil {
+		return
+	}
+	if ro, ok := s.conf.Observer.(RevisionObserver); ok {
+		ro.OnUpdateRevision(k, rev)
+		return
 	}
+	s.conf.Observer.OnUpdate(k)
 }
 
 // Release frees all resources own by the store but leaves all keys in the
@@ -276,7 +304,7 @@ func (s *SharedStore) Close(ctx context.Context) {
 		// it from the shared keys.
 		delete(s.sharedKeys, name)
 
-		s.onDelete(key)
+		s.onDelete(key, 0)
 	}
 }
 
@@ -402,7 +430,7 @@ func (s *SharedStore) DeleteLocalKey(ctx context.Context, key NamedKey) {
 			s.getLogger().WithError(err).Warning("Unable to delete key in kvstore")
 		}
 
-		s.onDelete(key)
+		s.onDelete(key, 0)
 	}
 }
 
@@ -442,7 +470,7 @@ func (s *SharedStore) getLogger() *logrus.Entry {
 	})
 }
 
-func (s *SharedStore) updateKey(name string, value []byte) error {
+func (s *SharedStore) updateKey(name string, value []byte, rev uint64) error {
 	newKey := s.conf.KeyCreator()
 	if err := newKey.Unmarshal(value); err != nil {
 		return err
@@ -452,11 +480,11 @@ func (s *SharedStore) updateKey(name string, value []byte) error {
 	s.sharedKeys[name] = newKey
 	s.mutex.Unlock()
 
-	s.onUpdate(newKey)
+	s.onUpdate(newKey, rev)
 	return nil
 }
 
-func (s *SharedStore) deleteSharedKey(name string) {
+func (s *SharedStore) deleteSharedKey(name string, rev uint64) {
 	s.mutex.Lock()
 	existingKey, ok := s.sharedKeys[name]
 	delete(s.sharedKeys, name)
@@ -474,7 +502,7 @@ func (s *SharedStore) deleteSharedKey(name string) {
 				return
 			}
 
-			s.onDelete(existingKey)
+			s.onDelete(existingKey, rev)
 		}()
 	} else {
 		s.getLogger().WithField("key", name).
@@ -520,7 +548,7 @@ func (s *SharedStore) watcher(listDone chan bool) {
 
 		switch event.Typ {
 		case kvstore.EventTypeCreate, kvstore.EventTypeModify:
-			if err := s.updateKey(keyName, event.Value); err != nil {
+			if err := s.updateKey(keyName, event.Value, event.ModRevision); err != nil {
 				logger.WithError(err).Warningf("Unable to unmarshal store value: %s", string(event.Value))
 			}
 
@@ -530,7 +558,7 @@ func (s *SharedStore) watcher(listDone chan bool) {
 
 				s.syncLocalKey(s.conf.Context, localKey)
 			} else {
-				s.deleteSharedKey(keyName)
+				s.deleteSharedKey(keyName, event.ModRevision)
 			}
 		}
 	}
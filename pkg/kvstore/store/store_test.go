@@ -279,6 +279,63 @@ func (s *StoreSuite) TestStoreLocalKeyProtection(c *C) {
 	}), IsNil)
 }
 
+// blockingBackend is a kvstore.BackendOperations whose UpdateIfDifferent
+// blocks until either its ctx is cancelled or the test unblocks it, to
+// simulate a kvstore that has stopped responding.
+type blockingBackend struct {
+	kvstore.BackendOperations
+	unblock chan struct{}
+}
+
+func (b *blockingBackend) UpdateIfDifferent(ctx context.Context, key string, value []byte, lease bool) (bool, error) {
+	select {
+	case <-ctx.Done():
+		return false, ctx.Err()
+	case <-b.unblock:
+		return true, nil
+	}
+}
+
+// TestUpdateLocalKeySyncContextCancellation verifies that UpdateLocalKeySync
+// returns as soon as its context is cancelled, rather than waiting
+// indefinitely on a hung backend, and that the store can still be released
+// cleanly afterwards.
+func TestUpdateLocalKeySyncContextCancellation(t *testing.T) {
+	backend := &blockingBackend{unblock: make(chan struct{})}
+	s := &SharedStore{
+		conf:       Configuration{Prefix: testPrefix},
+		backend:    backend,
+		localKeys:  map[string]LocalKey{},
+		sharedKeys: map[string]Key{},
+	}
+
+	key := &TestType{Name: "blocked"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.UpdateLocalKeySync(ctx, key)
+	}()
+
+	cancel()
+
+	select {
+	case err := <-errCh:
+		if err != context.Canceled {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for UpdateLocalKeySync to return after cancellation")
+	}
+
+	if keys := s.getLocalKeys(); len(keys) != 0 {
+		t.Fatalf("expected the key to not be recorded as local after a failed sync, got %v", keys)
+	}
+
+	// A failed join/sync must not leave the store unreleasable.
+	s.Release()
+}
+
 func setupStoreCollaboration(c *C, storePrefix, keyPrefix string) *SharedStore {
 	store, err := JoinSharedStore(Configuration{
 		Prefix:                  storePrefix,
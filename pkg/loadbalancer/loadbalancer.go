@@ -201,6 +201,19 @@ type Backend struct {
 	// Node hosting this backend. This is used to determine backends local to
 	// a node.
 	NodeName string
+	// Weight is the load-balancing weight requested for this backend
+	// relative to its peers. Zero, the default, means equal weight among all
+	// backends of a service. The service manager and the BPF load-balancer
+	// maps do not read this field, so it does not change which backend a
+	// connection is sent to.
+	Weight uint16
+	// Zone is the topology zone this backend runs in, if known. Empty means
+	// the zone is unknown, not that the backend has no zone.
+	Zone string
+	// PortName is the container port name that was matched to select this
+	// backend, for a named-port local redirect policy. Empty if the backend
+	// wasn't selected by a named port.
+	PortName string
 	L3n4Addr
 }
 
@@ -271,6 +284,10 @@ func NewL4Type(name string) (L4Type, error) {
 		return TCP, nil
 	case "udp":
 		return UDP, nil
+	case "any":
+		// NONE is used elsewhere in this package as the wildcard protocol,
+		// matching traffic regardless of L4 protocol.
+		return NONE, nil
 	default:
 		return "", fmt.Errorf("unknown L4 protocol")
 	}
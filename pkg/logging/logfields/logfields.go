@@ -477,4 +477,12 @@ const (
 
 	// LRPBackendPorts are the parsed backend ports of the Local Redirect Policy.
 	LRPBackendPorts = "lrpBackendPorts"
+
+	// LRPFrontend is the single frontend address a Local Redirect Policy is
+	// upserting backends for.
+	LRPFrontend = "lrpFrontend"
+
+	// LRPBackends is the resolved list of backend IP:port:protocol tuples a
+	// Local Redirect Policy is installing for a frontend.
+	LRPBackends = "lrpBackends"
 )
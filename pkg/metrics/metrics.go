@@ -68,6 +68,9 @@ const (
 	// SubsystemTriggers is the subsystem to scope metrics related to the trigger package.
 	SubsystemTriggers = "triggers"
 
+	// SubsystemLRP is the subsystem to scope metrics related to local redirect policies.
+	SubsystemLRP = "lrp"
+
 	// Namespace is used to scope metrics from cilium. It is prepended to metric
 	// names and separated with a '_'
 	Namespace = "cilium"
@@ -175,6 +178,19 @@ const (
 
 	// LabelVersion is the label for the version number
 	LabelVersion = "version"
+
+	// LabelPolicyNamespace is the label for the Kubernetes namespace a local
+	// redirect policy belongs to.
+	LabelPolicyNamespace = "policy_namespace"
+
+	// LabelPolicyName is the label for the name of a local redirect policy.
+	LabelPolicyName = "policy_name"
+
+	// LabelNodeStore is the label for which node shared store a node/store
+	// metric refers to, e.g. "register" for the store joined by
+	// RegisterNode, or "main" for the store used for ongoing local node
+	// synchronization.
+	LabelNodeStore = "store"
 )
 
 var (
@@ -438,6 +454,39 @@ var (
 
 	// VersionMetric labelled by Cilium version
 	VersionMetric = NoOpGaugeVec
+
+	// LRPBackendCount is the number of active backends for a local redirect
+	// policy's frontend, labeled by policy namespace and name.
+	LRPBackendCount = NoOpGaugeVec
+
+	// LRPUpsertErrorsTotal is the number of failed backend upserts/deletes
+	// for a local redirect policy's frontend, labeled by policy namespace,
+	// name and the operation that failed.
+	LRPUpsertErrorsTotal = NoOpCounterVec
+
+	// LRPTimeToFirstBackend measures the duration between a local redirect
+	// policy's creation and the first time it has a backend programmed,
+	// labeled by policy namespace and name.
+	LRPTimeToFirstBackend = NoOpObserverVec
+
+	// LRPDroppedEventsTotal is the number of local redirect policy events
+	// that could not be processed because a required dependency (the pod
+	// store or service cache) was unavailable.
+	LRPDroppedEventsTotal = NoOpCounter
+
+	// LRPFamilyMismatchTotal is the number of times a local redirect policy
+	// was observed with no backends because every pod it selects is in a
+	// different address family than its frontend, labeled by policy
+	// namespace and name.
+	LRPFamilyMismatchTotal = NoOpCounterVec
+
+	// NodeStoreJoinDuration measures how long it takes to join a node
+	// shared store, labeled by which store was joined.
+	NodeStoreJoinDuration = NoOpObserverVec
+
+	// NodeStoreSyncErrorsTotal is the number of failed local node key
+	// syncs to a node shared store, labeled by which store failed.
+	NodeStoreSyncErrorsTotal = NoOpCounterVec
 )
 
 type Configuration struct {
@@ -494,6 +543,13 @@ type Configuration struct {
 	TriggerPolicyUpdateFolds                bool
 	TriggerPolicyUpdateCallDuration         bool
 	VersionMetric                           bool
+	LRPBackendCountEnabled                  bool
+	LRPUpsertErrorsTotalEnabled             bool
+	LRPTimeToFirstBackendEnabled            bool
+	LRPDroppedEventsTotalEnabled            bool
+	LRPFamilyMismatchTotalEnabled           bool
+	NodeStoreJoinDurationEnabled            bool
+	NodeStoreSyncErrorsTotalEnabled         bool
 }
 
 func DefaultMetrics() map[string]struct{} {
@@ -553,6 +609,12 @@ func DefaultMetrics() map[string]struct{} {
 		Namespace + "_" + SubsystemTriggers + "_policy_update_folds":                 {},
 		Namespace + "_" + SubsystemTriggers + "_policy_update_call_duration_seconds": {},
 		Namespace + "_version":                                                       {},
+		Namespace + "_" + SubsystemLRP + "_backend_count":                            {},
+		Namespace + "_" + SubsystemLRP + "_upsert_errors_total":                      {},
+		Namespace + "_" + SubsystemLRP + "_time_to_first_backend_seconds":            {},
+		Namespace + "_" + SubsystemLRP + "_dropped_events_total":                     {},
+		Namespace + "_" + SubsystemNodes + "_store_join_duration_seconds":            {},
+		Namespace + "_" + SubsystemNodes + "_store_sync_errors_total":                {},
 	}
 }
 
@@ -1182,6 +1244,83 @@ func CreateConfiguration(metricsEnabled []string) (Configuration, []prometheus.C
 
 			collectors = append(collectors, VersionMetric)
 			c.VersionMetric = true
+
+		case Namespace + "_" + SubsystemLRP + "_backend_count":
+			LRPBackendCount = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Namespace: Namespace,
+				Subsystem: SubsystemLRP,
+				Name:      "backend_count",
+				Help:      "Number of active backends for a local redirect policy's frontend",
+			}, []string{LabelPolicyNamespace, LabelPolicyName})
+
+			collectors = append(collectors, LRPBackendCount)
+			c.LRPBackendCountEnabled = true
+
+		case Namespace + "_" + SubsystemLRP + "_upsert_errors_total":
+			LRPUpsertErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: SubsystemLRP,
+				Name:      "upsert_errors_total",
+				Help:      "Number of failed local redirect policy backend upserts/deletes",
+			}, []string{LabelPolicyNamespace, LabelPolicyName, LabelOperation})
+
+			collectors = append(collectors, LRPUpsertErrorsTotal)
+			c.LRPUpsertErrorsTotalEnabled = true
+
+		case Namespace + "_" + SubsystemLRP + "_time_to_first_backend_seconds":
+			LRPTimeToFirstBackend = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: SubsystemLRP,
+				Name:      "time_to_first_backend_seconds",
+				Help:      "Duration between a local redirect policy's creation and its first programmed backend",
+			}, []string{LabelPolicyNamespace, LabelPolicyName})
+
+			collectors = append(collectors, LRPTimeToFirstBackend)
+			c.LRPTimeToFirstBackendEnabled = true
+
+		case Namespace + "_" + SubsystemLRP + "_dropped_events_total":
+			LRPDroppedEventsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: SubsystemLRP,
+				Name:      "dropped_events_total",
+				Help:      "Number of local redirect policy events dropped due to a missing pod store or service cache",
+			})
+
+			collectors = append(collectors, LRPDroppedEventsTotal)
+			c.LRPDroppedEventsTotalEnabled = true
+
+		case Namespace + "_" + SubsystemLRP + "_family_mismatch_total":
+			LRPFamilyMismatchTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: SubsystemLRP,
+				Name:      "family_mismatch_total",
+				Help:      "Number of times a local redirect policy had no backends because all selected pods were in a different address family than its frontend",
+			}, []string{LabelPolicyNamespace, LabelPolicyName})
+
+			collectors = append(collectors, LRPFamilyMismatchTotal)
+			c.LRPFamilyMismatchTotalEnabled = true
+
+		case Namespace + "_" + SubsystemNodes + "_store_join_duration_seconds":
+			NodeStoreJoinDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Namespace: Namespace,
+				Subsystem: SubsystemNodes,
+				Name:      "store_join_duration_seconds",
+				Help:      "Duration of joining a node shared store",
+			}, []string{LabelNodeStore})
+
+			collectors = append(collectors, NodeStoreJoinDuration)
+			c.NodeStoreJoinDurationEnabled = true
+
+		case Namespace + "_" + SubsystemNodes + "_store_sync_errors_total":
+			NodeStoreSyncErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace: Namespace,
+				Subsystem: SubsystemNodes,
+				Name:      "store_sync_errors_total",
+				Help:      "Number of failed local node key syncs to a node shared store",
+			}, []string{LabelNodeStore})
+
+			collectors = append(collectors, NodeStoreSyncErrorsTotal)
+			c.NodeStoreSyncErrorsTotalEnabled = true
 		}
 	}
 
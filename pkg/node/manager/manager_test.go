@@ -274,6 +274,57 @@ func (s *managerTestSuite) TestMultipleSources(c *check.C) {
 	}
 }
 
+// TestKVStoreSourceDoesNotOverwriteLocal verifies that a node update sourced
+// from the kvstore, such as one forwarded by node/store.NodeObserver, is
+// rejected once the node is owned by local agent state, and that it's
+// accepted for a node that isn't owned by a higher-precedence source yet.
+func (s *managerTestSuite) TestKVStoreSourceDoesNotOverwriteLocal(c *check.C) {
+	dp := newSignalNodeHandler()
+	dp.EnableNodeAddEvent = true
+	dp.EnableNodeUpdateEvent = true
+	mngr, err := NewManager("test", dp, newIPcacheMock(), &configMock{})
+	c.Assert(err, check.IsNil)
+	defer mngr.Close()
+
+	n1kvstore := nodeTypes.Node{Name: "node1", Cluster: "c1", Source: source.KVStore}
+	mngr.NodeUpdated(n1kvstore)
+	select {
+	case nodeEvent := <-dp.NodeAddEvent:
+		c.Assert(nodeEvent, checker.DeepEquals, n1kvstore)
+	case nodeEvent := <-dp.NodeUpdateEvent:
+		c.Errorf("Unexpected NodeUpdate() event %#v", nodeEvent)
+	case <-time.After(3 * time.Second):
+		c.Errorf("timeout while waiting for NodeAdd() event for node1")
+	}
+
+	// the local agent can overwrite a kvstore-sourced node
+	n1agent := nodeTypes.Node{Name: "node1", Cluster: "c1", Source: source.Local}
+	mngr.NodeUpdated(n1agent)
+	select {
+	case nodeEvent := <-dp.NodeUpdateEvent:
+		c.Assert(nodeEvent, checker.DeepEquals, n1agent)
+	case nodeEvent := <-dp.NodeAddEvent:
+		c.Errorf("Unexpected NodeAdd() event %#v", nodeEvent)
+	case <-time.After(3 * time.Second):
+		c.Errorf("timeout while waiting for NodeUpdate() event for node1")
+	}
+
+	// a stale kvstore update must not downgrade the local-owned node
+	mngr.NodeUpdated(n1kvstore)
+	select {
+	case nodeEvent := <-dp.NodeAddEvent:
+		c.Errorf("Unexpected NodeAdd() event %#v", nodeEvent)
+	case nodeEvent := <-dp.NodeUpdateEvent:
+		c.Errorf("Unexpected NodeUpdate() event %#v", nodeEvent)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	nodes := mngr.GetNodes()
+	n, ok := nodes[n1agent.Identity()]
+	c.Assert(ok, check.Equals, true)
+	c.Assert(n, checker.DeepEquals, n1agent)
+}
+
 func (s *managerTestSuite) BenchmarkUpdateAndDeleteCycle(c *check.C) {
 	mngr, err := NewManager("test", fake.NewNodeHandler(), newIPcacheMock(), &configMock{})
 	c.Assert(err, check.IsNil)
@@ -0,0 +1,86 @@
+// Copyright 2018-2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"testing"
+
+	"github.com/cilium/cilium/pkg/kvstore/store"
+	nodeTypes "github.com/cilium/cilium/pkg/node/types"
+)
+
+// extendedNode embeds a nodeTypes.Node alongside a field that a downstream
+// distribution might want to round-trip through the shared store, which the
+// default KeyCreator would otherwise drop on decode.
+type extendedNode struct {
+	nodeTypes.Node
+	Region string
+}
+
+func (n *extendedNode) GetNode() *nodeTypes.Node {
+	return &n.Node
+}
+
+func newExtendedNodeKeyCreator() store.KeyCreator {
+	return func() store.Key {
+		return &extendedNode{}
+	}
+}
+
+// TestNodeObserverCustomKeyCreatorRoundTrip verifies that a NodeObserver
+// constructed with a custom KeyCreator delivers the canonical node carried
+// by a decoded key implementing NodeGetter, rather than dropping it.
+func TestNodeObserverCustomKeyCreatorRoundTrip(t *testing.T) {
+	manager := &batchingNodeManager{}
+	creator := newExtendedNodeKeyCreator()
+	observer := NewNodeObserver(manager, creator)
+
+	if observer.KeyCreator() == nil {
+		t.Fatal("expected the observer to retain the custom KeyCreator")
+	}
+
+	// Simulate the shared store decoding an entry with the custom creator:
+	// the extra Region field is preserved on the decoded key, even though
+	// only the embedded Node is ever delivered to the manager.
+	key := creator()
+	decoded := key.(*extendedNode)
+	decoded.Node = nodeTypes.Node{Name: "node-a"}
+	decoded.Region = "us-west"
+
+	observer.OnUpdate(decoded)
+	observer.Flush()
+
+	if manager.batchCalls != 1 || manager.batchedSize != 1 {
+		t.Fatalf("expected the decoded node to be delivered, got %d batch calls of size %d", manager.batchCalls, manager.batchedSize)
+	}
+
+	if decoded.Region != "us-west" {
+		t.Fatalf("expected the custom field to survive decoding, got %q", decoded.Region)
+	}
+
+	deleted := creator().(*extendedNode)
+	deleted.Node = nodeTypes.Node{Name: "node-a"}
+	deleted.Region = "us-west"
+	observer.OnDelete(deleted)
+}
+
+// TestNodeObserverDefaultKeyCreator verifies that NewNodeObserver without an
+// explicit creator falls back to the package-level KeyCreator.
+func TestNodeObserverDefaultKeyCreator(t *testing.T) {
+	observer := NewNodeObserver(&batchingNodeManager{})
+	if _, ok := observer.KeyCreator()().(*nodeTypes.Node); !ok {
+		t.Fatalf("expected the default KeyCreator to produce a *nodeTypes.Node")
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2018-2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"time"
+
+	"github.com/cilium/cilium/pkg/metrics"
+	"github.com/cilium/cilium/pkg/option"
+)
+
+const (
+	// storeRegister identifies the shared store joined by RegisterNode.
+	storeRegister = "register"
+	// storeMain identifies the shared store used by NodeRegistrar for
+	// ongoing local node synchronization.
+	storeMain = "main"
+)
+
+func observeJoinDuration(store string, duration time.Duration) {
+	if !option.Config.MetricsConfig.NodeStoreJoinDurationEnabled {
+		return
+	}
+	metrics.NodeStoreJoinDuration.WithLabelValues(store).Observe(duration.Seconds())
+}
+
+func recordSyncError(store string) {
+	if !option.Config.MetricsConfig.NodeStoreSyncErrorsTotalEnabled {
+		return
+	}
+	metrics.NodeStoreSyncErrorsTotal.WithLabelValues(store).Inc()
+}
@@ -0,0 +1,99 @@
+// Copyright 2018-2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/cilium/cilium/pkg/kvstore"
+	"github.com/cilium/cilium/pkg/metrics"
+	"github.com/cilium/cilium/pkg/option"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// erroringBackend is a kvstore.BackendOperations whose UpdateIfDifferent
+// always fails, to simulate a kvstore that rejects a local key sync.
+type erroringBackend struct {
+	kvstore.BackendOperations
+}
+
+func (erroringBackend) UpdateIfDifferent(ctx context.Context, key string, value []byte, lease bool) (bool, error) {
+	return false, fmt.Errorf("simulated kvstore failure")
+}
+
+// TestRecordSyncErrorIncrementsSyncErrorCounter verifies that
+// recordSyncError, which UpdateLocalKeySync and registerAttempt call when a
+// local key sync fails, increments NodeStoreSyncErrorsTotal under the label
+// of the given store only. The fake, always-erroring backend stands in for
+// the failure; the sync path itself is not exercised here.
+func TestRecordSyncErrorIncrementsSyncErrorCounter(t *testing.T) {
+	origEnabled := option.Config.MetricsConfig.NodeStoreSyncErrorsTotalEnabled
+	option.Config.MetricsConfig.NodeStoreSyncErrorsTotalEnabled = true
+	defer func() { option.Config.MetricsConfig.NodeStoreSyncErrorsTotalEnabled = origEnabled }()
+
+	origCounterVec := metrics.NodeStoreSyncErrorsTotal
+	counterVec := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "test_node_store_sync_errors_total",
+	}, []string{metrics.LabelNodeStore})
+	metrics.NodeStoreSyncErrorsTotal = counterVec
+	defer func() { metrics.NodeStoreSyncErrorsTotal = origCounterVec }()
+
+	counter := counterVec.WithLabelValues(storeRegister)
+	if got := testutil.ToFloat64(counter); got != 0 {
+		t.Fatalf("expected counter to start at 0, got %v", got)
+	}
+
+	backend := erroringBackend{}
+	if _, err := backend.UpdateIfDifferent(context.Background(), "irrelevant", nil, true); err == nil {
+		t.Fatal("expected the fake backend to fail the update")
+	}
+	recordSyncError(storeRegister)
+
+	if got := testutil.ToFloat64(counter); got != 1 {
+		t.Fatalf("expected counter to be 1 after a failed sync, got %v", got)
+	}
+	if got := testutil.ToFloat64(counterVec.WithLabelValues(storeMain)); got != 0 {
+		t.Fatalf("expected the main store's counter to be untouched, got %v", got)
+	}
+}
+
+// TestObserveJoinDurationRecordsHistogram verifies that observeJoinDuration
+// records an observation in NodeStoreJoinDuration, labeled by store.
+func TestObserveJoinDurationRecordsHistogram(t *testing.T) {
+	origEnabled := option.Config.MetricsConfig.NodeStoreJoinDurationEnabled
+	option.Config.MetricsConfig.NodeStoreJoinDurationEnabled = true
+	defer func() { option.Config.MetricsConfig.NodeStoreJoinDurationEnabled = origEnabled }()
+
+	origHistogramVec := metrics.NodeStoreJoinDuration
+	histogramVec := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "test_node_store_join_duration_seconds",
+	}, []string{metrics.LabelNodeStore})
+	metrics.NodeStoreJoinDuration = histogramVec
+	defer func() { metrics.NodeStoreJoinDuration = origHistogramVec }()
+
+	if got := testutil.CollectAndCount(histogramVec); got != 0 {
+		t.Fatalf("expected no observation before any join, got %d", got)
+	}
+
+	observeJoinDuration(storeRegister, 0)
+
+	if got := testutil.CollectAndCount(histogramVec); got != 1 {
+		t.Fatalf("expected one observation after the join, got %d", got)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2018-2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"testing"
+
+	nodeTypes "github.com/cilium/cilium/pkg/node/types"
+)
+
+// revisionNodeManager is a NodeManager that also implements
+// NodeManagerRevisions, recording the revision delivered alongside each
+// update/deletion.
+type revisionNodeManager struct {
+	updates   []nodeTypes.Node
+	revisions []uint64
+}
+
+func (m *revisionNodeManager) NodeUpdated(n nodeTypes.Node)      {}
+func (m *revisionNodeManager) NodeDeleted(n nodeTypes.Node)      {}
+func (m *revisionNodeManager) Exists(id nodeTypes.Identity) bool { return false }
+
+func (m *revisionNodeManager) NodeUpdatedRevision(n nodeTypes.Node, rev uint64) {
+	m.updates = append(m.updates, n)
+	m.revisions = append(m.revisions, rev)
+}
+
+func (m *revisionNodeManager) NodeDeletedRevision(n nodeTypes.Node, rev uint64) {
+	m.updates = append(m.updates, n)
+	m.revisions = append(m.revisions, rev)
+}
+
+// TestNodeObserverDeliversRevisions verifies that a NodeManager implementing
+// NodeManagerRevisions receives the kvstore modification revision alongside
+// each node update/deletion, and that revisions observed across a node's
+// lifetime are non-decreasing.
+func TestNodeObserverDeliversRevisions(t *testing.T) {
+	manager := &revisionNodeManager{}
+	observer := NewNodeObserver(manager)
+	observer.Flush()
+
+	observer.OnUpdateRevision(&nodeTypes.Node{Name: "node-a"}, 10)
+	observer.OnUpdateRevision(&nodeTypes.Node{Name: "node-a"}, 15)
+	observer.OnDeleteRevision(&nodeTypes.Node{Name: "node-a"}, 20)
+
+	if len(manager.revisions) != 3 {
+		t.Fatalf("expected 3 revision-tagged deliveries, got %d", len(manager.revisions))
+	}
+	if got := manager.revisions; got[0] != 10 || got[1] != 15 || got[2] != 20 {
+		t.Fatalf("expected revisions [10 15 20], got %v", got)
+	}
+	for i := 1; i < len(manager.revisions); i++ {
+		if manager.revisions[i] < manager.revisions[i-1] {
+			t.Fatalf("expected non-decreasing revisions, got %v", manager.revisions)
+		}
+	}
+}
+
+// TestNodeObserverBufferedRevisions verifies that revisions observed while
+// buffering is true are preserved and delivered individually once Flush is
+// called, for a manager that doesn't implement NodeManagerBatch.
+func TestNodeObserverBufferedRevisions(t *testing.T) {
+	manager := &revisionNodeManager{}
+	observer := NewNodeObserver(manager)
+
+	observer.OnUpdateRevision(&nodeTypes.Node{Name: "node-a"}, 5)
+	observer.OnUpdateRevision(&nodeTypes.Node{Name: "node-b"}, 7)
+
+	if len(manager.revisions) != 0 {
+		t.Fatalf("expected no deliveries before Flush, got %d", len(manager.revisions))
+	}
+
+	observer.Flush()
+
+	if len(manager.revisions) != 2 || manager.revisions[0] != 5 || manager.revisions[1] != 7 {
+		t.Fatalf("expected buffered revisions [5 7] delivered after Flush, got %v", manager.revisions)
+	}
+}
+
+// TestNodeObserverDefaultDeliveryHasZeroRevision verifies that OnUpdate and
+// OnDelete, called without a revision (e.g. from an Observer caller that
+// predates RevisionObserver), deliver a zero revision rather than panicking
+// or being dropped.
+func TestNodeObserverDefaultDeliveryHasZeroRevision(t *testing.T) {
+	manager := &revisionNodeManager{}
+	observer := NewNodeObserver(manager)
+	observer.Flush()
+
+	observer.OnUpdate(&nodeTypes.Node{Name: "node-a"})
+	observer.OnDelete(&nodeTypes.Node{Name: "node-a"})
+
+	if len(manager.revisions) != 2 || manager.revisions[0] != 0 || manager.revisions[1] != 0 {
+		t.Fatalf("expected a zero revision for plain OnUpdate/OnDelete, got %v", manager.revisions)
+	}
+}
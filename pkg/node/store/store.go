@@ -16,10 +16,14 @@ package store
 
 import (
 	"context"
+	"fmt"
 	"path"
+	"time"
 
+	"github.com/cilium/cilium/pkg/backoff"
 	"github.com/cilium/cilium/pkg/kvstore"
 	"github.com/cilium/cilium/pkg/kvstore/store"
+	"github.com/cilium/cilium/pkg/lock"
 	nodeTypes "github.com/cilium/cilium/pkg/node/types"
 	"github.com/cilium/cilium/pkg/option"
 	"github.com/cilium/cilium/pkg/source"
@@ -39,32 +43,290 @@ var (
 	}
 )
 
+// NodeManagerBatch is an optional NodeManager extension. A NodeManager that
+// implements it receives the full initial set of nodes retrieved from the
+// kvstore as a single NodesBatchUpdated call, instead of one NodeUpdated
+// call per node, letting it build its state in one pass.
+type NodeManagerBatch interface {
+	// NodesBatchUpdated is called once, with every node found during the
+	// initial synchronization with the kvstore.
+	NodesBatchUpdated(nodes []nodeTypes.Node)
+}
+
+// NodeManagerSyncCompleted is an optional NodeManager extension. A
+// NodeManager that implements it is notified once the initial listing from a
+// node shared store has completed, so it can distinguish "I've seen every
+// node that existed at startup" from "still catching up".
+type NodeManagerSyncCompleted interface {
+	// NodeSyncCompleted is called once, after the initial node store listing
+	// has completed and any buffered updates have been delivered.
+	NodeSyncCompleted()
+}
+
+// NodeManagerRevisions is an optional NodeManager extension. A NodeManager
+// that implements it receives the kvstore modification revision associated
+// with each node update/deletion alongside the node itself, in place of the
+// plain NodeUpdated/NodeDeleted calls, letting consumers doing incremental
+// reconciliation detect gaps and order events across nodes. rev is zero when
+// the underlying backend could not determine a revision for the change; see
+// store.RevisionObserver.
+type NodeManagerRevisions interface {
+	// NodeUpdatedRevision is called instead of NodeUpdated when the manager
+	// implements NodeManagerRevisions.
+	NodeUpdatedRevision(n nodeTypes.Node, rev uint64)
+
+	// NodeDeletedRevision is called instead of NodeDeleted when the manager
+	// implements NodeManagerRevisions.
+	NodeDeletedRevision(n nodeTypes.Node, rev uint64)
+}
+
+// NodeGetter is implemented by any Key produced by a custom store.KeyCreator
+// passed to NewNodeObserver or RegisterNode, so that a downstream type
+// embedding a nodeTypes.Node alongside additional, implementation-specific
+// fields can still be unwrapped to the canonical node NodeObserver delivers
+// to the NodeManager.
+type NodeGetter interface {
+	// GetNode returns the canonical node carried by the key.
+	GetNode() *nodeTypes.Node
+}
+
+// toNode extracts the canonical *nodeTypes.Node carried by k: either k
+// itself, for the default KeyCreator, or the result of GetNode, for a key
+// produced by a custom KeyCreator implementing NodeGetter. Returns nil if k
+// is neither.
+func toNode(k interface{}) *nodeTypes.Node {
+	switch v := k.(type) {
+	case *nodeTypes.Node:
+		return v
+	case NodeGetter:
+		return v.GetNode()
+	default:
+		return nil
+	}
+}
+
 // NodeObserver implements the store.Observer interface and delegates update
-// and deletion events to the node object itself.
+// and deletion events to the node object itself. Updates observed while
+// buffering is true are held back and delivered together by Flush, instead
+// of being forwarded one at a time as they're observed.
 type NodeObserver struct {
 	manager NodeManager
+
+	// keyCreator is the KeyCreator this observer expects entries in its
+	// shared store to be decoded with. It defaults to the package-level
+	// KeyCreator, but can be overridden via NewNodeObserver so downstreams
+	// can preserve extended node metadata through a custom Key type; see
+	// NodeGetter.
+	keyCreator store.KeyCreator
+
+	mutex     lock.Mutex
+	buffering bool
+	buffered  []bufferedNodeUpdate
+}
+
+// bufferedNodeUpdate is a node update observed while buffering is true,
+// along with the kvstore modification revision it was observed at, if any.
+type bufferedNodeUpdate struct {
+	node nodeTypes.Node
+	rev  uint64
 }
 
 // NewNodeObserver returns a new NodeObserver associated with the specified
-// node manager
-func NewNodeObserver(manager NodeManager) *NodeObserver {
-	return &NodeObserver{manager: manager}
+// node manager. Updates are buffered until Flush is called, so that the
+// initial set of nodes retrieved from the kvstore can be delivered in one
+// pass; call Flush once the initial kvstore listing has completed.
+//
+// creator, if given, overrides the KeyCreator used to decode entries in the
+// shared store this observer is attached to; the decoded Key must implement
+// NodeGetter unless it is itself a *nodeTypes.Node. It defaults to the
+// package-level KeyCreator.
+func NewNodeObserver(manager NodeManager, creator ...store.KeyCreator) *NodeObserver {
+	kc := KeyCreator
+	if len(creator) > 0 && creator[0] != nil {
+		kc = creator[0]
+	}
+	return &NodeObserver{manager: manager, buffering: true, keyCreator: kc}
 }
 
+// KeyCreator returns the KeyCreator this observer was constructed with, for
+// callers that join a shared store on the observer's behalf (e.g.
+// RegisterNode) and need to configure the same one.
+func (o *NodeObserver) KeyCreator() store.KeyCreator {
+	return o.keyCreator
+}
+
+// OnUpdate tags the node with source.KVStore and forwards it to the manager
+// unconditionally; it does not itself enforce source precedence. Tagging the
+// source is what lets NodeManager.NodeUpdated apply source.AllowOverwrite
+// consistently across all of its callers, so a kvstore update here can never
+// clobber a node entry already owned by a higher-precedence source such as
+// source.Local.
 func (o *NodeObserver) OnUpdate(k store.Key) {
-	if n, ok := k.(*nodeTypes.Node); ok {
-		nodeCopy := n.DeepCopy()
-		nodeCopy.Source = source.KVStore
-		o.manager.NodeUpdated(*nodeCopy)
+	o.onUpdate(k, 0)
+}
+
+// OnUpdateRevision is OnUpdate, plus the kvstore modification revision
+// associated with the change; see NodeManagerRevisions.
+func (o *NodeObserver) OnUpdateRevision(k store.Key, rev uint64) {
+	o.onUpdate(k, rev)
+}
+
+func (o *NodeObserver) onUpdate(k store.Key, rev uint64) {
+	n := toNode(k)
+	if n == nil {
+		return
 	}
+	nodeCopy := n.DeepCopy()
+	nodeCopy.Source = source.KVStore
+
+	o.mutex.Lock()
+	if o.buffering {
+		o.buffered = append(o.buffered, bufferedNodeUpdate{node: *nodeCopy, rev: rev})
+		o.mutex.Unlock()
+		return
+	}
+	o.mutex.Unlock()
+
+	o.deliverUpdate(*nodeCopy, rev)
 }
 
 func (o *NodeObserver) OnDelete(k store.NamedKey) {
-	if n, ok := k.(*nodeTypes.Node); ok {
-		nodeCopy := n.DeepCopy()
-		nodeCopy.Source = source.KVStore
-		o.manager.NodeDeleted(*nodeCopy)
+	o.onDelete(k, 0)
+}
+
+// OnDeleteRevision is OnDelete, plus the kvstore modification revision
+// associated with the change; see NodeManagerRevisions.
+func (o *NodeObserver) OnDeleteRevision(k store.NamedKey, rev uint64) {
+	o.onDelete(k, rev)
+}
+
+func (o *NodeObserver) onDelete(k store.NamedKey, rev uint64) {
+	n := toNode(k)
+	if n == nil {
+		return
 	}
+	nodeCopy := n.DeepCopy()
+	nodeCopy.Source = source.KVStore
+	o.deliverDelete(*nodeCopy, rev)
+}
+
+// deliverUpdate delivers n to the manager, as NodeUpdatedRevision if it
+// implements NodeManagerRevisions, or NodeUpdated otherwise.
+func (o *NodeObserver) deliverUpdate(n nodeTypes.Node, rev uint64) {
+	if rm, ok := o.manager.(NodeManagerRevisions); ok {
+		rm.NodeUpdatedRevision(n, rev)
+		return
+	}
+	o.manager.NodeUpdated(n)
+}
+
+// deliverDelete delivers n to the manager, as NodeDeletedRevision if it
+// implements NodeManagerRevisions, or NodeDeleted otherwise.
+func (o *NodeObserver) deliverDelete(n nodeTypes.Node, rev uint64) {
+	if rm, ok := o.manager.(NodeManagerRevisions); ok {
+		rm.NodeDeletedRevision(n, rev)
+		return
+	}
+	o.manager.NodeDeleted(n)
+}
+
+// Flush ends the initial buffering period and delivers any nodes observed
+// during it: as a single NodesBatchUpdated call if the manager implements
+// NodeManagerBatch, or via one NodeUpdated call per node otherwise
+// (NodeUpdatedRevision if the manager implements NodeManagerRevisions).
+// Updates observed after Flush returns are delivered immediately, the same
+// way, as usual. If the manager implements NodeManagerSyncCompleted, NodeSyncCompleted
+// is called last, even if the initial listing was empty. Must be called
+// once, after the initial kvstore listing completes.
+func (o *NodeObserver) Flush() {
+	o.mutex.Lock()
+	buffered := o.buffered
+	o.buffered = nil
+	o.buffering = false
+	o.mutex.Unlock()
+
+	if len(buffered) > 0 {
+		if batch, ok := o.manager.(NodeManagerBatch); ok {
+			nodes := make([]nodeTypes.Node, len(buffered))
+			for i, b := range buffered {
+				nodes[i] = b.node
+			}
+			batch.NodesBatchUpdated(nodes)
+		} else {
+			for _, b := range buffered {
+				o.deliverUpdate(b.node, b.rev)
+			}
+		}
+	}
+
+	if synced, ok := o.manager.(NodeManagerSyncCompleted); ok {
+		synced.NodeSyncCompleted()
+	}
+}
+
+// NodeEventType identifies the kind of change delivered over a node
+// subscription's channel.
+type NodeEventType int
+
+const (
+	// NodeUpdated indicates the node was created or updated.
+	NodeUpdated NodeEventType = iota
+	// NodeDeleted indicates the node was removed from the store.
+	NodeDeleted
+)
+
+// NodeEvent is a single change to a node observed through the shared store.
+type NodeEvent struct {
+	Type NodeEventType
+	Node nodeTypes.Node
+}
+
+// nodeSubscription is a NodeManager that forwards every update and deletion
+// as a NodeEvent on a channel, until the context it was created with is
+// cancelled.
+type nodeSubscription struct {
+	ctx    context.Context
+	events chan NodeEvent
+}
+
+// SubscribeNodeEvents returns a NodeManager whose NodeUpdated and
+// NodeDeleted calls are delivered as NodeEvents on the returned channel,
+// and a context.CancelFunc that can be used to unsubscribe early.
+//
+// The returned channel is closed once ctx is done, so that callers which
+// only want to observe nodes for the lifetime of a request do not have to
+// thread an explicit unsubscribe call through RegisterNode / SharedStore.
+func SubscribeNodeEvents(ctx context.Context) (NodeManager, <-chan NodeEvent) {
+	sub := &nodeSubscription{
+		ctx:    ctx,
+		events: make(chan NodeEvent),
+	}
+
+	go func() {
+		<-ctx.Done()
+		close(sub.events)
+	}()
+
+	return sub, sub.events
+}
+
+func (s *nodeSubscription) send(ev NodeEvent) {
+	select {
+	case s.events <- ev:
+	case <-s.ctx.Done():
+	}
+}
+
+func (s *nodeSubscription) NodeUpdated(n nodeTypes.Node) {
+	s.send(NodeEvent{Type: NodeUpdated, Node: n})
+}
+
+func (s *nodeSubscription) NodeDeleted(n nodeTypes.Node) {
+	s.send(NodeEvent{Type: NodeDeleted, Node: n})
+}
+
+func (s *nodeSubscription) Exists(id nodeTypes.Identity) bool {
+	return false
 }
 
 // NodeRegistrar is a wrapper around store.SharedStore.
@@ -72,6 +334,118 @@ type NodeRegistrar struct {
 	*store.SharedStore
 }
 
+// MinNodesSignal wraps a NodeManager, tracking the number of distinct nodes
+// observed through it, so that a caller can block until either a minimum
+// node count is reached or the shared store's initial sync has completed
+// (meaning every node that existed at startup has now been observed, even
+// if that is fewer than minNodes). This lets a controller that shouldn't
+// act on a partial cluster view wait for a representative one, instead of
+// hardcoding an assumption about startup ordering relative to other
+// subsystems.
+//
+// A caveat: MinNodesSignal does not implement NodeManagerRevisions, so
+// wrapping a manager that does causes it to receive plain NodeUpdated and
+// NodeDeleted calls instead, without revision information. This is fine for
+// the readiness-only use case MinNodesSignal is meant for, but means it
+// should not be used to wrap a manager whose caller actually needs
+// revisions.
+type MinNodesSignal struct {
+	NodeManager
+	minNodes int
+
+	mutex lock.Mutex
+	seen  map[nodeTypes.Identity]struct{}
+	ready chan struct{}
+	fired bool
+}
+
+// WithMinimumNodesReady wraps manager so that the returned MinNodesSignal's
+// Ready channel closes once minNodes distinct nodes have been observed
+// through it, or the underlying shared store's initial sync completes,
+// whichever happens first. Pass the result to NodeRegistrar.RegisterNode (or
+// store.JoinSharedStore via NewNodeObserver) in place of manager.
+func WithMinimumNodesReady(manager NodeManager, minNodes int) *MinNodesSignal {
+	return &MinNodesSignal{
+		NodeManager: manager,
+		minNodes:    minNodes,
+		seen:        make(map[nodeTypes.Identity]struct{}),
+		ready:       make(chan struct{}),
+	}
+}
+
+// Ready returns a channel that is closed once the minimum node count given
+// to WithMinimumNodesReady has been observed, or the initial sync has
+// completed, whichever happens first.
+func (s *MinNodesSignal) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// NodeUpdated records n as observed before forwarding the call to the
+// wrapped manager.
+func (s *MinNodesSignal) NodeUpdated(n nodeTypes.Node) {
+	s.observe(n.Identity())
+	s.NodeManager.NodeUpdated(n)
+}
+
+// NodeDeleted stops counting n as observed before forwarding the call to
+// the wrapped manager.
+func (s *MinNodesSignal) NodeDeleted(n nodeTypes.Node) {
+	s.mutex.Lock()
+	delete(s.seen, n.Identity())
+	s.mutex.Unlock()
+	s.NodeManager.NodeDeleted(n)
+}
+
+// NodesBatchUpdated implements NodeManagerBatch, so that Flush delivers the
+// initial node listing to MinNodesSignal in one pass like it would to the
+// wrapped manager directly. It records every node as observed, then
+// forwards the batch to the wrapped manager if it also implements
+// NodeManagerBatch, or as individual NodeUpdated calls otherwise.
+func (s *MinNodesSignal) NodesBatchUpdated(nodes []nodeTypes.Node) {
+	for _, n := range nodes {
+		s.observe(n.Identity())
+	}
+	if batch, ok := s.NodeManager.(NodeManagerBatch); ok {
+		batch.NodesBatchUpdated(nodes)
+		return
+	}
+	for _, n := range nodes {
+		s.NodeManager.NodeUpdated(n)
+	}
+}
+
+// NodeSyncCompleted implements NodeManagerSyncCompleted, firing the
+// readiness signal even if minNodes was never reached, then forwarding the
+// call to the wrapped manager if it also implements NodeManagerSyncCompleted.
+func (s *MinNodesSignal) NodeSyncCompleted() {
+	s.fire()
+	if synced, ok := s.NodeManager.(NodeManagerSyncCompleted); ok {
+		synced.NodeSyncCompleted()
+	}
+}
+
+// observe records id as currently seen, firing the readiness signal if that
+// brings the observed count to minNodes.
+func (s *MinNodesSignal) observe(id nodeTypes.Identity) {
+	s.mutex.Lock()
+	s.seen[id] = struct{}{}
+	reached := len(s.seen) >= s.minNodes
+	s.mutex.Unlock()
+	if reached {
+		s.fire()
+	}
+}
+
+// fire closes the ready channel, if it hasn't already been closed.
+func (s *MinNodesSignal) fire() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.fired {
+		s.fired = true
+		close(s.ready)
+	}
+}
+
 // NodeManager is the interface that the manager of nodes has to implement
 type NodeManager interface {
 	// NodeUpdated is called when the store detects a change in node
@@ -85,35 +459,171 @@ type NodeManager interface {
 	Exists(id nodeTypes.Identity) bool
 }
 
-// RegisterNode registers the local node in the cluster
-func (nr *NodeRegistrar) RegisterNode(n *nodeTypes.Node, manager NodeManager) error {
-	if option.Config.KVStore == "" {
+// nodeIdentityWaitTimeout bounds how long RegisterNode waits for the local
+// node's own write to be observed back through the shared store watcher,
+// confirming the kvstore has accepted it and is propagating it to
+// collaborators. It is a var rather than a const so tests can shorten it.
+var nodeIdentityWaitTimeout = 30 * time.Second
+
+// nodeIdentityPollInterval is how often RegisterNode polls manager.Exists
+// while waiting for the local node identity to be allocated.
+var nodeIdentityPollInterval = 50 * time.Millisecond
+
+// waitForNodeIdentity blocks until manager reports the local node as
+// existing, ctx is canceled, or nodeIdentityWaitTimeout elapses, whichever
+// comes first.
+func waitForNodeIdentity(ctx context.Context, n *nodeTypes.Node, manager NodeManager) error {
+	id := n.Identity()
+	if manager.Exists(id) {
 		return nil
 	}
 
+	ctx, cancel := context.WithTimeout(ctx, nodeIdentityWaitTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(nodeIdentityPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if manager.Exists(id) {
+				return nil
+			}
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for node identity %s to be allocated by the kvstore: %w", id, ctx.Err())
+		}
+	}
+}
+
+// maxRegisterAttempts bounds how many join-and-initial-sync attempts
+// RegisterNode makes in all, the first one included. There's no way to tell a transient kvstore
+// outage apart from a permanent misconfiguration (neither kvstore errors nor
+// store.Configuration validation errors are typed for this), so a bounded
+// number of attempts, rather than error classification, is what keeps a
+// genuinely broken configuration from retrying forever.
+const maxRegisterAttempts = 5
+
+// registerBackoff is the backoff used between failed RegisterNode attempts.
+// It is a var, as a template copied per call, so a name can be attached for
+// logging and tests can substitute a faster one.
+var registerBackoff = backoff.Exponential{
+	Min:    100 * time.Millisecond,
+	Factor: 2.0,
+}
+
+// registerAttempt performs a single join-and-initial-sync attempt for n,
+// returning the joined store on success. It is a package variable purely so
+// tests can substitute a fake that fails a bounded number of times before
+// succeeding, without needing a real kvstore.
+var registerAttempt = func(ctx context.Context, n *nodeTypes.Node, manager NodeManager, creator store.KeyCreator) (*store.SharedStore, error) {
 	// Join the shared store holding node information of entire cluster
-	store, err := store.JoinSharedStore(store.Configuration{
+	observer := NewNodeObserver(manager, creator)
+	joinStart := time.Now()
+	registerStore, err := store.JoinSharedStore(store.Configuration{
 		Prefix:     NodeStorePrefix,
-		KeyCreator: KeyCreator,
-		Observer:   NewNodeObserver(manager),
+		KeyCreator: observer.KeyCreator(),
+		Observer:   observer,
 	})
+	observeJoinDuration(storeRegister, time.Since(joinStart))
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if err = store.UpdateLocalKeySync(context.TODO(), n); err != nil {
-		store.Release()
+	// JoinSharedStore only returns once the initial listing from the kvstore
+	// has completed, so every node observed up to this point belongs to that
+	// initial set.
+	observer.Flush()
+
+	if err := registerStore.UpdateLocalKeySync(ctx, n); err != nil {
+		recordSyncError(storeRegister)
+		registerStore.Release()
+		return nil, err
+	}
+
+	return registerStore, nil
+}
+
+// RegisterNode registers the local node in the cluster. ctx bounds how long
+// registration waits on a slow or hung kvstore; if it's canceled before the
+// local key is synced, the joined store is released before the error is
+// returned, so no dangling registration is left behind. A transient failure
+// to join or perform the initial sync is retried with exponential backoff,
+// up to maxRegisterAttempts, releasing the store between failed attempts so
+// no handle is left behind. Once the local key has been synced, RegisterNode
+// waits for the node identity to have been allocated by the kvstore, i.e.
+// for the local node to be observed back through the shared store watcher,
+// so that callers don't race ahead of other subsystems that depend on the
+// node being visible cluster-wide.
+//
+// creator, if given, overrides the KeyCreator used to decode entries in the
+// node shared store, so that downstreams can preserve extended node
+// metadata through a custom Key type; see NodeGetter. It defaults to the
+// package-level KeyCreator.
+func (nr *NodeRegistrar) RegisterNode(ctx context.Context, n *nodeTypes.Node, manager NodeManager, creator ...store.KeyCreator) error {
+	if option.Config.KVStore == "" {
+		return nil
+	}
+
+	var kc store.KeyCreator
+	if len(creator) > 0 {
+		kc = creator[0]
+	}
+
+	boff := registerBackoff
+	boff.Name = n.Name
+
+	var registerStore *store.SharedStore
+	var err error
+	for attempt := 0; attempt < maxRegisterAttempts; attempt++ {
+		if registerStore, err = registerAttempt(ctx, n, manager, kc); err == nil {
+			break
+		}
+
+		if waitErr := boff.Wait(ctx); waitErr != nil {
+			return fmt.Errorf("registering node %s: %w", n.Name, err)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("registering node %s: giving up after %d attempts: %w", n.Name, maxRegisterAttempts, err)
+	}
+
+	if err := waitForNodeIdentity(ctx, n, manager); err != nil {
+		registerStore.Release()
 		return err
 	}
 
-	nr.SharedStore = store
+	nr.SharedStore = registerStore
 
 	return nil
 }
 
 // UpdateLocalKeySync synchronizes the local key for the node using the
 // SharedStore.
-func (nr *NodeRegistrar) UpdateLocalKeySync(n *nodeTypes.Node) error {
-	return nr.SharedStore.UpdateLocalKeySync(context.TODO(), n)
+func (nr *NodeRegistrar) UpdateLocalKeySync(ctx context.Context, n *nodeTypes.Node) error {
+	if err := nr.SharedStore.UpdateLocalKeySync(ctx, n); err != nil {
+		recordSyncError(storeMain)
+		return err
+	}
+	return nil
+}
+
+// DeregisterNode removes the local node's key from the shared store and
+// stops participating in it, so that the node's entry doesn't linger in the
+// kvstore until its lease expires, e.g. during a graceful shutdown. It is a
+// no-op if RegisterNode was never called or has not completed successfully.
+//
+// Key deletion failures are logged by the underlying SharedStore rather than
+// returned, so DeregisterNode always returns nil today; it returns an error
+// to leave room for that to change without an API break.
+func (nr *NodeRegistrar) DeregisterNode(ctx context.Context) error {
+	if nr.SharedStore == nil {
+		return nil
+	}
+
+	nr.SharedStore.Close(ctx)
+	nr.SharedStore = nil
+
+	return nil
 }
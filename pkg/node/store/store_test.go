@@ -0,0 +1,429 @@
+// Copyright 2018-2019 Authors of Cilium
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package store
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/cilium/cilium/pkg/backoff"
+	"github.com/cilium/cilium/pkg/kvstore/store"
+	"github.com/cilium/cilium/pkg/lock"
+	nodeTypes "github.com/cilium/cilium/pkg/node/types"
+	"github.com/cilium/cilium/pkg/option"
+)
+
+func TestSubscribeNodeEventsCancel(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	manager, events := SubscribeNodeEvents(ctx)
+
+	go manager.NodeUpdated(nodeTypes.Node{Name: "foo"})
+	select {
+	case ev := <-events:
+		if ev.Type != NodeUpdated || ev.Node.Name != "foo" {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for node event")
+	}
+
+	cancel()
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("expected events channel to be closed after cancellation")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for events channel to close")
+	}
+
+	// Give the goroutine that closes the channel a chance to exit before
+	// checking that it didn't leak.
+	for i := 0; i < 100; i++ {
+		if runtime.NumGoroutine() <= before {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := runtime.NumGoroutine(); got > before {
+		t.Errorf("expected no leaked goroutines, started with %d, now at %d", before, got)
+	}
+}
+
+// TestSubscribeNodeEventsDrainBeforeDelete verifies that a node transitioning
+// to the draining state is delivered as a node update before the eventual
+// deletion, so subscribers have a chance to shift traffic away first.
+func TestSubscribeNodeEventsDrainBeforeDelete(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	manager, events := SubscribeNodeEvents(ctx)
+
+	go func() {
+		manager.NodeUpdated(nodeTypes.Node{Name: "foo", Draining: true})
+		manager.NodeDeleted(nodeTypes.Node{Name: "foo"})
+	}()
+
+	select {
+	case ev := <-events:
+		if ev.Type != NodeUpdated || !ev.Node.Draining {
+			t.Fatalf("expected a draining node update first, got: %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the draining update")
+	}
+
+	select {
+	case ev := <-events:
+		if ev.Type != NodeDeleted {
+			t.Fatalf("expected the node deletion to follow the drain update, got: %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the node deletion")
+	}
+}
+
+// existsNodeManager is a NodeManager whose Exists result can be toggled at
+// runtime, to drive waitForNodeIdentity's polling loop in tests.
+type existsNodeManager struct {
+	mutex  lock.Mutex
+	exists bool
+}
+
+func (m *existsNodeManager) NodeUpdated(n nodeTypes.Node) {}
+func (m *existsNodeManager) NodeDeleted(n nodeTypes.Node) {}
+func (m *existsNodeManager) Exists(id nodeTypes.Identity) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.exists
+}
+
+func (m *existsNodeManager) setExists(exists bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.exists = exists
+}
+
+// withShortNodeIdentityWait lowers the poll interval and timeout used by
+// waitForNodeIdentity for the duration of a test, restoring them afterwards.
+func withShortNodeIdentityWait(t *testing.T, timeout time.Duration) {
+	t.Helper()
+	prevTimeout, prevInterval := nodeIdentityWaitTimeout, nodeIdentityPollInterval
+	nodeIdentityWaitTimeout = timeout
+	nodeIdentityPollInterval = time.Millisecond
+	t.Cleanup(func() {
+		nodeIdentityWaitTimeout = prevTimeout
+		nodeIdentityPollInterval = prevInterval
+	})
+}
+
+// TestWaitForNodeIdentitySuccess verifies that waitForNodeIdentity returns as
+// soon as the manager reports the local node as existing.
+func TestWaitForNodeIdentitySuccess(t *testing.T) {
+	withShortNodeIdentityWait(t, time.Second)
+
+	manager := &existsNodeManager{}
+	n := &nodeTypes.Node{Name: "foo", Cluster: "default"}
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		manager.setExists(true)
+	}()
+
+	if err := waitForNodeIdentity(context.Background(), n, manager); err != nil {
+		t.Fatalf("expected waitForNodeIdentity to succeed, got: %v", err)
+	}
+}
+
+// TestWaitForNodeIdentityTimeout verifies that waitForNodeIdentity gives up
+// and returns an error once its timeout elapses, if the manager never
+// reports the local node as existing.
+func TestWaitForNodeIdentityTimeout(t *testing.T) {
+	withShortNodeIdentityWait(t, 20*time.Millisecond)
+
+	manager := &existsNodeManager{}
+	n := &nodeTypes.Node{Name: "foo", Cluster: "default"}
+
+	if err := waitForNodeIdentity(context.Background(), n, manager); err == nil {
+		t.Fatal("expected waitForNodeIdentity to time out")
+	}
+}
+
+// batchingNodeManager is a NodeManager that also implements
+// NodeManagerBatch and NodeManagerSyncCompleted, recording individual and
+// batched updates, and sync completions, separately.
+type batchingNodeManager struct {
+	updates     []nodeTypes.Node
+	batchCalls  int
+	batchedSize int
+	syncedCalls int
+}
+
+func (m *batchingNodeManager) NodeUpdated(n nodeTypes.Node)      { m.updates = append(m.updates, n) }
+func (m *batchingNodeManager) NodeDeleted(n nodeTypes.Node)      {}
+func (m *batchingNodeManager) Exists(id nodeTypes.Identity) bool { return false }
+func (m *batchingNodeManager) NodesBatchUpdated(nodes []nodeTypes.Node) {
+	m.batchCalls++
+	m.batchedSize = len(nodes)
+}
+func (m *batchingNodeManager) NodeSyncCompleted() { m.syncedCalls++ }
+
+// TestNodeObserverBatchesInitialSync verifies that node updates observed
+// before Flush is called are delivered as a single NodesBatchUpdated call
+// when the manager implements NodeManagerBatch, and that updates observed
+// after Flush are delivered individually as before.
+func TestNodeObserverBatchesInitialSync(t *testing.T) {
+	manager := &batchingNodeManager{}
+	observer := NewNodeObserver(manager)
+
+	observer.OnUpdate(&nodeTypes.Node{Name: "node-a"})
+	observer.OnUpdate(&nodeTypes.Node{Name: "node-b"})
+	observer.OnUpdate(&nodeTypes.Node{Name: "node-c"})
+
+	if len(manager.updates) != 0 {
+		t.Fatalf("expected no individual updates before Flush, got %d", len(manager.updates))
+	}
+
+	observer.Flush()
+
+	if manager.batchCalls != 1 {
+		t.Fatalf("expected exactly one batched call, got %d", manager.batchCalls)
+	}
+	if manager.batchedSize != 3 {
+		t.Fatalf("expected the batch to contain all 3 nodes, got %d", manager.batchedSize)
+	}
+	if len(manager.updates) != 0 {
+		t.Fatalf("expected no individual updates for the initial set, got %d", len(manager.updates))
+	}
+
+	observer.OnUpdate(&nodeTypes.Node{Name: "node-d"})
+	if len(manager.updates) != 1 || manager.updates[0].Name != "node-d" {
+		t.Fatalf("expected the post-Flush update to be delivered individually, got %+v", manager.updates)
+	}
+}
+
+// TestNodeObserverFlushNotifiesSyncCompleted verifies that Flush notifies a
+// NodeManagerSyncCompleted manager exactly once, after any buffered updates
+// have been delivered, and that it does so even when the initial listing was
+// empty.
+func TestNodeObserverFlushNotifiesSyncCompleted(t *testing.T) {
+	manager := &batchingNodeManager{}
+	observer := NewNodeObserver(manager)
+
+	observer.OnUpdate(&nodeTypes.Node{Name: "node-a"})
+	observer.Flush()
+
+	if manager.syncedCalls != 1 {
+		t.Fatalf("expected NodeSyncCompleted to be called exactly once, got %d", manager.syncedCalls)
+	}
+	if manager.batchCalls != 1 {
+		t.Fatalf("expected the buffered update to still be delivered before the sync completion, got %d batch calls", manager.batchCalls)
+	}
+
+	// An empty initial listing must still signal sync completion.
+	emptyManager := &batchingNodeManager{}
+	NewNodeObserver(emptyManager).Flush()
+	if emptyManager.syncedCalls != 1 {
+		t.Fatalf("expected NodeSyncCompleted to fire even with no buffered nodes, got %d", emptyManager.syncedCalls)
+	}
+}
+
+// TestNodeObserverFallsBackWithoutBatchExtension verifies that, when the
+// manager doesn't implement NodeManagerBatch, buffered updates are delivered
+// one at a time via NodeUpdated instead.
+func TestNodeObserverFallsBackWithoutBatchExtension(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	manager, events := SubscribeNodeEvents(ctx)
+
+	observer := NewNodeObserver(manager)
+	observer.OnUpdate(&nodeTypes.Node{Name: "node-a"})
+	observer.OnUpdate(&nodeTypes.Node{Name: "node-b"})
+
+	go observer.Flush()
+
+	seen := make(map[string]bool)
+	for i := 0; i < 2; i++ {
+		select {
+		case ev := <-events:
+			if ev.Type != NodeUpdated {
+				t.Fatalf("unexpected event type: %+v", ev)
+			}
+			seen[ev.Node.Name] = true
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for a fallback node update")
+		}
+	}
+	if !seen["node-a"] || !seen["node-b"] {
+		t.Fatalf("expected both buffered nodes to be delivered individually, got %+v", seen)
+	}
+}
+
+// withFastRegisterBackoff shortens the backoff between RegisterNode retry
+// attempts for the duration of a test, restoring it afterwards.
+func withFastRegisterBackoff(t *testing.T) {
+	t.Helper()
+	prev := registerBackoff
+	registerBackoff = backoff.Exponential{Min: time.Millisecond, Factor: 2.0}
+	t.Cleanup(func() { registerBackoff = prev })
+}
+
+// TestRegisterNodeRetriesTransientFailures verifies that RegisterNode
+// retries a failing join-and-initial-sync attempt with backoff and succeeds
+// once the underlying operation does, registering the successful attempt's
+// store.
+func TestRegisterNodeRetriesTransientFailures(t *testing.T) {
+	withFastRegisterBackoff(t)
+
+	prevKVStore := option.Config.KVStore
+	option.Config.KVStore = "etcd"
+	t.Cleanup(func() { option.Config.KVStore = prevKVStore })
+
+	prevAttempt := registerAttempt
+	t.Cleanup(func() { registerAttempt = prevAttempt })
+
+	var calls int
+	var failed int
+	succeeded := &store.SharedStore{}
+	registerAttempt = func(ctx context.Context, n *nodeTypes.Node, manager NodeManager, creator store.KeyCreator) (*store.SharedStore, error) {
+		calls++
+		if calls <= 2 {
+			failed++
+			return nil, fmt.Errorf("simulated transient kvstore failure")
+		}
+		return succeeded, nil
+	}
+
+	nr := &NodeRegistrar{}
+	manager := &existsNodeManager{exists: true}
+	n := &nodeTypes.Node{Name: "foo", Cluster: "default"}
+
+	if err := nr.RegisterNode(context.Background(), n, manager); err != nil {
+		t.Fatalf("expected RegisterNode to eventually succeed, got: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts (2 failures then a success), got %d", calls)
+	}
+	if failed != 2 {
+		t.Fatalf("expected 2 failed attempts before the success, got %d", failed)
+	}
+	if nr.SharedStore != succeeded {
+		t.Fatalf("expected the successful attempt's store to be registered")
+	}
+}
+
+// TestRegisterNodeGivesUpAfterMaxAttempts verifies that RegisterNode stops
+// retrying and returns an error once a failing join-and-initial-sync attempt
+// has been made maxRegisterAttempts times in all.
+func TestRegisterNodeGivesUpAfterMaxAttempts(t *testing.T) {
+	withFastRegisterBackoff(t)
+
+	prevKVStore := option.Config.KVStore
+	option.Config.KVStore = "etcd"
+	t.Cleanup(func() { option.Config.KVStore = prevKVStore })
+
+	prevAttempt := registerAttempt
+	t.Cleanup(func() { registerAttempt = prevAttempt })
+
+	var calls int
+	registerAttempt = func(ctx context.Context, n *nodeTypes.Node, manager NodeManager, creator store.KeyCreator) (*store.SharedStore, error) {
+		calls++
+		return nil, fmt.Errorf("simulated permanent kvstore failure")
+	}
+
+	nr := &NodeRegistrar{}
+	n := &nodeTypes.Node{Name: "foo", Cluster: "default"}
+
+	if err := nr.RegisterNode(context.Background(), n, &existsNodeManager{}); err == nil {
+		t.Fatal("expected RegisterNode to give up and return an error")
+	}
+	if calls != maxRegisterAttempts {
+		t.Fatalf("expected exactly %d attempts, got %d", maxRegisterAttempts, calls)
+	}
+}
+
+// TestMinNodesSignalFiresAtThreshold verifies that a MinNodesSignal's Ready
+// channel closes as soon as the configured minimum number of distinct nodes
+// has been observed, and not before, while still forwarding every update to
+// the wrapped manager.
+func TestMinNodesSignalFiresAtThreshold(t *testing.T) {
+	manager := &batchingNodeManager{}
+	signal := WithMinimumNodesReady(manager, 3)
+
+	signal.NodeUpdated(nodeTypes.Node{Name: "node-a"})
+	signal.NodeUpdated(nodeTypes.Node{Name: "node-b"})
+
+	select {
+	case <-signal.Ready():
+		t.Fatal("expected Ready to still be open below the threshold")
+	default:
+	}
+
+	signal.NodeUpdated(nodeTypes.Node{Name: "node-c"})
+
+	select {
+	case <-signal.Ready():
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for Ready to close at the threshold")
+	}
+
+	if len(manager.updates) != 3 {
+		t.Fatalf("expected every update to still be forwarded to the wrapped manager, got %d", len(manager.updates))
+	}
+}
+
+// TestMinNodesSignalFiresOnSyncCompleted verifies that a MinNodesSignal's
+// Ready channel closes once the initial sync completes, even if the minimum
+// node count was never reached.
+func TestMinNodesSignalFiresOnSyncCompleted(t *testing.T) {
+	manager := &batchingNodeManager{}
+	signal := WithMinimumNodesReady(manager, 10)
+
+	signal.NodeUpdated(nodeTypes.Node{Name: "node-a"})
+	signal.NodeSyncCompleted()
+
+	select {
+	case <-signal.Ready():
+	default:
+		t.Fatal("expected Ready to close once the initial sync completed")
+	}
+	if manager.syncedCalls != 1 {
+		t.Fatalf("expected NodeSyncCompleted to still be forwarded to the wrapped manager, got %d calls", manager.syncedCalls)
+	}
+}
+
+// TestDeregisterNodeReleasesStore verifies that DeregisterNode releases the
+// registered shared store and clears it, so a second call is a no-op.
+func TestDeregisterNodeReleasesStore(t *testing.T) {
+	nr := &NodeRegistrar{SharedStore: &store.SharedStore{}}
+
+	if err := nr.DeregisterNode(context.Background()); err != nil {
+		t.Fatalf("expected DeregisterNode to succeed, got: %v", err)
+	}
+	if nr.SharedStore != nil {
+		t.Fatal("expected SharedStore to be cleared after DeregisterNode")
+	}
+
+	// Calling it again, with no store registered, must not panic.
+	if err := nr.DeregisterNode(context.Background()); err != nil {
+		t.Fatalf("expected a second DeregisterNode call to be a no-op, got: %v", err)
+	}
+}
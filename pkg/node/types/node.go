@@ -171,6 +171,12 @@ type Node struct {
 
 	// Node labels
 	Labels map[string]string
+
+	// Draining is true when the node is being decommissioned and is expected
+	// to be deleted shortly. Observers of the shared node store receive this
+	// as a regular node update before the eventual deletion, so that they
+	// can shift traffic away from the node ahead of its abrupt removal.
+	Draining bool
 }
 
 // Fullname returns the node's full name including the cluster name if a
@@ -0,0 +1,105 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"testing"
+
+	fakeDatapath "github.com/cilium/cilium/pkg/datapath/fake"
+	"github.com/cilium/cilium/pkg/k8s"
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/lock"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+func newNodePortSvcCache(t *testing.T) (*k8s.ServiceCache, k8s.ServiceID) {
+	t.Helper()
+	svcCache := k8s.NewServiceCache(fakeDatapath.NewNodeAddressing())
+	k8sSvc := &slimcorev1.Service{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Spec: slimcorev1.ServiceSpec{
+			ClusterIP: "172.0.20.1",
+			Type:      slimcorev1.ServiceTypeNodePort,
+			Ports: []slimcorev1.ServicePort{
+				{Port: 80, Protocol: slimcorev1.ProtocolTCP},
+			},
+		},
+	}
+	svcCache.UpdateService(k8sSvc, lock.NewStoppableWaitGroup())
+	return &svcCache, k8s.ServiceID{Name: "foo", Namespace: "bar"}
+}
+
+// TestGetAndUpsertPolicySvcConfigClusterIPOnlyByDefault verifies that a
+// service-based LRP config with no additionalFrontendTypes ignores a
+// service's NodePort frontend, preserving the original ClusterIP-only
+// behavior.
+func TestGetAndUpsertPolicySvcConfigClusterIPOnlyByDefault(t *testing.T) {
+	svcCache, svcID := newNodePortSvcCache(t)
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	config := &LRPConfig{
+		id:           policyID{Name: "lrp", Namespace: "bar"},
+		lrpType:      lrpConfigTypeSvc,
+		frontendType: svcFrontendAll,
+		serviceID:    &svcID,
+	}
+	rpm.storePolicyConfig(*config)
+
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	rpm.getAndUpsertPolicySvcConfig(rpm.policyConfigs[config.id], svcCache, podStore)
+
+	stored := rpm.policyConfigs[config.id]
+	if len(stored.frontendMappings) != 0 {
+		t.Fatalf("expected no frontends for a NodePort service without additionalFrontendTypes, got %v", stored.frontendMappings)
+	}
+}
+
+// TestGetAndUpsertPolicySvcConfigAdditionalFrontendTypes verifies that
+// setting additionalFrontendTypes lets a service-based LRP config also
+// redirect a service's NodePort frontend.
+func TestGetAndUpsertPolicySvcConfigAdditionalFrontendTypes(t *testing.T) {
+	svcCache, svcID := newNodePortSvcCache(t)
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	config := &LRPConfig{
+		id:                      policyID{Name: "lrp", Namespace: "bar"},
+		lrpType:                 lrpConfigTypeSvc,
+		frontendType:            svcFrontendAll,
+		serviceID:               &svcID,
+		additionalFrontendTypes: []lb.SVCType{lb.SVCTypeNodePort},
+	}
+	rpm.storePolicyConfig(*config)
+
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	rpm.getAndUpsertPolicySvcConfig(rpm.policyConfigs[config.id], svcCache, podStore)
+
+	stored := rpm.policyConfigs[config.id]
+	if len(stored.frontendMappings) != 1 {
+		t.Fatalf("expected 1 frontend from the NodePort type, got %v", stored.frontendMappings)
+	}
+
+	// Re-running the upsert must not duplicate the frontend mapping.
+	rpm.getAndUpsertPolicySvcConfig(rpm.policyConfigs[config.id], svcCache, podStore)
+	stored = rpm.policyConfigs[config.id]
+	if len(stored.frontendMappings) != 1 {
+		t.Fatalf("expected frontend mappings to not duplicate across repeated calls, got %v", stored.frontendMappings)
+	}
+}
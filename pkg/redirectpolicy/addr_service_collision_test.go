@@ -0,0 +1,109 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	fakeDatapath "github.com/cilium/cilium/pkg/datapath/fake"
+	"github.com/cilium/cilium/pkg/k8s"
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/lock"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/cache"
+)
+
+// recordingHook is a logrus.Hook that records every entry fired at or above
+// its configured level, for tests that need to assert a warning was logged.
+type recordingHook struct {
+	levels  []logrus.Level
+	entries []*logrus.Entry
+}
+
+func (h *recordingHook) Levels() []logrus.Level { return h.levels }
+
+func (h *recordingHook) Fire(entry *logrus.Entry) error {
+	h.entries = append(h.entries, entry)
+	return nil
+}
+
+// TestOnAddServiceWarnsOnAddrPolicyFrontendCollision verifies that adding a
+// Kubernetes service whose ClusterIP collides with an address-matcher LRP's
+// frontend logs a warning, since that policy never references the service
+// by name and so wouldn't otherwise be reconciled by OnAddService at all.
+func TestOnAddServiceWarnsOnAddrPolicyFrontendCollision(t *testing.T) {
+	hook := &recordingHook{levels: []logrus.Level{logrus.WarnLevel}}
+	log.Logger.AddHook(hook)
+	defer func() {
+		hooks := log.Logger.Hooks[logrus.WarnLevel]
+		for i, h := range hooks {
+			if h == hook {
+				log.Logger.Hooks[logrus.WarnLevel] = append(hooks[:i], hooks[i+1:]...)
+				break
+			}
+		}
+	}()
+
+	svcCache := k8s.NewServiceCache(fakeDatapath.NewNodeAddressing())
+
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	addrConfig := LRPConfig{
+		id:           policyID{Name: "addr-lrp", Namespace: "bar"},
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("172.0.20.1"), 80, lb.ScopeExternal),
+		}},
+	}
+	if ok, err := rpm.AddRedirectPolicy(addrConfig, &svcCache, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	k8sSvc := &slimcorev1.Service{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Spec: slimcorev1.ServiceSpec{
+			ClusterIP: "172.0.20.1",
+			Type:      slimcorev1.ServiceTypeClusterIP,
+			Ports: []slimcorev1.ServicePort{
+				{Port: 80, Protocol: slimcorev1.ProtocolTCP},
+			},
+		},
+	}
+	svcID := svcCache.UpdateService(k8sSvc, lock.NewStoppableWaitGroup())
+
+	rpm.OnAddService(svcID, &svcCache, podStore)
+
+	found := false
+	for _, entry := range hook.entries {
+		if strings.Contains(entry.Message, "collides") {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatal("expected a warning about the colliding frontend to be logged")
+	}
+}
@@ -0,0 +1,80 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/vishvananda/netlink"
+)
+
+// linkGetter abstracts netlink access for resolving an interface-name
+// frontend to its primary address, so that it can be swapped out with a
+// fake implementation in tests.
+type linkGetter interface {
+	// LinkByName returns the link with the given name, or an error if it
+	// does not exist.
+	LinkByName(name string) (netlink.Link, error)
+	// AddrList returns the addresses configured on the given link for the
+	// given address family (netlink.FAMILY_V4, netlink.FAMILY_V6 or
+	// netlink.FAMILY_ALL).
+	AddrList(link netlink.Link, family int) ([]netlink.Addr, error)
+}
+
+// netlinkLinkGetter is the production linkGetter backed by vishvananda/netlink.
+type netlinkLinkGetter struct{}
+
+func (netlinkLinkGetter) LinkByName(name string) (netlink.Link, error) {
+	return netlink.LinkByName(name)
+}
+
+func (netlinkLinkGetter) AddrList(link netlink.Link, family int) ([]netlink.Addr, error) {
+	return netlink.AddrList(link, family)
+}
+
+// errInterfaceNotFound indicates that the requested interface does not
+// currently exist on the node. Callers are expected to defer resolution
+// until the interface shows up.
+type errInterfaceNotFound struct {
+	ifName string
+}
+
+func (e *errInterfaceNotFound) Error() string {
+	return fmt.Sprintf("interface %s not found", e.ifName)
+}
+
+// resolveInterfaceAddr returns the primary IP address (preferring IPv4,
+// then IPv6) currently assigned to the given interface.
+func resolveInterfaceAddr(lg linkGetter, ifName string) (net.IP, error) {
+	link, err := lg.LinkByName(ifName)
+	if err != nil {
+		return nil, &errInterfaceNotFound{ifName: ifName}
+	}
+
+	if addrs, err := lg.AddrList(link, netlink.FAMILY_V4); err == nil && len(addrs) > 0 {
+		return addrs[0].IP, nil
+	}
+
+	addrs, err := lg.AddrList(link, netlink.FAMILY_V6)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("interface %s has no addresses", ifName)
+	}
+
+	return addrs[0].IP, nil
+}
@@ -0,0 +1,141 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/cilium/cilium/pkg/loadbalancer"
+
+	"github.com/vishvananda/netlink"
+)
+
+// fakeLinkGetter is a fake linkGetter backed by an in-memory set of links and
+// their addresses, for use in tests.
+type fakeLinkGetter struct {
+	links map[string]netlink.Link
+	addrs map[string][]netlink.Addr
+}
+
+func newFakeLinkGetter() *fakeLinkGetter {
+	return &fakeLinkGetter{
+		links: make(map[string]netlink.Link),
+		addrs: make(map[string][]netlink.Addr),
+	}
+}
+
+func (f *fakeLinkGetter) addLink(name string, v4, v6 []net.IP) {
+	link := &netlink.Dummy{
+		LinkAttrs: netlink.LinkAttrs{Name: name},
+	}
+	f.links[name] = link
+
+	var addrs []netlink.Addr
+	for _, ip := range v4 {
+		addrs = append(addrs, netlink.Addr{IPNet: &net.IPNet{IP: ip, Mask: net.CIDRMask(32, 32)}})
+	}
+	f.addrs[fmt.Sprintf("%s-4", name)] = addrs
+
+	addrs = nil
+	for _, ip := range v6 {
+		addrs = append(addrs, netlink.Addr{IPNet: &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}})
+	}
+	f.addrs[fmt.Sprintf("%s-6", name)] = addrs
+}
+
+func (f *fakeLinkGetter) LinkByName(name string) (netlink.Link, error) {
+	link, ok := f.links[name]
+	if !ok {
+		return nil, fmt.Errorf("link %s not found", name)
+	}
+	return link, nil
+}
+
+func (f *fakeLinkGetter) AddrList(link netlink.Link, family int) ([]netlink.Addr, error) {
+	suffix := "4"
+	if family == netlink.FAMILY_V6 {
+		suffix = "6"
+	}
+	return f.addrs[fmt.Sprintf("%s-%s", link.Attrs().Name, suffix)], nil
+}
+
+func TestResolveInterfaceAddr(t *testing.T) {
+	lg := newFakeLinkGetter()
+	lg.addLink("eth0", []net.IP{net.ParseIP("10.0.0.1")}, []net.IP{net.ParseIP("fd00::1")})
+	lg.addLink("eth1", nil, []net.IP{net.ParseIP("fd00::2")})
+
+	ip, err := resolveInterfaceAddr(lg, "eth0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ip.Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("got %v, want 10.0.0.1 (IPv4 should be preferred)", ip)
+	}
+
+	ip, err = resolveInterfaceAddr(lg, "eth1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ip.Equal(net.ParseIP("fd00::2")) {
+		t.Errorf("got %v, want fd00::2 (IPv6 fallback)", ip)
+	}
+
+	if _, err := resolveInterfaceAddr(lg, "eth2"); err == nil {
+		t.Error("expected an error for a non-existent interface")
+	} else if _, ok := err.(*errInterfaceNotFound); !ok {
+		t.Errorf("expected errInterfaceNotFound, got %T: %v", err, err)
+	}
+}
+
+func TestResolveInterfaceFrontends(t *testing.T) {
+	lg := newFakeLinkGetter()
+
+	rpm := NewRedirectPolicyManager(nil, nil)
+	rpm.linkGetter = lg
+
+	feM := &feMapping{
+		feAddr: loadbalancer.NewL3n4Addr(loadbalancer.TCP, net.IP{}, 80, loadbalancer.ScopeExternal),
+		ifName: "eth0",
+	}
+	config := &LRPConfig{
+		id:               policyID{Name: "test", Namespace: "default"},
+		lrpType:          lrpConfigTypeAddr,
+		frontendType:     addrFrontendSinglePort,
+		frontendMappings: []*feMapping{feM},
+	}
+	rpm.storePolicyConfig(*config)
+
+	// Interface doesn't exist yet: resolution should be deferred, not error out.
+	if changed := rpm.resolveInterfaceFrontends(config); changed {
+		t.Error("expected no change while the interface is missing")
+	}
+	if len(feM.feAddr.IP) != 0 {
+		t.Errorf("expected no IP to be assigned, got %v", feM.feAddr.IP)
+	}
+
+	lg.addLink("eth0", []net.IP{net.ParseIP("192.168.1.5")}, nil)
+
+	if changed := rpm.resolveInterfaceFrontends(config); !changed {
+		t.Fatal("expected resolution to report a change once the interface appears")
+	}
+	if !feM.feAddr.IP.Equal(net.ParseIP("192.168.1.5")) {
+		t.Errorf("got %v, want 192.168.1.5", feM.feAddr.IP)
+	}
+	if id, ok := rpm.policyFrontendsByHash[frontendHash(feM.feAddr)]; !ok || id != config.id {
+		t.Error("expected the frontend hash index to be updated to the resolved address")
+	}
+}
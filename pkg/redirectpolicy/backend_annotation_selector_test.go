@@ -0,0 +1,176 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// annotationSelectorSpec returns a CiliumLocalRedirectPolicySpec matching
+// app=foo pods that are additionally annotated with release=stable.
+func annotationSelectorSpec() v2.CiliumLocalRedirectPolicySpec {
+	return v2.CiliumLocalRedirectPolicySpec{
+		RedirectFrontend: v2.RedirectFrontend{
+			AddressMatcher: &v2.Frontend{
+				IP:      "10.0.0.1",
+				ToPorts: []v2.PortInfo{{Port: "80", Protocol: api.ProtoTCP}},
+			},
+		},
+		RedirectBackend: v2.RedirectBackend{
+			LocalEndpointSelector: slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+			BackendAnnotationSelector: &slim_metav1.LabelSelector{
+				MatchLabels: map[string]string{"release": "stable"},
+			},
+			ToPorts: []v2.PortInfo{{Port: "80", Protocol: api.ProtoTCP}},
+		},
+	}
+}
+
+// TestBackendAnnotationSelectorMatchesAnnotatedPod verifies that a pod
+// matching both the label selector and backendAnnotationSelector is
+// selected as a backend.
+func TestBackendAnnotationSelectorMatchesAnnotatedPod(t *testing.T) {
+	config, err := getSanitizedLRPConfig("annotated-lrp", "default", "", annotationSelectorSpec())
+	if err != nil {
+		t.Fatalf("failed to sanitize config: %v", err)
+	}
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	annotatedPod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:        "annotated-pod",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "foo"},
+			Annotations: map[string]string{"release": "stable"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	if err := podStore.Add(annotatedPod); err != nil {
+		t.Fatalf("failed to add pod: %v", err)
+	}
+
+	ok, err := rpm.AddRedirectPolicy(*config, nil, podStore)
+	if !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	stored := rpm.policyConfigs[config.id]
+	backends := stored.frontendMappings[0].backends
+	if len(backends) != 1 || !backends[0].IP.Equal(net.ParseIP("10.1.1.1")) {
+		t.Fatalf("expected the annotated pod's backend, got %v", backends)
+	}
+}
+
+// TestBackendAnnotationSelectorExcludesLabelOnlyPod verifies that a pod
+// matching only the label selector, without the required annotation, is not
+// selected as a backend.
+func TestBackendAnnotationSelectorExcludesLabelOnlyPod(t *testing.T) {
+	config, err := getSanitizedLRPConfig("annotated-lrp", "default", "", annotationSelectorSpec())
+	if err != nil {
+		t.Fatalf("failed to sanitize config: %v", err)
+	}
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	labelOnlyPod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "label-only-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.2",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.2"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	if err := podStore.Add(labelOnlyPod); err != nil {
+		t.Fatalf("failed to add pod: %v", err)
+	}
+
+	ok, err := rpm.AddRedirectPolicy(*config, nil, podStore)
+	if !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	stored := rpm.policyConfigs[config.id]
+	if backends := stored.frontendMappings[0].backends; len(backends) != 0 {
+		t.Fatalf("expected no backends for a pod missing the required annotation, got %v", backends)
+	}
+}
+
+// TestBackendAnnotationSelectorUnsetMatchesAnyAnnotations verifies that a
+// policy with no backendAnnotationSelector behaves as before: it selects
+// every pod matching the label selector regardless of annotations.
+func TestBackendAnnotationSelectorUnsetMatchesAnyAnnotations(t *testing.T) {
+	spec := annotationSelectorSpec()
+	spec.RedirectBackend.BackendAnnotationSelector = nil
+
+	config, err := getSanitizedLRPConfig("annotated-lrp", "default", "", spec)
+	if err != nil {
+		t.Fatalf("failed to sanitize config: %v", err)
+	}
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	labelOnlyPod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "label-only-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.2",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.2"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	if err := podStore.Add(labelOnlyPod); err != nil {
+		t.Fatalf("failed to add pod: %v", err)
+	}
+
+	ok, err := rpm.AddRedirectPolicy(*config, nil, podStore)
+	if !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	stored := rpm.policyConfigs[config.id]
+	backends := stored.frontendMappings[0].backends
+	if len(backends) != 1 || !backends[0].IP.Equal(net.ParseIP("10.1.1.2")) {
+		t.Fatalf("expected the label-only pod's backend to be selected absent an annotation selector, got %v", backends)
+	}
+}
@@ -0,0 +1,83 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	fakeDatapath "github.com/cilium/cilium/pkg/datapath/fake"
+	"github.com/cilium/cilium/pkg/k8s"
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/lock"
+)
+
+func TestGetServiceBackendCoverage(t *testing.T) {
+	svcCache := k8s.NewServiceCache(fakeDatapath.NewNodeAddressing())
+
+	k8sEndpoints := &slimcorev1.Endpoints{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Subsets: []slimcorev1.EndpointSubset{
+			{
+				Addresses: []slimcorev1.EndpointAddress{
+					{IP: "10.0.0.1"},
+					{IP: "10.0.0.2"},
+				},
+				Ports: []slimcorev1.EndpointPort{
+					{Port: 80, Protocol: slimcorev1.ProtocolTCP},
+				},
+			},
+		},
+	}
+	svcCache.UpdateEndpoints(k8sEndpoints, lock.NewStoppableWaitGroup())
+
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	svcID := k8s.ServiceID{Name: "foo", Namespace: "bar"}
+	id := policyID{Name: "lrp", Namespace: "bar"}
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("172.0.20.1"), 80, lb.ScopeExternal),
+		backends: []backend{
+			{IP: net.ParseIP("10.0.0.1"), L4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	config := &LRPConfig{
+		id:               id,
+		lrpType:          lrpConfigTypeSvc,
+		frontendType:     svcFrontendAll,
+		serviceID:        &svcID,
+		frontendMappings: []*feMapping{feM},
+	}
+	rpm.storePolicyConfig(*config)
+
+	coverage, err := rpm.GetServiceBackendCoverage(id, &svcCache)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(coverage.LRPBackends) != 1 {
+		t.Fatalf("expected 1 LRP backend, got %d", len(coverage.LRPBackends))
+	}
+	if len(coverage.ServiceEndpoints.Backends) != 2 {
+		t.Fatalf("expected 2 service endpoints, got %d", len(coverage.ServiceEndpoints.Backends))
+	}
+	if _, ok := coverage.ServiceEndpoints.Backends["10.0.0.2"]; !ok {
+		t.Error("expected remote-only endpoint 10.0.0.2 to be present in the full service endpoint set")
+	}
+}
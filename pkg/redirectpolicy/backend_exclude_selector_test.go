@@ -0,0 +1,174 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// canaryExcludeSpec returns a CiliumLocalRedirectPolicySpec matching app=foo
+// pods, excluding those additionally labeled canary=true.
+func canaryExcludeSpec() v2.CiliumLocalRedirectPolicySpec {
+	return v2.CiliumLocalRedirectPolicySpec{
+		RedirectFrontend: v2.RedirectFrontend{
+			AddressMatcher: &v2.Frontend{
+				IP:      "10.0.0.1",
+				ToPorts: []v2.PortInfo{{Port: "80", Protocol: api.ProtoTCP}},
+			},
+		},
+		RedirectBackend: v2.RedirectBackend{
+			LocalEndpointSelector: slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+			BackendExcludeSelector: &slim_metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "foo", "canary": "true"},
+			},
+			ToPorts: []v2.PortInfo{{Port: "80", Protocol: api.ProtoTCP}},
+		},
+	}
+}
+
+// TestBackendExcludeSelectorRejectsFullOverlap verifies that an exclude
+// selector whose MatchLabels are a subset of the include selector's is
+// rejected, since it would exclude every selected backend.
+func TestBackendExcludeSelectorRejectsFullOverlap(t *testing.T) {
+	spec := canaryExcludeSpec()
+	spec.RedirectBackend.BackendExcludeSelector = &slim_metav1.LabelSelector{
+		MatchLabels: map[string]string{"app": "foo"},
+	}
+
+	if _, err := getSanitizedLRPConfig("canary-lrp", "default", "", spec); err == nil {
+		t.Fatal("expected an exclude selector fully overlapping the include selector to be rejected")
+	}
+}
+
+// TestBackendExcludeSelectorAcceptsPartialOverlap verifies that an exclude
+// selector with an additional, narrowing label is accepted.
+func TestBackendExcludeSelectorAcceptsPartialOverlap(t *testing.T) {
+	if _, err := getSanitizedLRPConfig("canary-lrp", "default", "", canaryExcludeSpec()); err != nil {
+		t.Fatalf("expected a narrowing exclude selector to be accepted, got: %v", err)
+	}
+}
+
+// TestBackendExcludeSelectorExcludesMatchingPods verifies end to end that a
+// pod matching backendExcludeSelector is not selected as a backend, while a
+// pod matching only the include selector is, and that without an exclude
+// selector both would have been selected.
+func TestBackendExcludeSelectorExcludesMatchingPods(t *testing.T) {
+	config, err := getSanitizedLRPConfig("canary-lrp", "default", "", canaryExcludeSpec())
+	if err != nil {
+		t.Fatalf("failed to sanitize config: %v", err)
+	}
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	regularPod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "regular-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	canaryPod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "canary-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo", "canary": "true"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.2",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.2"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	for _, pod := range []*slimcorev1.Pod{regularPod, canaryPod} {
+		if err := podStore.Add(pod); err != nil {
+			t.Fatalf("failed to add pod %s: %v", pod.Name, err)
+		}
+	}
+
+	ok, err := rpm.AddRedirectPolicy(*config, nil, podStore)
+	if !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	stored := rpm.policyConfigs[config.id]
+	backends := stored.frontendMappings[0].backends
+	if len(backends) != 1 || !backends[0].IP.Equal(net.ParseIP("10.1.1.1")) {
+		t.Fatalf("expected only the regular pod's backend, got %v", backends)
+	}
+}
+
+// TestBackendExcludeSelectorUnsetIncludesAllMatches verifies that a policy
+// with no backendExcludeSelector behaves as before: it selects every pod
+// matching the include selector.
+func TestBackendExcludeSelectorUnsetIncludesAllMatches(t *testing.T) {
+	spec := canaryExcludeSpec()
+	spec.RedirectBackend.BackendExcludeSelector = nil
+
+	config, err := getSanitizedLRPConfig("canary-lrp", "default", "", spec)
+	if err != nil {
+		t.Fatalf("failed to sanitize config: %v", err)
+	}
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	canaryPod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "canary-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo", "canary": "true"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.2",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.2"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	if err := podStore.Add(canaryPod); err != nil {
+		t.Fatalf("failed to add pod: %v", err)
+	}
+
+	ok, err := rpm.AddRedirectPolicy(*config, nil, podStore)
+	if !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	stored := rpm.policyConfigs[config.id]
+	backends := stored.frontendMappings[0].backends
+	if len(backends) != 1 || !backends[0].IP.Equal(net.ParseIP("10.1.1.2")) {
+		t.Fatalf("expected the canary pod's backend to be selected absent an exclude selector, got %v", backends)
+	}
+}
+
@@ -0,0 +1,136 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// fakeHealthChecker is a BackendHealthChecker whose verdict for a given
+// IP:port can be flipped at runtime, to drive health-transition tests.
+type fakeHealthChecker struct {
+	unhealthy map[string]bool
+}
+
+func (f *fakeHealthChecker) IsHealthy(ip net.IP, port lb.L4Addr) bool {
+	return !f.unhealthy[ip.String()]
+}
+
+func (f *fakeHealthChecker) setUnhealthy(ip string, unhealthy bool) {
+	if f.unhealthy == nil {
+		f.unhealthy = make(map[string]bool)
+	}
+	f.unhealthy[ip] = unhealthy
+}
+
+func newBackendHealthTestConfig() LRPConfig {
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	return LRPConfig{
+		id:           policyID{Name: "test", Namespace: "default"},
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		}},
+		backendSelector: selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+}
+
+// TestBackendHealthCheckerExcludesUnhealthyBackend verifies that a backend
+// reported unhealthy by the configured BackendHealthChecker is left out of
+// the installed backend set.
+func TestBackendHealthCheckerExcludesUnhealthyBackend(t *testing.T) {
+	svc := &fakeSvcManager{}
+	checker := &fakeHealthChecker{}
+	checker.setUnhealthy("10.1.1.2", true)
+	rpm := NewRedirectPolicyManager(svc, nil, WithBackendHealthChecker(checker))
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	addTestPod(t, podStore, "healthy-pod", "10.1.1.1", map[string]string{"app": "foo"})
+	addTestPod(t, podStore, "unhealthy-pod", "10.1.1.2", map[string]string{"app": "foo"})
+
+	config := newBackendHealthTestConfig()
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	if svc.lastUpserted == nil || len(svc.lastUpserted.Backends) != 1 {
+		t.Fatalf("expected exactly 1 healthy backend to be installed, got %+v", svc.lastUpserted)
+	}
+	if got := svc.lastUpserted.Backends[0].IP.String(); got != "10.1.1.1" {
+		t.Fatalf("expected the healthy pod's IP to be installed, got %s", got)
+	}
+}
+
+// TestBackendHealthCheckerDefaultIncludesEveryBackend verifies that with no
+// BackendHealthChecker configured, every selected backend is installed
+// regardless of health, preserving the original behavior.
+func TestBackendHealthCheckerDefaultIncludesEveryBackend(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	addTestPod(t, podStore, "pod-a", "10.1.1.1", map[string]string{"app": "foo"})
+	addTestPod(t, podStore, "pod-b", "10.1.1.2", map[string]string{"app": "foo"})
+
+	config := newBackendHealthTestConfig()
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	if svc.lastUpserted == nil || len(svc.lastUpserted.Backends) != 2 {
+		t.Fatalf("expected both backends to be installed, got %+v", svc.lastUpserted)
+	}
+}
+
+// TestBackendHealthChangedReinstallsRecoveredBackend verifies that a backend
+// excluded for failing a health check is installed once BackendHealthChanged
+// reports it healthy again.
+func TestBackendHealthChangedReinstallsRecoveredBackend(t *testing.T) {
+	svc := &fakeSvcManager{}
+	checker := &fakeHealthChecker{}
+	checker.setUnhealthy("10.1.1.2", true)
+	rpm := NewRedirectPolicyManager(svc, nil, WithBackendHealthChecker(checker))
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	addTestPod(t, podStore, "healthy-pod", "10.1.1.1", map[string]string{"app": "foo"})
+	addTestPod(t, podStore, "recovering-pod", "10.1.1.2", map[string]string{"app": "foo"})
+
+	config := newBackendHealthTestConfig()
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	if len(svc.lastUpserted.Backends) != 1 {
+		t.Fatalf("expected only the healthy backend to be installed initially, got %+v", svc.lastUpserted)
+	}
+
+	checker.setUnhealthy("10.1.1.2", false)
+	rpm.BackendHealthChanged(net.ParseIP("10.1.1.2"), lb.L4Addr{Protocol: lb.TCP, Port: 80})
+
+	if len(svc.lastUpserted.Backends) != 2 {
+		t.Fatalf("expected the recovered backend to be installed after BackendHealthChanged, got %+v", svc.lastUpserted)
+	}
+}
@@ -0,0 +1,125 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestBackendNamespaceRejectedForServiceMatcher verifies that a
+// backendNamespace on a service-matcher policy is rejected at sanitization.
+func TestBackendNamespaceRejectedForServiceMatcher(t *testing.T) {
+	spec := v2.CiliumLocalRedirectPolicySpec{
+		RedirectFrontend: v2.RedirectFrontend{
+			ServiceMatcher: &v2.ServiceInfo{
+				Name:      "my-service",
+				Namespace: "default",
+			},
+		},
+		RedirectBackend: v2.RedirectBackend{
+			LocalEndpointSelector: slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "proxy"}},
+			ToPorts: []v2.PortInfo{
+				{Port: "53", Protocol: api.ProtoUDP},
+			},
+			BackendNamespace: "observability",
+		},
+	}
+
+	if _, err := getSanitizedLRPConfig("svc-lrp", "default", "", spec); err == nil {
+		t.Fatal("expected backendNamespace to be rejected for a service matcher policy")
+	}
+}
+
+// TestAddRedirectPolicyBackendNamespace verifies that a policy with
+// backendNamespace set selects backends from that namespace instead of the
+// policy's own namespace.
+func TestAddRedirectPolicyBackendNamespace(t *testing.T) {
+	spec := v2.CiliumLocalRedirectPolicySpec{
+		RedirectFrontend: v2.RedirectFrontend{
+			AddressMatcher: &v2.Frontend{
+				IP: "169.254.169.254",
+				ToPorts: []v2.PortInfo{
+					{Port: "53", Protocol: api.ProtoUDP},
+				},
+			},
+		},
+		RedirectBackend: v2.RedirectBackend{
+			LocalEndpointSelector: slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "agent"}},
+			ToPorts: []v2.PortInfo{
+				{Port: "53", Protocol: api.ProtoUDP},
+			},
+			BackendNamespace: "observability",
+		},
+	}
+
+	config, err := getSanitizedLRPConfig("agent-lrp", "default", "", spec)
+	if err != nil {
+		t.Fatalf("failed to sanitize config: %v", err)
+	}
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	ownNamespacePod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "agent-in-default",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "agent"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	backendNamespacePod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "agent-in-observability",
+			Namespace: "observability",
+			Labels:    map[string]string{"app": "agent"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.2",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.2"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	for _, pod := range []*slimcorev1.Pod{ownNamespacePod, backendNamespacePod} {
+		if err := podStore.Add(pod); err != nil {
+			t.Fatalf("failed to add pod %s: %v", pod.Name, err)
+		}
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(*config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	stored := rpm.policyConfigs[config.id]
+	if len(stored.frontendMappings[0].backends) != 1 || !stored.frontendMappings[0].backends[0].IP.Equal(net.ParseIP("10.1.1.2")) {
+		t.Fatalf("expected only the backend-namespace pod's backend, got %v", stored.frontendMappings[0].backends)
+	}
+}
@@ -0,0 +1,33 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import "net"
+
+// BackendIPObserver can sign up to receive events whenever a pod IP starts
+// or stops backing a local redirect policy that has skipRedirectFromBackend
+// enabled, so that the datapath can exempt the backend's own traffic from
+// the policy's redirect.
+type BackendIPObserver interface {
+	// LocalRedirectBackendIPAdded is called when ip starts backing at least
+	// one skipRedirectFromBackend policy. Implementations must ensure that
+	// the callback returns within a reasonable period.
+	LocalRedirectBackendIPAdded(ip net.IP)
+
+	// LocalRedirectBackendIPRemoved is called when ip no longer backs any
+	// skipRedirectFromBackend policy. Implementations must ensure that the
+	// callback returns within a reasonable period.
+	LocalRedirectBackendIPRemoved(ip net.IP)
+}
@@ -0,0 +1,101 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// fakeBackendIPObserver is a fake BackendIPObserver that records every
+// added/removed IP it is notified of.
+type fakeBackendIPObserver struct {
+	added   []net.IP
+	removed []net.IP
+}
+
+func (f *fakeBackendIPObserver) LocalRedirectBackendIPAdded(ip net.IP) {
+	f.added = append(f.added, ip)
+}
+
+func (f *fakeBackendIPObserver) LocalRedirectBackendIPRemoved(ip net.IP) {
+	f.removed = append(f.removed, ip)
+}
+
+// TestSkipRedirectFromBackendReportedAndWithdrawn verifies that a subscribed
+// BackendIPObserver is notified when a pod backing a skipRedirectFromBackend
+// policy is added, and again when that pod is deleted.
+func TestSkipRedirectFromBackendReportedAndWithdrawn(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	observer := &fakeBackendIPObserver{}
+	rpm.Subscribe(observer)
+
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "backend-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.0.0.5",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.0.0.5"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	podStore.Add(pod)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+	config := LRPConfig{
+		id:                      policyID{Name: "test", Namespace: "default"},
+		lrpType:                 lrpConfigTypeAddr,
+		frontendType:            addrFrontendSinglePort,
+		frontendMappings:        []*feMapping{feM},
+		backendSelector:         selector,
+		skipRedirectFromBackend: true,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	if len(observer.added) != 1 || !observer.added[0].Equal(net.ParseIP("10.0.0.5")) {
+		t.Fatalf("expected the backend pod IP to be reported as added, got %v", observer.added)
+	}
+	if len(observer.removed) != 0 {
+		t.Fatalf("expected no removals yet, got %v", observer.removed)
+	}
+
+	rpm.OnDeletePod(pod)
+
+	if len(observer.removed) != 1 || !observer.removed[0].Equal(net.ParseIP("10.0.0.5")) {
+		t.Fatalf("expected the backend pod IP to be reported as removed after pod deletion, got %v", observer.removed)
+	}
+}
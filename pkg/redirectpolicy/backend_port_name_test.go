@@ -0,0 +1,91 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestNamedPortBackendReportsMatchedPortName verifies that a backend matched
+// through a named-port policy carries the matched container port name on the
+// upserted lb.Backend.
+func TestNamedPortBackendReportsMatchedPortName(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	if err := podStore.Add(&slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Spec: slimcorev1.PodSpec{
+			Containers: []slimcorev1.Container{{
+				Ports: []slimcorev1.ContainerPort{{
+					Name:          "dns",
+					ContainerPort: 8053,
+					Protocol:      "UDP",
+				}},
+			}},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("failed to add pod: %v", err)
+	}
+
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.UDP, net.ParseIP("10.0.0.1"), 53, lb.ScopeExternal),
+		fePort: "dns",
+	}
+	bePort := bePortInfo{
+		l4Addr: lb.L4Addr{Protocol: lb.UDP, Port: 8053},
+		name:   "dns",
+	}
+	config := LRPConfig{
+		id:                     policyID{Name: "test", Namespace: "default"},
+		lrpType:                lrpConfigTypeAddr,
+		frontendType:           addrFrontendNamedPorts,
+		frontendMappings:       []*feMapping{feM},
+		backendSelector:        api.NewESFromK8sLabelSelector("", &slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}}),
+		backendPorts:           []bePortInfo{bePort},
+		backendPortsByPortName: map[portName]*bePortInfo{"dns": &bePort},
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	if svc.lastUpserted == nil || len(svc.lastUpserted.Backends) != 1 {
+		t.Fatalf("expected exactly one upserted backend, got %+v", svc.lastUpserted)
+	}
+	if name := svc.lastUpserted.Backends[0].PortName; name != "dns" {
+		t.Fatalf("expected the upserted backend to report matched port name \"dns\", got %q", name)
+	}
+}
@@ -0,0 +1,49 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+)
+
+// backendWeightAnnotation sets the weight recorded on a pod's LRP backends,
+// as lb.Backend.Weight of the upserted service. A missing or invalid value
+// falls back to 0, equal weight with every other backend of the policy.
+const backendWeightAnnotation = "policy.cilium.io/lrp-backend-weight"
+
+// podBackendWeight returns the backend weight pod requests via
+// backendWeightAnnotation, or 0 (equal weight) if the annotation is absent
+// or not a valid non-negative integer.
+func podBackendWeight(pod *slimcorev1.Pod) uint16 {
+	raw, ok := pod.GetAnnotations()[backendWeightAnnotation]
+	if !ok {
+		return 0
+	}
+
+	weight, err := strconv.ParseUint(raw, 10, 16)
+	if err != nil {
+		log.WithError(err).WithFields(logrus.Fields{
+			logfields.K8sPodName:   pod.GetName(),
+			logfields.K8sNamespace: pod.GetNamespace(),
+		}).Warningf("Ignoring invalid %s annotation value %q", backendWeightAnnotation, raw)
+		return 0
+	}
+	return uint16(weight)
+}
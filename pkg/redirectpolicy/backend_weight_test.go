@@ -0,0 +1,101 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestPodBackendWeight(t *testing.T) {
+	withAnnotation := func(v string) *slimcorev1.Pod {
+		return &slimcorev1.Pod{
+			ObjectMeta: slim_metav1.ObjectMeta{
+				Name:        "foo-pod",
+				Namespace:   "default",
+				Annotations: map[string]string{backendWeightAnnotation: v},
+			},
+		}
+	}
+
+	if w := podBackendWeight(withAnnotation("42")); w != 42 {
+		t.Fatalf("expected weight 42, got %d", w)
+	}
+	if w := podBackendWeight(withAnnotation("not-a-number")); w != 0 {
+		t.Fatalf("expected invalid annotation value to fall back to 0, got %d", w)
+	}
+	if w := podBackendWeight(&slimcorev1.Pod{}); w != 0 {
+		t.Fatalf("expected a missing annotation to fall back to 0, got %d", w)
+	}
+}
+
+// TestWeightedBackendFlowsIntoUpsertedService verifies that a pod's
+// backendWeightAnnotation ends up on the corresponding lb.Backend in the
+// service upserted for a policy that selects it.
+func TestWeightedBackendFlowsIntoUpsertedService(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	if err := podStore.Add(&slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:        "foo-pod",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "foo"},
+			Annotations: map[string]string{backendWeightAnnotation: "30"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("failed to add pod: %v", err)
+	}
+
+	config := LRPConfig{
+		id:           policyID{Name: "test", Namespace: "default"},
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		}},
+		backendSelector: api.NewESFromK8sLabelSelector("",
+			&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}}),
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	if svc.lastUpserted == nil || len(svc.lastUpserted.Backends) != 1 {
+		t.Fatalf("expected exactly one upserted backend, got %+v", svc.lastUpserted)
+	}
+	if w := svc.lastUpserted.Backends[0].Weight; w != 30 {
+		t.Fatalf("expected the upserted backend to carry weight 30, got %d", w)
+	}
+}
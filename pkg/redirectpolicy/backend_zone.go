@@ -0,0 +1,39 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+)
+
+// topologyZoneLabel is the well-known Kubernetes label carrying a node's
+// (and, when projected onto pods by the scheduler or downward API, a pod's)
+// topology zone.
+const topologyZoneLabel = "topology.kubernetes.io/zone"
+
+// backendZoneAnnotation lets an operator override the zone reported for an
+// LRP backend when topologyZoneLabel isn't projected onto the pod, e.g. on
+// clusters that don't populate pod topology labels.
+const backendZoneAnnotation = "policy.cilium.io/lrp-backend-zone"
+
+// podZone returns the topology zone to report for pod's backends: the
+// backendZoneAnnotation override if set, otherwise the pod's own
+// topologyZoneLabel value, or "" if neither is present.
+func podZone(pod *slimcorev1.Pod) string {
+	if zone, ok := pod.GetAnnotations()[backendZoneAnnotation]; ok {
+		return zone
+	}
+	return pod.GetLabels()[topologyZoneLabel]
+}
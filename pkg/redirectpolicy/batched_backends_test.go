@@ -0,0 +1,125 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+func newBatchedBackendsConfig(selector api.EndpointSelector) LRPConfig {
+	return LRPConfig{
+		id:           policyID{Name: "test", Namespace: "default"},
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		}},
+		backendSelector: selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+}
+
+// TestAddRedirectPolicyBatchesInitialBackends verifies that adding a policy
+// that already selects several existing pods programs the service once,
+// not once per already-selected pod.
+func TestAddRedirectPolicyBatchesInitialBackends(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	const numPods = 20
+	for i := 0; i < numPods; i++ {
+		addTestPod(t, podStore, fmt.Sprintf("pod-%d", i), fmt.Sprintf("10.1.1.%d", i+1),
+			map[string]string{"app": "foo"})
+	}
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := newBatchedBackendsConfig(selector)
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	if svc.upsertCount != 1 {
+		t.Fatalf("expected a single upsert for %d already-selected pods, got %d", numPods, svc.upsertCount)
+	}
+
+	storedConfig := rpm.policyConfigs[config.id]
+	if got := totalBackends(storedConfig); got != numPods {
+		t.Fatalf("expected all %d pods to be programmed as backends, got %d", numPods, got)
+	}
+}
+
+// BenchmarkOnAddPodAmongManyExisting measures how many UpsertService calls
+// are made when a single new pod is added to a policy that already has many
+// backends: it must stay at one, not grow with the size of the existing
+// backend set.
+func BenchmarkOnAddPodAmongManyExisting(b *testing.B) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	const numExistingPods = 100
+	for i := 0; i < numExistingPods; i++ {
+		addTestPod(b, podStore, fmt.Sprintf("pod-%d", i), fmt.Sprintf("10.1.1.%d", i+1),
+			map[string]string{"app": "foo"})
+	}
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := newBatchedBackendsConfig(selector)
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		b.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ip := fmt.Sprintf("10.2.2.%d", (i%254)+1)
+		pod := &slimcorev1.Pod{
+			ObjectMeta: slim_metav1.ObjectMeta{
+				Name:      fmt.Sprintf("new-pod-%d", i),
+				Namespace: "default",
+				Labels:    map[string]string{"app": "foo"},
+			},
+			Status: slimcorev1.PodStatus{
+				PodIP:  ip,
+				PodIPs: []slimcorev1.PodIP{{IP: ip}},
+				Conditions: []slimcorev1.PodCondition{
+					{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+				},
+			},
+		}
+		if err := podStore.Add(pod); err != nil {
+			b.Fatalf("failed to add pod to store: %v", err)
+		}
+
+		before := svc.upsertCount
+		rpm.OnAddPod(pod)
+		if got := svc.upsertCount - before; got != 1 {
+			b.Fatalf("expected adding one pod to trigger exactly one upsert, got %d", got)
+		}
+	}
+}
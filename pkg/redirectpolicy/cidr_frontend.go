@@ -0,0 +1,63 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"fmt"
+	"net"
+)
+
+// maxCIDRFrontendAddresses caps how many per-address frontends a single CIDR
+// address matcher can expand into, so that an overly broad CIDR (e.g. a
+// typo'd /16) can't blow up policyFrontendsByHash with tens of thousands of
+// entries.
+const maxCIDRFrontendAddresses = 64
+
+// expandCIDRFrontendAddresses returns every address contained in cidr, in
+// ascending order. It rejects a CIDR that parses to an invalid range, or
+// that would expand into more than maxCIDRFrontendAddresses addresses.
+func expandCIDRFrontendAddresses(cidr string) ([]net.IP, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address matcher CIDR %s: %w", cidr, err)
+	}
+
+	ones, bits := ipNet.Mask.Size()
+	if bits-ones > 6 {
+		// 2^6 == maxCIDRFrontendAddresses; reject before attempting the
+		// shift below so an enormous CIDR can't be misread as a small count.
+		return nil, fmt.Errorf("address matcher CIDR %s is too large: it must not expand into "+
+			"more than %d addresses", cidr, maxCIDRFrontendAddresses)
+	}
+
+	count := 1 << uint(bits-ones)
+	addrs := make([]net.IP, 0, count)
+	for ip := ipNet.IP.Mask(ipNet.Mask); ipNet.Contains(ip); ip = nextIP(ip) {
+		addrs = append(addrs, append(net.IP{}, ip...))
+	}
+	return addrs, nil
+}
+
+// nextIP returns the address immediately following ip.
+func nextIP(ip net.IP) net.IP {
+	next := append(net.IP{}, ip...)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
@@ -0,0 +1,88 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/policy/api"
+)
+
+// TestExpandCIDRFrontendAddresses verifies that a /30 expands to its 4
+// addresses and that an overly broad CIDR is rejected.
+func TestExpandCIDRFrontendAddresses(t *testing.T) {
+	addrs, err := expandCIDRFrontendAddresses("169.254.169.0/30")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"169.254.169.0", "169.254.169.1", "169.254.169.2", "169.254.169.3"}
+	if len(addrs) != len(want) {
+		t.Fatalf("expected %d addresses, got %d: %v", len(want), len(addrs), addrs)
+	}
+	for i, w := range want {
+		if !addrs[i].Equal(net.ParseIP(w)) {
+			t.Errorf("address %d: expected %s, got %s", i, w, addrs[i])
+		}
+	}
+
+	if _, err := expandCIDRFrontendAddresses("169.254.0.0/16"); err == nil {
+		t.Fatal("expected a /16 CIDR to be rejected for exceeding the address cap")
+	}
+
+	if _, err := expandCIDRFrontendAddresses("not-a-cidr"); err == nil {
+		t.Fatal("expected a malformed CIDR to be rejected")
+	}
+}
+
+// TestCIDRFrontendExpandsIntoPerAddressMappings verifies that getSanitizedLRPConfig
+// expands a CIDR address matcher into one feMapping per address, and that
+// the result is rejected if IP or Interface are also set.
+func TestCIDRFrontendExpandsIntoPerAddressMappings(t *testing.T) {
+	spec := v2.CiliumLocalRedirectPolicySpec{
+		RedirectFrontend: v2.RedirectFrontend{
+			AddressMatcher: &v2.Frontend{
+				CIDR: "169.254.169.0/30",
+				ToPorts: []v2.PortInfo{
+					{Port: "80", Protocol: api.ProtoTCP},
+				},
+			},
+		},
+		RedirectBackend: v2.RedirectBackend{
+			LocalEndpointSelector: slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "proxy"}},
+			ToPorts: []v2.PortInfo{
+				{Port: "80", Protocol: api.ProtoTCP},
+			},
+		},
+	}
+
+	config, err := getSanitizedLRPConfig("cidr-lrp", "default", "", spec)
+	if err != nil {
+		t.Fatalf("failed to sanitize config: %v", err)
+	}
+	if len(config.frontendMappings) != 4 {
+		t.Fatalf("expected 4 frontend mappings for a /30, got %d", len(config.frontendMappings))
+	}
+	if config.frontendType != addrFrontendSinglePort {
+		t.Fatalf("expected addrFrontendSinglePort, got %v", config.frontendType)
+	}
+
+	spec.RedirectFrontend.AddressMatcher.IP = "169.254.169.254"
+	if _, err := getSanitizedLRPConfig("cidr-lrp", "default", "", spec); err == nil {
+		t.Fatal("expected an error when both CIDR and IP are specified")
+	}
+}
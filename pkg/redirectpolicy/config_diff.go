@@ -0,0 +1,84 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import "sort"
+
+// ConfigDiff summarizes how one LRPConfig differs from another, for
+// operators and callers that need more than a boolean "changed" result.
+type ConfigDiff struct {
+	// AddedFrontends lists frontends present in the new config but not the
+	// old one.
+	AddedFrontends []frontend
+	// RemovedFrontends lists frontends present in the old config but not the
+	// new one.
+	RemovedFrontends []frontend
+	// BackendPortsChanged is true if the set of backend ports/protocols
+	// differs between the two configs.
+	BackendPortsChanged bool
+	// SelectorChanged is true if the backend selector differs between the
+	// two configs.
+	SelectorChanged bool
+}
+
+// Empty reports whether the diff carries no differences at all.
+func (d ConfigDiff) Empty() bool {
+	return len(d.AddedFrontends) == 0 && len(d.RemovedFrontends) == 0 &&
+		!d.BackendPortsChanged && !d.SelectorChanged
+}
+
+// DiffConfigs computes the difference between old and new, the same
+// dimensions updateRedirectPolicy's configsEqual check considers, but
+// broken out so that callers needing to know what specifically changed
+// (rather than just whether anything did) can query it, e.g. for
+// reconciliation logging.
+func DiffConfigs(old, new LRPConfig) ConfigDiff {
+	var diff ConfigDiff
+
+	oldByHash := make(map[string]*frontend, len(old.frontendMappings))
+	for _, feM := range old.frontendMappings {
+		oldByHash[frontendHash(feM.feAddr)] = feM.feAddr
+	}
+	newByHash := make(map[string]*frontend, len(new.frontendMappings))
+	for _, feM := range new.frontendMappings {
+		newByHash[frontendHash(feM.feAddr)] = feM.feAddr
+	}
+
+	for hash, addr := range newByHash {
+		if _, ok := oldByHash[hash]; !ok {
+			diff.AddedFrontends = append(diff.AddedFrontends, *addr)
+		}
+	}
+	for hash, addr := range oldByHash {
+		if _, ok := newByHash[hash]; !ok {
+			diff.RemovedFrontends = append(diff.RemovedFrontends, *addr)
+		}
+	}
+	sortFrontends(diff.AddedFrontends)
+	sortFrontends(diff.RemovedFrontends)
+
+	diff.BackendPortsChanged = !backendPortsEqual(old.backendPorts, new.backendPorts)
+	diff.SelectorChanged = old.backendSelector.String() != new.backendSelector.String()
+
+	return diff
+}
+
+// sortFrontends orders addrs deterministically so that DiffConfigs' results
+// don't depend on map iteration order.
+func sortFrontends(addrs []frontend) {
+	sort.Slice(addrs, func(i, j int) bool {
+		return frontendHash(&addrs[i]) < frontendHash(&addrs[j])
+	})
+}
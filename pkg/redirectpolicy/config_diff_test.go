@@ -0,0 +1,107 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+)
+
+func TestDiffConfigsNoOp(t *testing.T) {
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := LRPConfig{
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{
+			{feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal)},
+		},
+		backendPorts:    []bePortInfo{{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 8080}}},
+		backendSelector: selector,
+	}
+
+	diff := DiffConfigs(config, config)
+	if !diff.Empty() {
+		t.Errorf("expected no diff for identical configs, got %+v", diff)
+	}
+}
+
+func TestDiffConfigsFrontendsAddedAndRemoved(t *testing.T) {
+	oldConfig := LRPConfig{
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{
+			{feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal)},
+		},
+	}
+	newConfig := LRPConfig{
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{
+			{feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.2"), 80, lb.ScopeExternal)},
+		},
+	}
+
+	diff := DiffConfigs(oldConfig, newConfig)
+	if len(diff.AddedFrontends) != 1 || !diff.AddedFrontends[0].IP.Equal(net.ParseIP("10.0.0.2")) {
+		t.Errorf("expected 10.0.0.2 to be added, got %+v", diff.AddedFrontends)
+	}
+	if len(diff.RemovedFrontends) != 1 || !diff.RemovedFrontends[0].IP.Equal(net.ParseIP("10.0.0.1")) {
+		t.Errorf("expected 10.0.0.1 to be removed, got %+v", diff.RemovedFrontends)
+	}
+	if diff.BackendPortsChanged || diff.SelectorChanged {
+		t.Errorf("expected only the frontend set to differ, got %+v", diff)
+	}
+}
+
+func TestDiffConfigsBackendPortsChanged(t *testing.T) {
+	oldConfig := LRPConfig{
+		backendPorts: []bePortInfo{{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 8080}}},
+	}
+	newConfig := LRPConfig{
+		backendPorts: []bePortInfo{{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 9090}}},
+	}
+
+	diff := DiffConfigs(oldConfig, newConfig)
+	if !diff.BackendPortsChanged {
+		t.Error("expected backend port change to be detected")
+	}
+	if diff.SelectorChanged || len(diff.AddedFrontends) != 0 || len(diff.RemovedFrontends) != 0 {
+		t.Errorf("expected only backend ports to differ, got %+v", diff)
+	}
+}
+
+func TestDiffConfigsSelectorChanged(t *testing.T) {
+	oldConfig := LRPConfig{
+		backendSelector: api.NewESFromK8sLabelSelector("",
+			&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}}),
+	}
+	newConfig := LRPConfig{
+		backendSelector: api.NewESFromK8sLabelSelector("",
+			&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "bar"}}),
+	}
+
+	diff := DiffConfigs(oldConfig, newConfig)
+	if !diff.SelectorChanged {
+		t.Error("expected selector change to be detected")
+	}
+	if diff.BackendPortsChanged || len(diff.AddedFrontends) != 0 || len(diff.RemovedFrontends) != 0 {
+		t.Errorf("expected only the selector to differ, got %+v", diff)
+	}
+}
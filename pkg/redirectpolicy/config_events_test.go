@@ -0,0 +1,130 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestInvalidConfigEventFiresOnRejection verifies that AddRedirectPolicy
+// records an InvalidConfig event against a config rejected by isValidConfig,
+// e.g. because it duplicates an already-stored frontend.
+func TestInvalidConfigEventFiresOnRejection(t *testing.T) {
+	recorder := &fakeEventRecorder{}
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, recorder)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	feAddr := lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal)
+
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	addTestPod(t, podStore, "foo-pod", "10.1.1.1", map[string]string{"app": "foo"})
+
+	first := LRPConfig{
+		id:              policyID{Name: "first", Namespace: "default"},
+		lrpType:         lrpConfigTypeAddr,
+		frontendType:    addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{feAddr: feAddr}},
+		backendSelector: selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	if ok, err := rpm.AddRedirectPolicy(first, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed for first config: ok=%v err=%v", ok, err)
+	}
+	if len(recorder.events) != 1 || recorder.events[0] != "Programmed" {
+		t.Fatalf("expected a single Programmed event for the valid config, got %v", recorder.events)
+	}
+
+	duplicate := LRPConfig{
+		id:              policyID{Name: "second", Namespace: "default"},
+		lrpType:         lrpConfigTypeAddr,
+		frontendType:    addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{feAddr: feAddr}},
+		backendSelector: selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	ok, err := rpm.AddRedirectPolicy(duplicate, nil, podStore)
+	if ok || err == nil {
+		t.Fatal("expected AddRedirectPolicy to reject a duplicate frontend")
+	}
+	if len(recorder.events) != 2 || recorder.events[1] != "InvalidConfig" {
+		t.Fatalf("expected an InvalidConfig event for the rejected duplicate, got %v", recorder.events)
+	}
+}
+
+// TestProgrammedEventFiresOnceOnFirstBackend verifies that a Programmed
+// event is recorded the first time a policy has a backend programmed, and
+// isn't repeated on later backend churn.
+func TestProgrammedEventFiresOnceOnFirstBackend(t *testing.T) {
+	recorder := &fakeEventRecorder{}
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, recorder)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := newNoBackendsTestConfig(selector)
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	if len(recorder.events) != 1 || recorder.events[0] != "NoLocalBackends" {
+		t.Fatalf("expected exactly one NoLocalBackends event before any pod exists, got %v", recorder.events)
+	}
+
+	pod := newTestPod("foo-pod", "10.1.1.1", map[string]string{"app": "foo"})
+	addTestPod(t, podStore, "foo-pod", "10.1.1.1", map[string]string{"app": "foo"})
+	rpm.OnAddPod(pod)
+	if len(recorder.events) != 2 || recorder.events[1] != "Programmed" {
+		t.Fatalf("expected a Programmed event once the first backend is resolved, got %v", recorder.events)
+	}
+
+	pod2 := newTestPod("foo-pod-2", "10.1.1.2", map[string]string{"app": "foo"})
+	addTestPod(t, podStore, "foo-pod-2", "10.1.1.2", map[string]string{"app": "foo"})
+	rpm.OnAddPod(pod2)
+	if len(recorder.events) != 2 {
+		t.Fatalf("expected no additional Programmed event for a second backend, got %v", recorder.events)
+	}
+}
+
+// newTestPod builds a Ready slim Pod matching the shape addTestPod stores in
+// the pod cache, for use with Manager.OnAddPod.
+func newTestPod(name, ip string, labels map[string]string) *slimcorev1.Pod {
+	return &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    labels,
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  ip,
+			PodIPs: []slimcorev1.PodIP{{IP: ip}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+}
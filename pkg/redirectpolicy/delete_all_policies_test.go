@@ -0,0 +1,87 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	"github.com/cilium/cilium/pkg/k8s"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+)
+
+// TestDeleteAllPoliciesClearsEverything verifies that DeleteAllPolicies
+// removes the LB service entry for every configured policy, of both the
+// address and service frontend types, and leaves policyFrontendsByHash,
+// policyServices, policyPods and policyConfigs empty.
+func TestDeleteAllPoliciesClearsEverything(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+
+	addrID := policyID{Name: "addr-lrp", Namespace: "default"}
+	addrFeM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		backends: []backend{
+			{IP: net.ParseIP("10.1.1.1"), L4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	addrConfig := LRPConfig{
+		id:               addrID,
+		lrpType:          lrpConfigTypeAddr,
+		frontendType:     addrFrontendSinglePort,
+		frontendMappings: []*feMapping{addrFeM},
+	}
+	rpm.storePolicyConfig(addrConfig)
+	rpm.recordPodOwnership(rpm.policyConfigs[addrID], k8s.ServiceID{Name: "addr-pod", Namespace: "default"},
+		"uid-addr", true, 0, "", "", addrFeM.backends)
+
+	svcID := k8s.ServiceID{Name: "svc", Namespace: "default"}
+	svcFeM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.2"), 80, lb.ScopeExternal),
+		backends: []backend{
+			{IP: net.ParseIP("10.1.1.2"), L4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	svcConfig := LRPConfig{
+		id:               policyID{Name: "svc-lrp", Namespace: "default"},
+		lrpType:          lrpConfigTypeSvc,
+		frontendType:     svcFrontendAll,
+		serviceID:        &svcID,
+		frontendMappings: []*feMapping{svcFeM},
+	}
+	rpm.storePolicyConfig(svcConfig)
+	rpm.recordPodOwnership(rpm.policyConfigs[svcConfig.id], k8s.ServiceID{Name: "svc-pod", Namespace: "default"},
+		"uid-svc", true, 0, "", "", svcFeM.backends)
+
+	if err := rpm.DeleteAllPolicies(); err != nil {
+		t.Fatalf("DeleteAllPolicies failed: %v", err)
+	}
+
+	if svc.deleteCount != 2 {
+		t.Fatalf("expected a service deletion for each of the 2 policies, got %d", svc.deleteCount)
+	}
+	if len(rpm.policyConfigs) != 0 {
+		t.Fatalf("expected policyConfigs to be empty, got %d entries", len(rpm.policyConfigs))
+	}
+	if len(rpm.policyFrontendsByHash) != 0 {
+		t.Fatalf("expected policyFrontendsByHash to be empty, got %d entries", len(rpm.policyFrontendsByHash))
+	}
+	if len(rpm.policyServices) != 0 {
+		t.Fatalf("expected policyServices to be empty, got %d entries", len(rpm.policyServices))
+	}
+	if len(rpm.policyPods) != 0 {
+		t.Fatalf("expected policyPods to be empty, got %d entries", len(rpm.policyPods))
+	}
+}
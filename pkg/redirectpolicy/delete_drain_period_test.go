@@ -0,0 +1,118 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+	"github.com/cilium/cilium/pkg/testutils"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+func newDeleteDrainPeriodTestConfig(drainPeriod time.Duration) LRPConfig {
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	return LRPConfig{
+		id:           policyID{Name: "test", Namespace: "default"},
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		}},
+		backendSelector: selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+		deleteDrainPeriod: drainPeriod,
+	}
+}
+
+// TestDeleteDrainPeriodHoldsServiceWithZeroWeightBackends verifies that
+// deleting a policy with a deleteDrainPeriod doesn't immediately remove the
+// service, and instead re-upserts it once with its backends at zero weight.
+func TestDeleteDrainPeriodHoldsServiceWithZeroWeightBackends(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	config := newDeleteDrainPeriodTestConfig(time.Hour)
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo-pod",
+			Namespace: "default",
+			UID:       "uid-1",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	if err := podStore.Add(pod); err != nil {
+		t.Fatalf("failed to add pod to store: %v", err)
+	}
+	rpm.OnAddPod(pod)
+
+	if err := rpm.DeleteRedirectPolicy(config); err != nil {
+		t.Fatalf("DeleteRedirectPolicy failed: %v", err)
+	}
+
+	if svc.deleteCount != 0 {
+		t.Fatalf("expected no service deletion within the drain period, got %d deletions", svc.deleteCount)
+	}
+	if svc.lastUpserted == nil || len(svc.lastUpserted.Backends) != 1 || svc.lastUpserted.Backends[0].Weight != 0 {
+		t.Fatalf("expected a final upsert with the backend at zero weight, got %+v", svc.lastUpserted)
+	}
+}
+
+// TestDeleteDrainPeriodDeletesServiceAfterDrainElapses verifies that, once
+// the drain period elapses, the service is actually removed.
+func TestDeleteDrainPeriodDeletesServiceAfterDrainElapses(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	config := newDeleteDrainPeriodTestConfig(time.Millisecond)
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	if err := rpm.DeleteRedirectPolicy(config); err != nil {
+		t.Fatalf("DeleteRedirectPolicy failed: %v", err)
+	}
+
+	err := testutils.WaitUntil(func() bool {
+		rpm.mutex.RLock()
+		defer rpm.mutex.RUnlock()
+		return svc.deleteCount == 1
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("expected the service to be deleted once the drain period elapsed: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/metrics"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestDroppedEventsCounterOnNilPodStore verifies that applying a config with
+// a nil pod store increments the dropped events counter instead of panicking
+// or silently leaving no trace of the failure.
+func TestDroppedEventsCounterOnNilPodStore(t *testing.T) {
+	origCounter := metrics.LRPDroppedEventsTotal
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_lrp_dropped_events_total"})
+	metrics.LRPDroppedEventsTotal = counter
+	defer func() { metrics.LRPDroppedEventsTotal = origCounter }()
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+
+	id := policyID{Name: "test", Namespace: "default"}
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := LRPConfig{
+		id:           id,
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		}},
+		backendSelector: selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+
+	if got := testutil.ToFloat64(counter); got != 0 {
+		t.Fatalf("expected counter to start at 0, got %v", got)
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, nil); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	if got := testutil.ToFloat64(counter); got != 1 {
+		t.Fatalf("expected counter to be 1 after an event with a nil pod store, got %v", got)
+	}
+}
@@ -0,0 +1,172 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// addDualStackTestPod adds a pod with both an IPv4 and an IPv6 address to
+// podStore.
+func addDualStackTestPod(t *testing.T, podStore cache.Store, name, ipv4, ipv6 string, labels map[string]string) {
+	t.Helper()
+	if err := podStore.Add(&slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    labels,
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP: ipv4,
+			PodIPs: []slimcorev1.PodIP{
+				{IP: ipv4},
+				{IP: ipv6},
+			},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("failed to add pod %s: %v", name, err)
+	}
+}
+
+// TestAddRedirectPolicyDualStackSinglePort verifies that a dual-stack pod
+// backs both an IPv4 and an IPv6 frontend mapping of the same single-port
+// policy, each with only the backend IP matching its own family.
+func TestAddRedirectPolicyDualStackSinglePort(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	addDualStackTestPod(t, podStore, "foo-pod", "10.1.1.1", "fd00::1", map[string]string{"app": "foo"})
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	feM4 := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+	feM6 := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("fd00::2"), 80, lb.ScopeExternal),
+	}
+	config := LRPConfig{
+		id:               policyID{Name: "test", Namespace: "default"},
+		lrpType:          lrpConfigTypeAddr,
+		frontendType:     addrFrontendSinglePort,
+		frontendMappings: []*feMapping{feM4, feM6},
+		backendSelector:  selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	if svc.upsertCount != 2 {
+		t.Fatalf("expected one upsert per address family, got %d", svc.upsertCount)
+	}
+
+	storedConfig := rpm.policyConfigs[config.id]
+	if len(feM4.backends) != 1 || !feM4.backends[0].IP.Equal(net.ParseIP("10.1.1.1")) {
+		t.Fatalf("expected the IPv4 frontend to be backed by the pod's IPv4 address, got %v", feM4.backends)
+	}
+	if len(feM6.backends) != 1 || !feM6.backends[0].IP.Equal(net.ParseIP("fd00::1")) {
+		t.Fatalf("expected the IPv6 frontend to be backed by the pod's IPv6 address, got %v", feM6.backends)
+	}
+	if got := totalBackends(storedConfig); got != 2 {
+		t.Fatalf("expected 2 total backends across both families, got %d", got)
+	}
+}
+
+// TestAddRedirectPolicyDualStackNamedPorts verifies the same dual-stack
+// behavior for a named-port policy.
+func TestAddRedirectPolicyDualStackNamedPorts(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Spec: slimcorev1.PodSpec{
+			Containers: []slimcorev1.Container{{
+				Ports: []slimcorev1.ContainerPort{{
+					Name:          "web",
+					Protocol:      slimcorev1.ProtocolTCP,
+					ContainerPort: 8080,
+				}},
+			}},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP: "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{
+				{IP: "10.1.1.1"},
+				{IP: "fd00::1"},
+			},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	if err := podStore.Add(pod); err != nil {
+		t.Fatalf("failed to add pod to store: %v", err)
+	}
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	feM4 := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		fePort: "web",
+	}
+	feM6 := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("fd00::2"), 80, lb.ScopeExternal),
+		fePort: "web",
+	}
+	config := LRPConfig{
+		id:               policyID{Name: "test", Namespace: "default"},
+		lrpType:          lrpConfigTypeAddr,
+		frontendType:     addrFrontendNamedPorts,
+		frontendMappings: []*feMapping{feM4, feM6},
+		backendSelector:  selector,
+		backendPortsByPortName: map[string]*bePortInfo{
+			"web": {l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 8080}, name: "web"},
+		},
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	if svc.upsertCount != 2 {
+		t.Fatalf("expected one upsert per address family, got %d", svc.upsertCount)
+	}
+
+	if len(feM4.backends) != 1 || !feM4.backends[0].IP.Equal(net.ParseIP("10.1.1.1")) {
+		t.Fatalf("expected the IPv4 frontend to be backed by the pod's IPv4 address, got %v", feM4.backends)
+	}
+	if len(feM6.backends) != 1 || !feM6.backends[0].IP.Equal(net.ParseIP("fd00::1")) {
+		t.Fatalf("expected the IPv6 frontend to be backed by the pod's IPv6 address, got %v", feM6.backends)
+	}
+}
@@ -0,0 +1,54 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestAddRedirectPolicyRejectsIntraPolicyFrontendCollision verifies that an
+// address-type LRP declaring the same frontend IP/port/protocol tuple twice
+// is rejected by isValidConfig before any service is upserted.
+func TestAddRedirectPolicyRejectsIntraPolicyFrontendCollision(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	feAddr := lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal)
+	config := LRPConfig{
+		id:      policyID{Name: "test", Namespace: "default"},
+		lrpType: lrpConfigTypeAddr,
+		frontendMappings: []*feMapping{
+			{feAddr: feAddr.DeepCopy()},
+			{feAddr: feAddr.DeepCopy()},
+		},
+	}
+
+	ok, err := rpm.AddRedirectPolicy(config, nil, podStore)
+	if ok || err == nil {
+		t.Fatalf("expected AddRedirectPolicy to reject duplicate frontend mappings, got ok=%v err=%v", ok, err)
+	}
+	if svc.upsertCount != 0 {
+		t.Fatalf("expected no service upsert for a rejected config, got %d", svc.upsertCount)
+	}
+	if _, exists := rpm.policyConfigs[config.id]; exists {
+		t.Fatalf("expected the rejected config not to be stored")
+	}
+}
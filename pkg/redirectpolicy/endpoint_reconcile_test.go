@@ -0,0 +1,102 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"testing"
+
+	fakeDatapath "github.com/cilium/cilium/pkg/datapath/fake"
+	"github.com/cilium/cilium/pkg/k8s"
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/lock"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestOnUpdateEndpointsRecomputesServiceBackends verifies that
+// OnUpdateEndpoints re-resolves the backends of a service-type policy that
+// selects the affected service, picking up a pod that newly matches the
+// policy's backend selector between the time the policy was added and the
+// endpoint event arrives.
+func TestOnUpdateEndpointsRecomputesServiceBackends(t *testing.T) {
+	svcCache := k8s.NewServiceCache(fakeDatapath.NewNodeAddressing())
+	swg := lock.NewStoppableWaitGroup()
+
+	k8sSvc := &slimcorev1.Service{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Spec: slimcorev1.ServiceSpec{
+			ClusterIP: "172.0.20.1",
+			Type:      slimcorev1.ServiceTypeClusterIP,
+			Ports: []slimcorev1.ServicePort{
+				{Port: 80, Protocol: slimcorev1.ProtocolTCP},
+			},
+		},
+	}
+	svcID := svcCache.UpdateService(k8sSvc, swg)
+
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	id := policyID{Name: "lrp", Namespace: "default"}
+	config := LRPConfig{
+		id:           id,
+		lrpType:      lrpConfigTypeSvc,
+		frontendType: svcFrontendAll,
+		serviceID:    &svcID,
+		backendSelector: api.NewESFromK8sLabelSelector("",
+			&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}}),
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	if ok, err := rpm.AddRedirectPolicy(config, &svcCache, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	if got := rpm.GetPolicyBackends(id); len(got["172.0.20.1:80"]) != 0 {
+		t.Fatalf("expected no backends before any matching pod existed, got %v", got)
+	}
+
+	// A pod matching the backend selector shows up in the pod store after
+	// the policy was added, simulating endpoint readiness becoming known
+	// before the corresponding pod update reaches the manager.
+	addTestPod(t, podStore, "foo-pod", "10.1.1.1", map[string]string{"app": "foo"})
+
+	k8sEndpoints := &slimcorev1.Endpoints{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "default",
+		},
+		Subsets: []slimcorev1.EndpointSubset{
+			{
+				Addresses: []slimcorev1.EndpointAddress{{IP: "10.1.1.1"}},
+				Ports:     []slimcorev1.EndpointPort{{Port: 80, Protocol: slimcorev1.ProtocolTCP}},
+			},
+		},
+	}
+	updatedID, _ := svcCache.UpdateEndpoints(k8sEndpoints, swg)
+
+	rpm.OnUpdateEndpoints(updatedID, &svcCache, podStore)
+
+	got := rpm.GetPolicyBackends(id)
+	if len(got["172.0.20.1:80"]) != 1 {
+		t.Fatalf("expected one backend to be recomputed after the endpoint update, got %v", got)
+	}
+}
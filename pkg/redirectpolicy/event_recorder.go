@@ -0,0 +1,62 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"fmt"
+
+	"github.com/cilium/cilium/pkg/logging/logfields"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// K8sEventRecorder records Kubernetes events by creating core/v1 Event
+// objects directly through the given client. component is reported as the
+// event's source.
+type K8sEventRecorder struct {
+	events    typedcorev1.EventsGetter
+	component string
+}
+
+// NewK8sEventRecorder returns an eventRecorder that records events through
+// events.
+func NewK8sEventRecorder(events typedcorev1.EventsGetter, component string) *K8sEventRecorder {
+	return &K8sEventRecorder{events: events, component: component}
+}
+
+// Eventf implements eventRecorder.
+func (r *K8sEventRecorder) Eventf(objRef *corev1.ObjectReference, eventtype, reason, messageFmt string, args ...interface{}) {
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", objRef.Name, now.UnixNano()),
+			Namespace: objRef.Namespace,
+		},
+		InvolvedObject: *objRef,
+		Reason:         reason,
+		Message:        fmt.Sprintf(messageFmt, args...),
+		Source:         corev1.EventSource{Component: r.component},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+		Type:           eventtype,
+	}
+	if _, err := r.events.Events(objRef.Namespace).CreateWithEventNamespace(event); err != nil {
+		log.WithError(err).WithField(logfields.LRPName, objRef.Name).
+			Warn("Failed to record Kubernetes event for local redirect policy")
+	}
+}
@@ -0,0 +1,117 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestParseBackendFieldSelector verifies that a supported field selector
+// parses successfully, an unsupported field is rejected, and an empty
+// selector is a no-op.
+func TestParseBackendFieldSelector(t *testing.T) {
+	if selector, err := parseBackendFieldSelector(""); err != nil || selector != nil {
+		t.Fatalf("expected an empty selector string to parse to a nil selector, got %v, err=%v", selector, err)
+	}
+
+	if _, err := parseBackendFieldSelector("spec.hostNetwork=true"); err != nil {
+		t.Fatalf("expected a supported field selector to parse, got: %v", err)
+	}
+
+	if _, err := parseBackendFieldSelector("spec.nodeName=foo"); err == nil {
+		t.Fatal("expected an unsupported field selector field to be rejected")
+	}
+}
+
+// TestLRPFieldSelectorConstraint verifies that a policy with a backend field
+// selector only selects pods whose spec fields match, even when their labels
+// match the backend selector.
+func TestLRPFieldSelectorConstraint(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	fieldSelector, err := parseBackendFieldSelector("spec.hostNetwork=true")
+	if err != nil {
+		t.Fatalf("failed to parse field selector: %v", err)
+	}
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+	config := LRPConfig{
+		id:                   policyID{Name: "test", Namespace: "default"},
+		lrpType:              lrpConfigTypeAddr,
+		frontendType:         addrFrontendSinglePort,
+		frontendMappings:     []*feMapping{feM},
+		backendSelector:      selector,
+		backendFieldSelector: fieldSelector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	rpm.storePolicyConfig(config)
+
+	hostNetworkPod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "host-network-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Spec: slimcorev1.PodSpec{HostNetwork: true},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	regularPod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "regular-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.2",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.2"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	for _, pod := range []*slimcorev1.Pod{hostNetworkPod, regularPod} {
+		if err := podStore.Add(pod); err != nil {
+			t.Fatalf("failed to add pod %s: %v", pod.Name, err)
+		}
+	}
+
+	rpm.OnAddPod(hostNetworkPod)
+	rpm.OnAddPod(regularPod)
+
+	stored := rpm.policyConfigs[config.id]
+	if len(stored.frontendMappings[0].backends) != 1 || !stored.frontendMappings[0].backends[0].IP.Equal(net.ParseIP("10.1.1.1")) {
+		t.Fatalf("expected only the host-network pod's backend, got %v", stored.frontendMappings[0].backends)
+	}
+}
@@ -0,0 +1,46 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+)
+
+// TestFilterBackendsRemovesOnlySpecifiedBackends verifies that filterBackends
+// removes exactly the backends passed to it, regardless of how many are
+// requested for removal, leaving the rest of fe.backends untouched.
+func TestFilterBackendsRemovesOnlySpecifiedBackends(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+
+	keep := backend{IP: net.ParseIP("10.1.1.1"), L4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}}
+	removeA := backend{IP: net.ParseIP("10.1.1.2"), L4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}}
+	removeB := backend{IP: net.ParseIP("10.1.1.3"), L4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}}
+
+	fe := &feMapping{
+		feAddr:   lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		backends: []backend{keep, removeA, removeB},
+	}
+
+	remaining := rpm.filterBackends(fe, "owner-uid", removeA, removeB)
+	if len(remaining) != 1 {
+		t.Fatalf("expected exactly one backend to remain, got %d: %v", len(remaining), remaining)
+	}
+	if !remaining[0].IP.Equal(keep.IP) {
+		t.Fatalf("expected the untouched backend to remain, got %v", remaining[0])
+	}
+}
@@ -0,0 +1,62 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestAddRedirectPolicyAllowsSameIPPortDifferentProtocol verifies that two
+// LRPs declaring the same frontend IP:port but different protocols (TCP vs
+// UDP) are treated as distinct frontends, not rejected as a collision.
+func TestAddRedirectPolicyAllowsSameIPPortDifferentProtocol(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	tcpConfig := LRPConfig{
+		id:      policyID{Name: "tcp-policy", Namespace: "default"},
+		lrpType: lrpConfigTypeAddr,
+		frontendMappings: []*feMapping{
+			{feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal)},
+		},
+	}
+	udpConfig := LRPConfig{
+		id:      policyID{Name: "udp-policy", Namespace: "default"},
+		lrpType: lrpConfigTypeAddr,
+		frontendMappings: []*feMapping{
+			{feAddr: lb.NewL3n4Addr(lb.UDP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal)},
+		},
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(tcpConfig, nil, podStore); !ok || err != nil {
+		t.Fatalf("expected the TCP policy to be accepted, got ok=%v err=%v", ok, err)
+	}
+	if ok, err := rpm.AddRedirectPolicy(udpConfig, nil, podStore); !ok || err != nil {
+		t.Fatalf("expected the UDP policy on the same IP:port to be accepted, got ok=%v err=%v", ok, err)
+	}
+
+	if _, ok := rpm.policyConfigs[tcpConfig.id]; !ok {
+		t.Fatalf("expected the TCP policy to remain stored")
+	}
+	if _, ok := rpm.policyConfigs[udpConfig.id]; !ok {
+		t.Fatalf("expected the UDP policy to remain stored")
+	}
+}
@@ -0,0 +1,141 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+)
+
+func TestGetPoliciesReturnsDeepCopy(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+
+	feM := &feMapping{
+		feAddr:   lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		backends: []backend{{IP: net.ParseIP("10.1.1.1"), L4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}}},
+	}
+	config := LRPConfig{
+		id:               policyID{Name: "test", Namespace: "default"},
+		lrpType:          lrpConfigTypeAddr,
+		frontendMappings: []*feMapping{feM},
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}, name: "http"},
+		},
+	}
+	rpm.storePolicyConfig(config)
+
+	policies := rpm.GetPolicies()
+	if len(policies) != 1 {
+		t.Fatalf("expected 1 policy, got %d", len(policies))
+	}
+
+	// Mutate everything reachable off the returned config.
+	got := policies[0]
+	got.frontendMappings[0].feAddr.IP = net.ParseIP("10.0.0.2")
+	got.frontendMappings[0].backends[0].IP = net.ParseIP("10.1.1.2")
+	got.frontendMappings = append(got.frontendMappings, &feMapping{})
+	got.backendPorts[0].name = "mutated"
+	got.backendPorts = append(got.backendPorts, bePortInfo{name: "extra"})
+
+	stored := rpm.policyConfigs[config.id]
+	if !stored.frontendMappings[0].feAddr.IP.Equal(net.ParseIP("10.0.0.1")) {
+		t.Fatalf("mutating the returned frontend address affected manager state: %v", stored.frontendMappings[0].feAddr.IP)
+	}
+	if !stored.frontendMappings[0].backends[0].IP.Equal(net.ParseIP("10.1.1.1")) {
+		t.Fatalf("mutating the returned backend affected manager state: %v", stored.frontendMappings[0].backends[0].IP)
+	}
+	if len(stored.frontendMappings) != 1 {
+		t.Fatalf("appending to the returned frontend mappings affected manager state: %d", len(stored.frontendMappings))
+	}
+	if len(stored.backendPorts) != 1 || stored.backendPorts[0].name != "http" {
+		t.Fatalf("mutating the returned backend ports affected manager state: %+v", stored.backendPorts)
+	}
+}
+
+func TestGetPolicyBackendsReturnsDeepCopy(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+
+	feAddr := lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal)
+	feM := &feMapping{
+		feAddr:   feAddr,
+		backends: []backend{{IP: net.ParseIP("10.1.1.1"), L4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}}},
+	}
+	id := policyID{Name: "test", Namespace: "default"}
+	config := LRPConfig{
+		id:               id,
+		lrpType:          lrpConfigTypeAddr,
+		frontendMappings: []*feMapping{feM},
+	}
+	rpm.storePolicyConfig(config)
+
+	backendsByFrontend := rpm.GetPolicyBackends(id)
+	bes, ok := backendsByFrontend[feAddr.String()]
+	if !ok || len(bes) != 1 {
+		t.Fatalf("expected 1 backend for frontend %v, got %v", feAddr, backendsByFrontend)
+	}
+
+	bes[0].IP = net.ParseIP("10.1.1.2")
+	backendsByFrontend[feAddr.String()] = append(bes, backend{})
+
+	if len(feM.backends) != 1 || !feM.backends[0].IP.Equal(net.ParseIP("10.1.1.1")) {
+		t.Fatalf("mutating the returned backends affected manager state: %v", feM.backends)
+	}
+
+	if rpm.GetPolicyBackends(policyID{Name: "missing", Namespace: "default"}) != nil {
+		t.Fatal("expected nil for an unknown policy")
+	}
+}
+
+func TestAllFrontends(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+
+	feM1 := &feMapping{feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal)}
+	config1 := LRPConfig{
+		id:               policyID{Name: "test1", Namespace: "default"},
+		lrpType:          lrpConfigTypeAddr,
+		frontendMappings: []*feMapping{feM1},
+	}
+	rpm.storePolicyConfig(config1)
+
+	feM2 := &feMapping{feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.2"), 443, lb.ScopeExternal)}
+	config2 := LRPConfig{
+		id:               policyID{Name: "test2", Namespace: "default"},
+		lrpType:          lrpConfigTypeAddr,
+		frontendMappings: []*feMapping{feM2},
+	}
+	rpm.storePolicyConfig(config2)
+
+	frontends := rpm.AllFrontends()
+	if len(frontends) != 2 {
+		t.Fatalf("expected 2 frontends, got %d: %v", len(frontends), frontends)
+	}
+
+	seen := make(map[string]bool, len(frontends))
+	for _, fe := range frontends {
+		seen[fe.String()] = true
+	}
+	if !seen[feM1.feAddr.String()] || !seen[feM2.feAddr.String()] {
+		t.Fatalf("expected both policies' frontends to be present, got %v", frontends)
+	}
+
+	// Mutating the returned slice must not affect manager state.
+	frontends[0].IP = net.ParseIP("10.0.0.99")
+	if rpm.policyConfigs[config1.id].frontendMappings[0].feAddr.IP.Equal(net.ParseIP("10.0.0.99")) ||
+		rpm.policyConfigs[config2.id].frontendMappings[0].feAddr.IP.Equal(net.ParseIP("10.0.0.99")) {
+		t.Fatal("mutating the returned frontends affected manager state")
+	}
+}
@@ -0,0 +1,144 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"testing"
+
+	fakeDatapath "github.com/cilium/cilium/pkg/datapath/fake"
+	"github.com/cilium/cilium/pkg/k8s"
+	v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// headlessServiceSpec returns a CiliumLocalRedirectPolicySpec matching the
+// "headless" service, redirecting its named "http" port and optionally
+// opting into redirectEndpoints.
+func headlessServiceSpec(redirectEndpoints bool) v2.CiliumLocalRedirectPolicySpec {
+	return v2.CiliumLocalRedirectPolicySpec{
+		RedirectFrontend: v2.RedirectFrontend{
+			ServiceMatcher: &v2.ServiceInfo{
+				Name:              "headless",
+				Namespace:         "default",
+				ToPorts:           []v2.PortInfo{{Port: "80", Name: "http", Protocol: api.ProtoTCP}},
+				RedirectEndpoints: redirectEndpoints,
+			},
+		},
+		RedirectBackend: v2.RedirectBackend{
+			LocalEndpointSelector: slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+			ToPorts:               []v2.PortInfo{{Port: "8080", Name: "http", Protocol: api.ProtoTCP}},
+		},
+	}
+}
+
+func addHeadlessServiceAndEndpoints(t *testing.T, svcCache *k8s.ServiceCache) k8s.ServiceID {
+	t.Helper()
+
+	k8sSvc := &slimcorev1.Service{
+		ObjectMeta: slim_metav1.ObjectMeta{Name: "headless", Namespace: "default"},
+		Spec: slimcorev1.ServiceSpec{
+			ClusterIP: "None",
+			Type:      slimcorev1.ServiceTypeClusterIP,
+			Ports:     []slimcorev1.ServicePort{{Name: "http", Port: 80, Protocol: slimcorev1.ProtocolTCP}},
+		},
+	}
+	svcID := svcCache.UpdateService(k8sSvc, lock.NewStoppableWaitGroup())
+
+	k8sEndpoints := &slimcorev1.Endpoints{
+		ObjectMeta: slim_metav1.ObjectMeta{Name: "headless", Namespace: "default"},
+		Subsets: []slimcorev1.EndpointSubset{{
+			Addresses: []slimcorev1.EndpointAddress{{IP: "10.1.1.1"}, {IP: "10.1.1.2"}},
+			Ports:     []slimcorev1.EndpointPort{{Name: "http", Port: 80, Protocol: slimcorev1.ProtocolTCP}},
+		}},
+	}
+	svcCache.UpdateEndpoints(k8sEndpoints, lock.NewStoppableWaitGroup())
+
+	return svcID
+}
+
+// TestRedirectEndpointsResolvesHeadlessServiceFrontends verifies that a
+// service-matcher policy with redirectEndpoints set redirects each of a
+// headless service's endpoints, rather than leaving the policy unresolved.
+func TestRedirectEndpointsResolvesHeadlessServiceFrontends(t *testing.T) {
+	config, err := getSanitizedLRPConfig("headless-lrp", "default", "", headlessServiceSpec(true))
+	if err != nil {
+		t.Fatalf("failed to sanitize config: %v", err)
+	}
+
+	svcCache := k8s.NewServiceCache(fakeDatapath.NewNodeAddressing())
+	svcID := addHeadlessServiceAndEndpoints(t, &svcCache)
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	config.serviceID = &svcID
+	rpm.getAndUpsertPolicySvcConfig(config, &svcCache, podStore)
+
+	if config.unresolvedReason != "" {
+		t.Fatalf("expected the policy to be resolved, got unresolvedReason %q", config.unresolvedReason)
+	}
+	if len(config.frontendMappings) != 2 {
+		t.Fatalf("expected one frontend per endpoint, got %d: %v", len(config.frontendMappings), config.frontendMappings)
+	}
+	seen := map[string]bool{}
+	for _, feM := range config.frontendMappings {
+		seen[feM.feAddr.IP.String()] = true
+	}
+	if !seen["10.1.1.1"] || !seen["10.1.1.2"] {
+		t.Fatalf("expected frontends for both endpoint IPs, got %v", seen)
+	}
+}
+
+// TestRedirectEndpointsUnsetLeavesHeadlessServiceUnresolved verifies that,
+// absent redirectEndpoints, a headless service is left unresolved exactly as
+// before this feature was added.
+func TestRedirectEndpointsUnsetLeavesHeadlessServiceUnresolved(t *testing.T) {
+	config, err := getSanitizedLRPConfig("headless-lrp", "default", "", headlessServiceSpec(false))
+	if err != nil {
+		t.Fatalf("failed to sanitize config: %v", err)
+	}
+
+	svcCache := k8s.NewServiceCache(fakeDatapath.NewNodeAddressing())
+	svcID := addHeadlessServiceAndEndpoints(t, &svcCache)
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	config.serviceID = &svcID
+	rpm.getAndUpsertPolicySvcConfig(config, &svcCache, podStore)
+
+	if config.unresolvedReason == "" {
+		t.Fatal("expected the policy to remain unresolved without redirectEndpoints set")
+	}
+	if len(config.frontendMappings) != 0 {
+		t.Fatalf("expected no frontends, got %v", config.frontendMappings)
+	}
+}
+
+// TestRedirectEndpointsRejectsFrontendAll verifies that redirectEndpoints is
+// rejected at config sanitization time for a service matcher with no
+// explicit toPorts, since there is no static port to match endpoints
+// against.
+func TestRedirectEndpointsRejectsFrontendAll(t *testing.T) {
+	spec := headlessServiceSpec(true)
+	spec.RedirectFrontend.ServiceMatcher.ToPorts = nil
+
+	if _, err := getSanitizedLRPConfig("headless-lrp", "default", "", spec); err == nil {
+		t.Fatal("expected an error for redirectEndpoints without toPorts")
+	}
+}
@@ -0,0 +1,111 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// hostNetworkLRP builds an address-matcher LRPConfig matching pods labeled
+// app=foo, with allowHostNetworkBackends set as given.
+func hostNetworkLRP(allowHostNetworkBackends bool) LRPConfig {
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+	return LRPConfig{
+		id:                       policyID{Name: "test", Namespace: "default"},
+		lrpType:                  lrpConfigTypeAddr,
+		frontendType:             addrFrontendSinglePort,
+		frontendMappings:         []*feMapping{feM},
+		backendSelector:          selector,
+		allowHostNetworkBackends: allowHostNetworkBackends,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+}
+
+func hostNetworkPod() *slimcorev1.Pod {
+	return &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "host-network-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Spec: slimcorev1.PodSpec{HostNetwork: true},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+}
+
+// TestHostNetworkBackendExcludedByDefault verifies that a hostNetwork pod
+// isn't installed as a backend for a policy that hasn't opted into
+// allowHostNetworkBackends, to avoid a redirect loop onto the node's own
+// address.
+func TestHostNetworkBackendExcludedByDefault(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	config := hostNetworkLRP(false)
+	rpm.storePolicyConfig(config)
+
+	pod := hostNetworkPod()
+	if err := podStore.Add(pod); err != nil {
+		t.Fatalf("failed to add pod: %v", err)
+	}
+	rpm.OnAddPod(pod)
+
+	stored := rpm.policyConfigs[config.id]
+	if len(stored.frontendMappings[0].backends) != 0 {
+		t.Fatalf("expected the hostNetwork pod to be excluded by default, got backends %v", stored.frontendMappings[0].backends)
+	}
+}
+
+// TestHostNetworkBackendAllowedWhenOptedIn verifies that a hostNetwork pod
+// is installed as a backend once the policy explicitly opts into
+// allowHostNetworkBackends.
+func TestHostNetworkBackendAllowedWhenOptedIn(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	config := hostNetworkLRP(true)
+	rpm.storePolicyConfig(config)
+
+	pod := hostNetworkPod()
+	if err := podStore.Add(pod); err != nil {
+		t.Fatalf("failed to add pod: %v", err)
+	}
+	rpm.OnAddPod(pod)
+
+	stored := rpm.policyConfigs[config.id]
+	if len(stored.frontendMappings[0].backends) != 1 || !stored.frontendMappings[0].backends[0].IP.Equal(net.ParseIP("10.1.1.1")) {
+		t.Fatalf("expected the hostNetwork pod to be installed as a backend, got %v", stored.frontendMappings[0].backends)
+	}
+}
@@ -0,0 +1,68 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+)
+
+// TestDeletePolicyBackendsIPReuse simulates a deleted pod's IP being reused
+// by a new pod before the old pod's delete event is processed. The old
+// pod's backend removal must not strip the new pod's identical-looking
+// IP:port backend.
+func TestDeletePolicyBackendsIPReuse(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+
+	config := &LRPConfig{
+		id:      policyID{Name: "test", Namespace: "default"},
+		lrpType: lrpConfigTypeAddr,
+	}
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+	config.frontendMappings = []*feMapping{feM}
+
+	oldPod := podID{Name: "old", Namespace: "default"}
+	newPod := podID{Name: "new", Namespace: "default"}
+	be := backend{IP: net.ParseIP("10.1.1.1"), L4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}}
+
+	// The old pod owns the backend first.
+	rpm.upsertServiceWithBackends(config, feM, oldPod, "uid-old", true, 0, "", "", []backend{be})
+	oldInfo := rpm.policyPods[oldPod][0]
+
+	// Its IP is reused by a new pod before the old pod's delete event is
+	// processed, so the new pod claims the same IP:port backend.
+	rpm.upsertServiceWithBackends(config, feM, newPod, "uid-new", true, 0, "", "", []backend{be})
+
+	// The old pod's delete event is now processed; it must not remove the
+	// backend since it's now owned by the new pod.
+	rpm.deletePolicyBackends(config, oldInfo.podUID, oldInfo.backends...)
+
+	if len(feM.backends) != 1 {
+		t.Fatalf("expected the reused backend to survive the stale pod's delete, got %v", feM.backends)
+	}
+
+	// Deleting the new pod's backends (the actual current owner) must
+	// remove it.
+	newInfo := rpm.policyPods[newPod][0]
+	rpm.deletePolicyBackends(config, newInfo.podUID, newInfo.backends...)
+	if len(feM.backends) != 0 {
+		t.Fatalf("expected the backend to be removed once its real owner is deleted, got %v", feM.backends)
+	}
+}
@@ -0,0 +1,87 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cilium/cilium/pkg/k8s"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestIsValidConfigSurvivesPolicyServicesDrift verifies that isValidConfig
+// doesn't panic when policyServices references a policy ID no longer
+// present in policyConfigs -- the same drift condition
+// TestOnAddServiceSurvivesPolicyServicesDrift simulates for
+// reconcileServiceConfigLocked, but hit instead via AddRedirectPolicy's
+// conflicting-service-matcher check.
+func TestIsValidConfigSurvivesPolicyServicesDrift(t *testing.T) {
+	hook := &recordingHook{levels: []logrus.Level{logrus.WarnLevel}}
+	log.Logger.AddHook(hook)
+	defer func() {
+		hooks := log.Logger.Hooks[logrus.WarnLevel]
+		for i, h := range hooks {
+			if h == hook {
+				log.Logger.Hooks[logrus.WarnLevel] = append(hooks[:i], hooks[i+1:]...)
+				break
+			}
+		}
+	}()
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	svcID := k8s.ServiceID{Name: "foo", Namespace: "bar"}
+	stale := &LRPConfig{
+		id:              policyID{Name: "stale-lrp", Namespace: "bar"},
+		lrpType:         lrpConfigTypeSvc,
+		frontendType:    svcFrontendAll,
+		serviceID:       &svcID,
+		backendSelector: selector,
+	}
+	rpm.storePolicyConfig(*stale)
+	delete(rpm.policyConfigs, stale.id)
+
+	newConfig := LRPConfig{
+		id:              policyID{Name: "new-lrp", Namespace: "bar"},
+		lrpType:         lrpConfigTypeSvc,
+		frontendType:    svcFrontendAll,
+		serviceID:       &svcID,
+		backendSelector: selector,
+	}
+
+	ok, err := rpm.AddRedirectPolicy(newConfig, nil, podStore)
+	if !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	found := false
+	for _, entry := range hook.entries {
+		if strings.Contains(entry.Message, "policyConfigs") {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatal("expected a warning about the policyServices/policyConfigs drift to be logged")
+	}
+}
@@ -15,22 +15,33 @@
 package redirectpolicy
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net"
+	"sort"
+	"strconv"
+	"time"
 
+	"github.com/cilium/cilium/pkg/controller"
 	"github.com/cilium/cilium/pkg/k8s"
+	v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
 	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
 	k8sUtils "github.com/cilium/cilium/pkg/k8s/utils"
 	lb "github.com/cilium/cilium/pkg/loadbalancer"
 	"github.com/cilium/cilium/pkg/lock"
 	"github.com/cilium/cilium/pkg/logging"
 	"github.com/cilium/cilium/pkg/logging/logfields"
+	"github.com/cilium/cilium/pkg/metrics"
 	nodeTypes "github.com/cilium/cilium/pkg/node/types"
 	"github.com/cilium/cilium/pkg/option"
 	serviceStore "github.com/cilium/cilium/pkg/service/store"
 	"github.com/cilium/cilium/pkg/u8proto"
 
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	k8sTypes "k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -39,9 +50,33 @@ var (
 	localRedirectSvcStr = "-local-redirect"
 )
 
+// interfaceResolveInterval is how often the redirect policy manager retries
+// resolving an address-type LRP frontend that binds to a local interface,
+// both to pick up an interface that didn't exist yet and to notice an
+// address change on one that did.
+const interfaceResolveInterval = 30 * time.Second
+
 type svcManager interface {
 	DeleteService(frontend lb.L3n4Addr) (bool, error)
 	UpsertService(*lb.SVC) (bool, lb.ID, error)
+	GetDeepCopyServices() []*lb.SVC
+}
+
+// eventRecorder records a Kubernetes event against the CiliumLocalRedirectPolicy
+// identified by objRef. A nil eventRecorder is valid; Manager treats it as "no
+// recorder configured" and skips event emission.
+type eventRecorder interface {
+	Eventf(objRef *corev1.ObjectReference, eventtype, reason, messageFmt string, args ...interface{})
+}
+
+// BackendHealthChecker reports whether a candidate backend is currently
+// healthy enough to receive redirected traffic, letting the manager exclude
+// an otherwise-selected backend whose target port isn't actually serving. A
+// nil BackendHealthChecker is valid; Manager treats it as "no health
+// checking configured" and installs every selected backend, which is the
+// default behavior.
+type BackendHealthChecker interface {
+	IsHealthy(ip net.IP, port lb.L4Addr) bool
 }
 
 // podID is pod name and namespace
@@ -58,6 +93,21 @@ type Manager struct {
 	svcManager svcManager
 
 	// Mutex to protect against concurrent access to the maps
+	//
+	// This is a single lock rather than a per-policy or sharded one. That
+	// was considered (see BenchmarkAddRedirectPolicyIndependentPolicies and
+	// TestAddRedirectPolicyConcurrentIndependentPoliciesRace): sharding by
+	// policyID would help AddRedirectPolicy/DeleteRedirectPolicy run
+	// concurrently for unrelated policies, but most of the maps below
+	// (policyPods, backendOwners, backendWeights, backendZones,
+	// backendPortNames, policyServices, skipRedirectIPs) are keyed by pod
+	// ID, backend IP:port:protocol hash, or service ID rather than
+	// policyID, and OnAddPod/OnDeletePod/Resync iterate across policies in
+	// a single pass. Sharding the lock by policyID wouldn't make those
+	// maps safe to access concurrently, and sharding the maps themselves by
+	// a key that doesn't match their natural lookup key is a bigger,
+	// riskier change than fits here. Left as a single lock; revisit if
+	// profiling shows this is an actual bottleneck.
 	mutex lock.RWMutex
 
 	// Stores mapping of all the current redirect policy frontend to their
@@ -72,41 +122,398 @@ type Manager struct {
 	policyPods map[podID][]podPolicyInfo
 	// Stores redirect policy configs indexed by policyID
 	policyConfigs map[policyID]*LRPConfig
+	// linkGetter resolves interface-name frontends to their primary address
+	linkGetter linkGetter
+	// controllers manages the background jobs that re-resolve interface-name
+	// frontends, keyed by policy
+	controllers *controller.Manager
+	// policyStats stores per-policy event counters, indexed by policyID
+	policyStats map[policyID]*policyStats
+	// backendOwners tracks which pod UID currently owns a given backend
+	// IP:port:protocol, so that removing a deleted pod's backends doesn't
+	// accidentally remove a different pod's backend after IP reuse.
+	backendOwners map[string]k8sTypes.UID
+	// backendWeights tracks the last-known backend weight requested by the
+	// pod owning a given backend IP:port:protocol, per backendWeightAnnotation.
+	// Entries are added alongside backendOwners and removed when the backend
+	// is genuinely removed, so a reused IP:port:protocol always reflects its
+	// current owner's weight.
+	backendWeights map[string]uint16
+	// backendZones tracks the last-known topology zone of the pod owning a
+	// given backend IP:port:protocol, per podZone. Entries are added and
+	// removed alongside backendWeights.
+	backendZones map[string]string
+	// backendPortNames tracks the backend port name that matched a given
+	// backend IP:port:protocol, for named-port policies, so that the
+	// upserted service reports which container port name a backend was
+	// selected through. Entries are added and removed alongside
+	// backendWeights.
+	backendPortNames map[string]string
+	// observers are notified when a pod IP starts or stops backing a
+	// skipRedirectFromBackend policy.
+	observers map[BackendIPObserver]struct{}
+	// skipRedirectIPs is the last reported set of pod IPs backing a
+	// skipRedirectFromBackend policy, indexed by IP string, so that
+	// reconcileSkipRedirectBackends can notify observers of only the delta.
+	skipRedirectIPs map[string]net.IP
+	// eventRecorder records Kubernetes events against LRP objects. May be
+	// nil, in which case event emission is skipped.
+	eventRecorder eventRecorder
+	// reportedNoBackends tracks which policies currently have a recorded
+	// NoLocalBackends event outstanding, so that repeated zero-backend
+	// evaluations (e.g. a flapping pod) don't spam the API server with
+	// duplicate events. Cleared once the policy has a backend again.
+	reportedNoBackends map[policyID]bool
+	// reportedFamilyMismatch tracks which policies currently have a recorded
+	// address family mismatch diagnostic outstanding, mirroring
+	// reportedNoBackends so repeated evaluations don't spam the log/metric.
+	// Cleared once the policy has a backend again.
+	reportedFamilyMismatch map[policyID]bool
+	// pendingFrontendDeletes tracks in-flight restartGracePeriod service
+	// deletion timers, indexed by frontend hash, so that a backend added to
+	// the same frontend before the timer fires (e.g. a single-backend pod
+	// recreated under a new UID with the same name) can cancel it.
+	pendingFrontendDeletes map[string]*time.Timer
+	// pendingPolicyDeletes tracks in-flight deleteDrainPeriod teardown
+	// timers, indexed by policyID, so that a policy re-added under the same
+	// ID before the timer fires can cancel it.
+	pendingPolicyDeletes map[policyID]*time.Timer
+	// strictSelectorMatch, if enabled via WithStrictSelectorMatch, causes
+	// AddRedirectPolicy to reject a newly added policy whose backend
+	// selector matches zero pods, rather than the default lenient behavior
+	// of storing the config and waiting for a matching pod to appear later.
+	strictSelectorMatch bool
+	// healthChecker, if set via WithBackendHealthChecker, is consulted for
+	// every candidate backend before it's installed, excluding any that
+	// aren't currently healthy. Nil preserves the default behavior of
+	// installing every selected backend regardless of health.
+	healthChecker BackendHealthChecker
+	// pendingHealthBackends tracks, by backend IP:port:protocol hash, the
+	// information needed to install a backend that was excluded because it
+	// failed a health check, once BackendHealthChanged reports it healthy
+	// again, without re-deriving it from the pod store.
+	pendingHealthBackends map[string]pendingHealthBackend
+	// closed is set by Close, once the manager is shutting down. Every
+	// public handler checks it right after acquiring mutex and bails out
+	// instead of mutating state that's in the middle of being torn down.
+	closed bool
+	// backendSnapshotStore, if set via WithBackendSnapshotStore, receives a
+	// snapshot of each policy's resolved backends on install/update, and is
+	// consulted by RestorePolicyBackends at startup. Nil by default, in
+	// which case no snapshotting happens.
+	backendSnapshotStore backendSnapshotStore
+}
+
+// NewRedirectPolicyManager returns a new Manager. eventRecorder may be nil,
+// in which case the manager skips recording Kubernetes events. Additional,
+// less commonly overridden behavior can be configured via opts, so that
+// future options don't require growing this signature further.
+func NewRedirectPolicyManager(svc svcManager, eventRecorder eventRecorder, opts ...ManagerOption) *Manager {
+	rpm := &Manager{
+		svcManager:             svc,
+		policyFrontendsByHash:  make(map[string]policyID),
+		policyServices:         make(map[k8s.ServiceID]policyID),
+		policyPods:             make(map[podID][]podPolicyInfo),
+		policyConfigs:          make(map[policyID]*LRPConfig),
+		linkGetter:             netlinkLinkGetter{},
+		controllers:            controller.NewManager(),
+		policyStats:            make(map[policyID]*policyStats),
+		backendOwners:          make(map[string]k8sTypes.UID),
+		backendWeights:         make(map[string]uint16),
+		backendZones:           make(map[string]string),
+		backendPortNames:       make(map[string]string),
+		observers:              make(map[BackendIPObserver]struct{}),
+		skipRedirectIPs:        make(map[string]net.IP),
+		eventRecorder:          eventRecorder,
+		reportedNoBackends:     make(map[policyID]bool),
+		reportedFamilyMismatch: make(map[policyID]bool),
+		pendingFrontendDeletes: make(map[string]*time.Timer),
+		pendingPolicyDeletes:   make(map[policyID]*time.Timer),
+		pendingHealthBackends:  make(map[string]pendingHealthBackend),
+	}
+
+	for _, opt := range opts {
+		opt(rpm)
+	}
+
+	return rpm
+}
+
+// ManagerOption configures optional Manager behavior that most callers don't
+// need to override, so that adding a new one doesn't require changing
+// NewRedirectPolicyManager's signature.
+type ManagerOption func(*Manager)
+
+// WithLinkGetter overrides the linkGetter used to resolve interface-name
+// frontends to their primary address, in place of the default netlink-backed
+// implementation. Mainly useful for tests.
+func WithLinkGetter(lg linkGetter) ManagerOption {
+	return func(rpm *Manager) {
+		rpm.linkGetter = lg
+	}
+}
+
+// WithObserver registers o to be notified of skipRedirectFromBackend pod IP
+// changes, equivalent to calling Subscribe on the Manager after construction.
+func WithObserver(o BackendIPObserver) ManagerOption {
+	return func(rpm *Manager) {
+		rpm.observers[o] = struct{}{}
+	}
+}
+
+// WithStrictSelectorMatch causes AddRedirectPolicy to reject a newly added
+// policy whose backend selector matches zero pods, returning an error and
+// recording a SelectorMatchesNoPods warning event, instead of the default
+// lenient behavior of storing the config silently until a matching pod
+// appears. A selector matching nothing is often a user mistake (e.g. a typo
+// in a label), so strict mode surfaces it immediately rather than waiting
+// for an operator to notice the policy isn't doing anything.
+func WithStrictSelectorMatch() ManagerOption {
+	return func(rpm *Manager) {
+		rpm.strictSelectorMatch = true
+	}
+}
+
+// WithBackendHealthChecker configures checker to be consulted before a
+// selected backend is installed, excluding any backend checker reports as
+// unhealthy. By default (no option, or a nil checker), every selected
+// backend is installed regardless of health.
+func WithBackendHealthChecker(checker BackendHealthChecker) ManagerOption {
+	return func(rpm *Manager) {
+		rpm.healthChecker = checker
+	}
+}
+
+// Subscribe adds the given BackendIPObserver to the manager, to be notified
+// upon changes to the set of pod IPs backing a skipRedirectFromBackend
+// policy.
+func (rpm *Manager) Subscribe(o BackendIPObserver) {
+	rpm.mutex.Lock()
+	defer rpm.mutex.Unlock()
+	if rpm.closed {
+		return
+	}
+	rpm.observers[o] = struct{}{}
+}
+
+// reconcileSkipRedirectBackends recomputes the current set of pod IPs
+// backing a skipRedirectFromBackend policy from policyPods and notifies
+// observers of any IP that was added or withdrawn since the last call. Must
+// be called with rpm.mutex held, after any mutation to policyPods.
+func (rpm *Manager) reconcileSkipRedirectBackends() {
+	current := make(map[string]net.IP)
+	for _, infos := range rpm.policyPods {
+		for _, info := range infos {
+			config, ok := rpm.policyConfigs[info.policyID]
+			if !ok || !config.skipRedirectFromBackend {
+				continue
+			}
+			for _, be := range info.backends {
+				current[be.IP.String()] = be.IP
+			}
+		}
+	}
+
+	for ipStr, ip := range current {
+		if _, ok := rpm.skipRedirectIPs[ipStr]; !ok {
+			for o := range rpm.observers {
+				o.LocalRedirectBackendIPAdded(ip)
+			}
+		}
+	}
+	for ipStr, ip := range rpm.skipRedirectIPs {
+		if _, ok := current[ipStr]; !ok {
+			for o := range rpm.observers {
+				o.LocalRedirectBackendIPRemoved(ip)
+			}
+		}
+	}
+
+	rpm.skipRedirectIPs = current
+}
+
+// policyStats holds a running count of the events observed for a single
+// local redirect policy, so that operators can gauge how much churn a
+// policy's backend set is experiencing over time.
+type policyStats struct {
+	// eventCount is the number of times the policy config was re-evaluated,
+	// e.g. due to a pod or service update.
+	eventCount int
+	// backendChurn is the number of times the re-evaluation above actually
+	// resulted in a different backend set being programmed.
+	backendChurn int
+	// lastUpdated is the time of the most recent event recorded above.
+	lastUpdated time.Time
+	// createdAt is the time the policy config was stored, used as the
+	// reference point for LRPTimeToFirstBackend.
+	createdAt time.Time
+	// firstBackendObserved is set once the policy has had at least one
+	// backend programmed, so LRPTimeToFirstBackend is only observed once.
+	firstBackendObserved bool
+}
+
+// recordPolicyEvent records that the given policy's config was re-evaluated.
+// The caller must hold rpm.mutex.
+func (rpm *Manager) recordPolicyEvent(id policyID) {
+	stats, ok := rpm.policyStats[id]
+	if !ok {
+		return
+	}
+	stats.eventCount++
+	stats.lastUpdated = time.Now()
+}
+
+// recordBackendChurn records that the given policy's programmed backend set
+// changed as a result of a re-evaluation. The caller must hold rpm.mutex.
+func (rpm *Manager) recordBackendChurn(id policyID) {
+	stats, ok := rpm.policyStats[id]
+	if !ok {
+		return
+	}
+	stats.backendChurn++
+	stats.lastUpdated = time.Now()
 }
 
-func NewRedirectPolicyManager(svc svcManager) *Manager {
-	return &Manager{
-		svcManager:            svc,
-		policyFrontendsByHash: make(map[string]policyID),
-		policyServices:        make(map[k8s.ServiceID]policyID),
-		policyPods:            make(map[podID][]podPolicyInfo),
-		policyConfigs:         make(map[policyID]*LRPConfig),
+// recordFirstBackend observes the duration since the given policy's config
+// was created, and records a Programmed event against it, the first time it
+// has a backend programmed. Subsequent calls are no-ops. The caller must
+// hold rpm.mutex.
+func (rpm *Manager) recordFirstBackend(config *LRPConfig) {
+	stats, ok := rpm.policyStats[config.id]
+	if !ok || stats.firstBackendObserved {
+		return
+	}
+	stats.firstBackendObserved = true
+	metrics.LRPTimeToFirstBackend.WithLabelValues(config.id.Namespace, config.id.Name).Observe(time.Since(stats.createdAt).Seconds())
+
+	if rpm.eventRecorder != nil {
+		rpm.eventRecorder.Eventf(configObjectRef(config.id, config.uid), corev1.EventTypeNormal, "Programmed",
+			"Local redirect policy %s was programmed with its first backend", config.id)
 	}
 }
 
+// ResetStats zeroes the event counters tracked for the given policy, without
+// affecting its programmed frontends, backends, or any other state. This
+// lets operators measure a fresh window, e.g. after applying a fix.
+func (rpm *Manager) ResetStats(id policyID) error {
+	rpm.mutex.Lock()
+	defer rpm.mutex.Unlock()
+
+	if rpm.closed {
+		return errManagerClosed
+	}
+
+	stats, ok := rpm.policyStats[id]
+	if !ok {
+		return fmt.Errorf("local redirect policy %v not found", id)
+	}
+	*stats = policyStats{}
+	return nil
+}
+
 // Event handlers
 
+// PolicyAddOutcome describes the result of adding or updating a local
+// redirect policy, distinguishing outcomes that AddRedirectPolicy's plain
+// bool return value collapses together.
+type PolicyAddOutcome int
+
+const (
+	// PolicyAddRejected means the policy failed validation, or, for a new
+	// policy with strictSelectorMatch enabled, its backend selector matched
+	// no pods. Nothing was stored.
+	PolicyAddRejected PolicyAddOutcome = iota
+	// PolicyAddUnchanged means an existing policy was resubmitted with no
+	// effective change, and the update was skipped.
+	PolicyAddUnchanged
+	// PolicyAddInstalledNoBackends means the policy was stored and applied,
+	// but currently has no backends, e.g. because its selector matches no
+	// ready pod yet.
+	PolicyAddInstalledNoBackends
+	// PolicyAddInstalledWithBackends means the policy was stored and applied,
+	// and has at least one backend installed.
+	PolicyAddInstalledWithBackends
+)
+
+// String returns a human-readable name for o, for logging and status
+// reporting.
+func (o PolicyAddOutcome) String() string {
+	switch o {
+	case PolicyAddRejected:
+		return "Rejected"
+	case PolicyAddUnchanged:
+		return "Unchanged"
+	case PolicyAddInstalledNoBackends:
+		return "InstalledNoBackends"
+	case PolicyAddInstalledWithBackends:
+		return "InstalledWithBackends"
+	default:
+		return "Unknown"
+	}
+}
+
 // AddRedirectPolicy parses the given local redirect policy config, and updates
-// internal state with the config fields.
+// internal state with the config fields. It's a thin wrapper around
+// AddRedirectPolicyOutcome for callers that only need to know whether the
+// policy was accepted, collapsing every installed or unchanged outcome to
+// true.
 func (rpm *Manager) AddRedirectPolicy(config LRPConfig, svcCache *k8s.ServiceCache, podStore cache.Store) (bool, error) {
+	outcome, err := rpm.AddRedirectPolicyOutcome(config, svcCache, podStore)
+	return outcome != PolicyAddRejected, err
+}
+
+// AddRedirectPolicyOutcome adds or updates the given local redirect policy
+// config and reports a typed outcome distinguishing rejection, a no-op
+// update, and installation with or without backends -- detail that
+// AddRedirectPolicy's bool return value can't express, for callers (e.g.
+// status reporting) that need to tell these cases apart.
+func (rpm *Manager) AddRedirectPolicyOutcome(config LRPConfig, svcCache *k8s.ServiceCache, podStore cache.Store) (PolicyAddOutcome, error) {
 	rpm.mutex.Lock()
 	defer rpm.mutex.Unlock()
 
-	_, ok := rpm.policyConfigs[config.id]
+	if rpm.closed {
+		return PolicyAddRejected, errManagerClosed
+	}
+
+	storedConfig, ok := rpm.policyConfigs[config.id]
 	if ok {
-		// TODO Existing policy update
-		log.Warn("Local redirect policy updates are not handled")
-		return true, nil
+		return rpm.updateRedirectPolicy(storedConfig, config, svcCache, podStore)
 	}
 
 	err := rpm.isValidConfig(config)
 	if err != nil {
-		return false, err
+		if rpm.eventRecorder != nil {
+			rpm.eventRecorder.Eventf(configObjectRef(config.id, config.uid), corev1.EventTypeWarning, "InvalidConfig",
+				"Local redirect policy %s is invalid: %s", config.id, err)
+		}
+		return PolicyAddRejected, err
 	}
 
 	// New redirect policy
 	rpm.storePolicyConfig(config)
+	pods := rpm.applyConfig(&config, svcCache, podStore)
 
+	if rpm.strictSelectorMatch && len(pods) == 0 {
+		if rpm.eventRecorder != nil {
+			rpm.eventRecorder.Eventf(configObjectRef(config.id, config.uid), corev1.EventTypeWarning, "SelectorMatchesNoPods",
+				"Local redirect policy %s backend selector %s matches no pods", config.id, config.backendSelector)
+		}
+		return PolicyAddRejected, fmt.Errorf("local redirect policy %s backend selector %s matches no pods", config.id, config.backendSelector)
+	}
+
+	rpm.snapshotPolicy(&config)
+	if totalBackends(&config) == 0 {
+		return PolicyAddInstalledNoBackends, nil
+	}
+	return PolicyAddInstalledWithBackends, nil
+}
+
+// applyConfig resolves frontends/backends for a newly stored config and
+// programs the resulting redirect service(s). It returns the pods selected
+// as backends, so callers can tell whether the config's selector matched
+// anything at all.
+func (rpm *Manager) applyConfig(config *LRPConfig, svcCache *k8s.ServiceCache, podStore cache.Store) []*podMetadata {
+	var pods []*podMetadata
 	switch config.lrpType {
 	case lrpConfigTypeAddr:
 		log.WithFields(logrus.Fields{
@@ -116,11 +523,11 @@ func (rpm *Manager) AddRedirectPolicy(config LRPConfig, svcCache *k8s.ServiceCac
 			logfields.LRPLocalEndpointSelector: config.backendSelector,
 			logfields.LRPBackendPorts:          config.backendPorts,
 		}).Debug("Add local redirect policy")
-		pods := rpm.getLocalPodsForPolicy(&config, podStore)
-		if len(pods) == 0 {
-			return true, nil
+		rpm.ensureInterfaceFrontends(config, podStore)
+		pods = rpm.getLocalPodsForPolicy(config, podStore)
+		if len(pods) > 0 {
+			rpm.upsertConfig(config, pods...)
 		}
-		rpm.upsertConfig(&config, pods...)
 
 	case lrpConfigTypeSvc:
 		log.WithFields(logrus.Fields{
@@ -132,30 +539,227 @@ func (rpm *Manager) AddRedirectPolicy(config LRPConfig, svcCache *k8s.ServiceCac
 			logfields.LRPBackendPorts:          config.backendPorts,
 		}).Debug("Add local redirect policy")
 
-		rpm.getAndUpsertPolicySvcConfig(&config, svcCache, podStore)
+		pods = rpm.getAndUpsertPolicySvcConfig(config, svcCache, podStore)
 	}
+	rpm.checkZeroBackends(config, pods)
+	return pods
+}
 
-	return true, nil
+// totalBackends returns the number of backends currently programmed across
+// all of the config's frontends.
+func totalBackends(config *LRPConfig) int {
+	count := 0
+	for _, feM := range config.frontendMappings {
+		count += len(feM.backends)
+	}
+	return count
 }
 
-// DeleteRedirectPolicy deletes the internal state associated with the given policy.
-func (rpm *Manager) DeleteRedirectPolicy(config LRPConfig) error {
-	rpm.mutex.Lock()
-	defer rpm.mutex.Unlock()
+// checkZeroBackends records a Warning NoLocalBackends event against config
+// the first time it is observed with no local backends, and clears that
+// state once it has a backend again, so a flapping pod can't spam repeated
+// events for the same policy. selectedPods, if non-empty, are checked for an
+// address family mismatch against config's frontends (see
+// checkFamilyMismatch). The caller must hold rpm.mutex.
+func (rpm *Manager) checkZeroBackends(config *LRPConfig, selectedPods []*podMetadata) {
+	if totalBackends(config) > 0 {
+		delete(rpm.reportedNoBackends, config.id)
+		delete(rpm.reportedFamilyMismatch, config.id)
+		return
+	}
 
-	storedConfig := rpm.policyConfigs[config.id]
-	if storedConfig == nil {
-		return fmt.Errorf("local redirect policy to be deleted not found")
+	rpm.checkFamilyMismatch(config, selectedPods)
+
+	if rpm.eventRecorder == nil {
+		return
 	}
-	log.WithFields(logrus.Fields{"policyID": config.id}).
-		Debug("Delete local redirect policy")
 
+	if rpm.reportedNoBackends[config.id] {
+		return
+	}
+	rpm.reportedNoBackends[config.id] = true
+
+	rpm.eventRecorder.Eventf(configObjectRef(config.id, config.uid), corev1.EventTypeWarning, "NoLocalBackends",
+		"Local redirect policy %s has no local backends", config.id)
+}
+
+// configObjectRef returns an ObjectReference to the CiliumLocalRedirectPolicy
+// backing id, for use with eventRecorder.Eventf.
+func configObjectRef(id policyID, uid k8sTypes.UID) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		Kind:       v2.CLRPKindDefinition,
+		APIVersion: v2.SchemeGroupVersion.String(),
+		Namespace:  id.Namespace,
+		Name:       id.Name,
+		UID:        uid,
+	}
+}
+
+// checkFamilyMismatch logs a warning and counts
+// metrics.LRPFamilyMismatchTotal, once per policy until it has a backend
+// again, when config currently has no backends and every pod it selects is
+// excluded purely by an address family mismatch against its frontends (e.g.
+// a v4 frontend selecting only v6-only pods). This distinguishes that
+// specific, easy-to-miss misconfiguration from other causes of a
+// backend-less policy, such as a selector matching no pods at all. The
+// caller must hold rpm.mutex.
+func (rpm *Manager) checkFamilyMismatch(config *LRPConfig, selectedPods []*podMetadata) {
+	if len(selectedPods) == 0 {
+		return
+	}
+	for _, pod := range selectedPods {
+		if !backendFamilyMismatch(config, pod) {
+			return
+		}
+	}
+
+	if rpm.reportedFamilyMismatch[config.id] {
+		return
+	}
+	rpm.reportedFamilyMismatch[config.id] = true
+
+	log.WithFields(logrus.Fields{
+		logfields.K8sNamespace: config.id.Namespace,
+		logfields.LRPName:      config.id.Name,
+	}).Warning("Local redirect policy has no backends because all selected pods are in a different address family than its frontend")
+	metrics.LRPFamilyMismatchTotal.WithLabelValues(config.id.Namespace, config.id.Name).Inc()
+}
+
+// updateRedirectPolicy handles a redirect policy update for a config that's
+// already stored. If the new config is identical to the one already in
+// effect, it's a no-op; otherwise the stale state for the old config is torn
+// down before the new config is applied, so that frontends or pods it no
+// longer selects stop being redirected.
+func (rpm *Manager) updateRedirectPolicy(storedConfig *LRPConfig, config LRPConfig, svcCache *k8s.ServiceCache, podStore cache.Store) (PolicyAddOutcome, error) {
+	if configsEqual(storedConfig, &config) {
+		log.WithFields(logrus.Fields{"policyID": config.id}).
+			Debug("Local redirect policy unchanged, skipping update")
+		return PolicyAddUnchanged, nil
+	}
+
+	config.id = storedConfig.id
+	if err := rpm.isValidConfig(config); err != nil {
+		if rpm.eventRecorder != nil {
+			rpm.eventRecorder.Eventf(configObjectRef(config.id, config.uid), corev1.EventTypeWarning, "InvalidConfig",
+				"Local redirect policy %s is invalid: %s", config.id, err)
+		}
+		return PolicyAddRejected, err
+	}
+
+	diff := DiffConfigs(*storedConfig, config)
+	log.WithFields(logrus.Fields{
+		"policyID":            config.id,
+		"addedFrontends":      len(diff.AddedFrontends),
+		"removedFrontends":    len(diff.RemovedFrontends),
+		"backendPortsChanged": diff.BackendPortsChanged,
+		"selectorChanged":     diff.SelectorChanged,
+	}).Debug("Update local redirect policy")
+
+	rpm.teardownConfig(storedConfig)
+	rpm.storePolicyConfig(config)
+	rpm.applyConfig(&config, svcCache, podStore)
+
+	rpm.snapshotPolicy(&config)
+	if totalBackends(&config) == 0 {
+		return PolicyAddInstalledNoBackends, nil
+	}
+	return PolicyAddInstalledWithBackends, nil
+}
+
+// configsEqual reports whether a and b would result in the same set of
+// frontends, backend selection and backend ports being programmed, so that
+// an update carrying no effective change can be skipped.
+func configsEqual(a, b *LRPConfig) bool {
+	if a.lrpType != b.lrpType || a.frontendType != b.frontendType {
+		return false
+	}
+	if a.backendSelector.String() != b.backendSelector.String() {
+		return false
+	}
+	if !backendPortsEqual(a.backendPorts, b.backendPorts) {
+		return false
+	}
+	if a.sessionAffinity != b.sessionAffinity || a.sessionAffinityTimeoutSec != b.sessionAffinityTimeoutSec {
+		return false
+	}
+
+	switch a.lrpType {
+	case lrpConfigTypeSvc:
+		return *a.serviceID == *b.serviceID
+	case lrpConfigTypeAddr:
+		return frontendHashes(a.frontendMappings).Equal(frontendHashes(b.frontendMappings))
+	}
+	return true
+}
+
+// backendPortsEqual reports whether two backend port lists carry the same
+// ports and protocols, ignoring order.
+func backendPortsEqual(a, b []bePortInfo) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[lb.L4Addr]int, len(a))
+	for _, p := range a {
+		seen[p.l4Addr]++
+	}
+	for _, p := range b {
+		if seen[p.l4Addr] == 0 {
+			return false
+		}
+		seen[p.l4Addr]--
+	}
+	return true
+}
+
+// frontendHash returns a key that uniquely identifies a frontend address for
+// use in policyFrontendsByHash and related lookups. lb.L3n4Addr.Hash omits
+// the protocol, so two frontends sharing an IP:port but differing only in
+// protocol (e.g. TCP vs UDP) would otherwise collide.
+func frontendHash(addr *frontend) string {
+	return addr.Hash() + "/" + string(addr.Protocol)
+}
+
+// frontendHashes returns the set of frontend address hashes in the given
+// frontend mappings.
+func frontendHashes(mappings []*feMapping) lbSets {
+	hashes := make(lbSets, len(mappings))
+	for _, feM := range mappings {
+		hashes[frontendHash(feM.feAddr)] = struct{}{}
+	}
+	return hashes
+}
+
+// lbSets is a set of frontend address hashes.
+type lbSets map[string]struct{}
+
+// Equal reports whether s and other contain the same hashes.
+func (s lbSets) Equal(other lbSets) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for h := range s {
+		if _, ok := other[h]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// teardownConfig removes all programmed state for storedConfig so that it
+// can be replaced by an updated config.
+// teardownConfig removes every LB service entry and all internal state
+// associated with storedConfig, returning any error the service manager
+// reported while doing so.
+func (rpm *Manager) teardownConfig(storedConfig *LRPConfig) error {
+	rpm.controllers.RemoveController(interfaceFrontendControllerName(storedConfig.id))
+
+	var err error
 	switch storedConfig.lrpType {
 	case lrpConfigTypeSvc:
-		rpm.deletePolicyService(*storedConfig.serviceID)
+		err = rpm.deletePolicyService(*storedConfig.serviceID)
 	case lrpConfigTypeAddr:
 		for _, feM := range storedConfig.frontendMappings {
-			rpm.deletePolicyFrontend(storedConfig, feM.feAddr)
+			err = errors.Join(err, rpm.deletePolicyFrontend(storedConfig, feM.feAddr))
 		}
 	}
 
@@ -172,8 +776,145 @@ func (rpm *Manager) DeleteRedirectPolicy(config LRPConfig) error {
 			delete(rpm.policyPods, p)
 		}
 	}
+	rpm.reconcileSkipRedirectBackends()
 	rpm.deletePolicyConfig(storedConfig)
-	return nil
+	return err
+}
+
+// DeleteRedirectPolicy deletes the internal state associated with the given policy.
+func (rpm *Manager) DeleteRedirectPolicy(config LRPConfig) error {
+	rpm.mutex.Lock()
+	defer rpm.mutex.Unlock()
+
+	if rpm.closed {
+		return errManagerClosed
+	}
+
+	storedConfig := rpm.policyConfigs[config.id]
+	if storedConfig == nil {
+		return fmt.Errorf("local redirect policy to be deleted not found")
+	}
+	log.WithFields(logrus.Fields{"policyID": config.id}).
+		Debug("Delete local redirect policy")
+
+	if storedConfig.deleteDrainPeriod > 0 {
+		rpm.drainAndTeardownConfig(storedConfig)
+		return nil
+	}
+
+	return rpm.teardownConfig(storedConfig)
+}
+
+// DeleteAllPolicies tears down every currently configured redirect policy:
+// it removes each one's LB service entry, and clears policyFrontendsByHash,
+// policyServices and policyPods along with the rest of their internal
+// state. This is meant for a clean shutdown or feature-disable path, where
+// callers would otherwise have to delete each policy individually and risk
+// missing one. Any errors the service manager reports while tearing down
+// individual policies are aggregated and returned; DeleteAllPolicies still
+// proceeds to tear down the remaining policies.
+func (rpm *Manager) DeleteAllPolicies() error {
+	rpm.mutex.Lock()
+	defer rpm.mutex.Unlock()
+
+	if rpm.closed {
+		return errManagerClosed
+	}
+
+	var err error
+	for _, storedConfig := range rpm.policyConfigs {
+		err = errors.Join(err, rpm.teardownConfig(storedConfig))
+	}
+	return err
+}
+
+// errManagerClosed is returned by every public handler once Close has been
+// called, instead of mutating state that's in the middle of being torn down.
+var errManagerClosed = errors.New("redirect policy manager is shutting down")
+
+// Close marks the manager as shutting down: every subsequent call to a
+// public handler is rejected with errManagerClosed (or, for handlers that
+// don't return an error, silently skipped) rather than risk mutating state
+// concurrently with, or after, a caller tearing the manager down. It doesn't
+// itself tear down any currently configured policy; callers that need that
+// should call DeleteAllPolicies first.
+func (rpm *Manager) Close() {
+	rpm.mutex.Lock()
+	defer rpm.mutex.Unlock()
+	rpm.closed = true
+}
+
+// drainAndTeardownConfig resets the weight of storedConfig's backends to
+// zero and upserts the service one last time with that weight, then defers
+// the actual teardown (service and backend removal) by
+// storedConfig.deleteDrainPeriod, so that in-flight connections to a
+// stateful local service have a chance to drain before the frontend
+// disappears. Zero is also the default weight (see lb.Backend.Weight), so
+// the backends are not marked apart from any other: they stay in the
+// service, unchanged in how traffic reaches them, until the teardown. The
+// caller must hold rpm.mutex.
+func (rpm *Manager) drainAndTeardownConfig(storedConfig *LRPConfig) {
+	if storedConfig.lrpType == lrpConfigTypeAddr {
+		for _, feM := range storedConfig.frontendMappings {
+			if len(feM.backends) == 0 {
+				continue
+			}
+			for _, be := range feM.backends {
+				rpm.backendWeights[be.StringWithProtocol()] = 0
+			}
+			rpm.upsertService(storedConfig, feM)
+		}
+	}
+
+	id := storedConfig.id
+	rpm.cancelPendingPolicyDelete(id)
+	rpm.pendingPolicyDeletes[id] = time.AfterFunc(storedConfig.deleteDrainPeriod, func() {
+		rpm.mutex.Lock()
+		defer rpm.mutex.Unlock()
+		delete(rpm.pendingPolicyDeletes, id)
+		if current, ok := rpm.policyConfigs[id]; ok && current == storedConfig {
+			rpm.teardownConfig(current)
+		}
+	})
+}
+
+// cancelPendingPolicyDelete stops and discards a deleteDrainPeriod teardown
+// timer for the policy identified by id, if one is outstanding. The caller
+// must hold rpm.mutex.
+func (rpm *Manager) cancelPendingPolicyDelete(id policyID) {
+	if timer, ok := rpm.pendingPolicyDeletes[id]; ok {
+		timer.Stop()
+		delete(rpm.pendingPolicyDeletes, id)
+	}
+}
+
+// Resync rebuilds the manager's view of every stored policy from the
+// current svcCache and podStore, as if each policy were being freshly
+// applied. This is primarily useful after an agent restart or a kvstore
+// reconnect, when pod or service events may have been missed while the
+// manager wasn't watching, leaving stale backends or frontends with no
+// backends behind in the LB maps.
+func (rpm *Manager) Resync(svcCache *k8s.ServiceCache, podStore cache.Store) {
+	rpm.mutex.Lock()
+	defer rpm.mutex.Unlock()
+
+	if rpm.closed {
+		return
+	}
+
+	configs := make([]*LRPConfig, 0, len(rpm.policyConfigs))
+	for _, config := range rpm.policyConfigs {
+		configs = append(configs, config)
+	}
+
+	for _, config := range configs {
+		rpm.teardownConfig(config)
+		for _, feM := range config.frontendMappings {
+			feM.backends = nil
+		}
+		rpm.storePolicyConfig(*config)
+		rpm.applyConfig(config, svcCache, podStore)
+	}
 }
 
 // OnAddService handles Kubernetes service (clusterIP type) add events, and
@@ -181,6 +922,58 @@ func (rpm *Manager) DeleteRedirectPolicy(config LRPConfig) error {
 func (rpm *Manager) OnAddService(svcID k8s.ServiceID, svcCache *k8s.ServiceCache, podStore cache.Store) {
 	rpm.mutex.Lock()
 	defer rpm.mutex.Unlock()
+	if rpm.closed {
+		return
+	}
+	rpm.checkAddrPolicyFrontendCollision(svcID, svcCache)
+	rpm.reconcileServiceConfigLocked(svcID, svcCache, podStore)
+}
+
+// checkAddrPolicyFrontendCollision logs a warning if svcID's own ClusterIP
+// frontend(s) happen to match the frontend an address-matcher LRP already
+// programs. An address-matcher policy doesn't reference svcID at all, so
+// reconcileServiceConfigLocked's policyServices lookup never notices this
+// case, but both the Kubernetes service and the policy would otherwise
+// silently race for ownership of the same datapath entry. Must be called
+// with rpm.mutex held.
+func (rpm *Manager) checkAddrPolicyFrontendCollision(svcID k8s.ServiceID, svcCache *k8s.ServiceCache) {
+	if svcCache == nil {
+		return
+	}
+
+	for _, addr := range svcCache.GetServiceAddrsWithType(svcID, lb.SVCTypeClusterIP) {
+		id, ok := rpm.policyFrontendsByHash[frontendHash(addr)]
+		if !ok {
+			continue
+		}
+		config, ok := rpm.policyConfigs[id]
+		if !ok || config.lrpType != lrpConfigTypeAddr {
+			continue
+		}
+		log.WithFields(logrus.Fields{
+			logfields.K8sSvcID: svcID,
+			logfields.LRPName:  id,
+		}).Warnf("Kubernetes service %s has a ClusterIP that collides with the frontend of local redirect policy %s; traffic to it may be redirected unexpectedly", svcID, id)
+	}
+}
+
+// OnUpdateEndpoints handles Kubernetes Endpoints/EndpointSlice update events
+// for svcID, and re-resolves the backends of the service-type policy config
+// associated with the service, if any. Endpoint readiness is a more direct
+// signal of backend eligibility than pod events, so this complements
+// OnAddPod/OnUpdatePod rather than replacing them.
+func (rpm *Manager) OnUpdateEndpoints(svcID k8s.ServiceID, svcCache *k8s.ServiceCache, podStore cache.Store) {
+	rpm.mutex.Lock()
+	defer rpm.mutex.Unlock()
+	if rpm.closed {
+		return
+	}
+	rpm.reconcileServiceConfigLocked(svcID, svcCache, podStore)
+}
+
+// reconcileServiceConfigLocked re-resolves the frontends and backends of the
+// policy config selecting svcID, if any. Must be called with rpm.mutex held.
+func (rpm *Manager) reconcileServiceConfigLocked(svcID k8s.ServiceID, svcCache *k8s.ServiceCache, podStore cache.Store) {
 	if len(rpm.policyConfigs) == 0 {
 		return
 	}
@@ -188,7 +981,15 @@ func (rpm *Manager) OnAddService(svcID k8s.ServiceID, svcCache *k8s.ServiceCache
 	// Check if this service is selected by any of the current policies.
 	if id, ok := rpm.policyServices[svcID]; ok {
 		// TODO Add unit test to assert lrpConfigType among other things.
-		config := rpm.policyConfigs[id]
+		config, ok := rpm.policyConfigs[id]
+		if !ok {
+			log.WithFields(logrus.Fields{
+				logfields.K8sSvcID: svcID,
+				logfields.LRPName:  id,
+			}).Warning("policyServices references a local redirect policy no longer in policyConfigs; dropping the stale entry")
+			delete(rpm.policyServices, svcID)
+			return
+		}
 		if !config.checkNamespace(svcID.Namespace) {
 			return
 		}
@@ -201,7 +1002,7 @@ func (rpm *Manager) OnAddService(svcID k8s.ServiceID, svcCache *k8s.ServiceCache
 func (rpm *Manager) OnDeleteService(svcID k8s.ServiceID) {
 	rpm.mutex.Lock()
 	defer rpm.mutex.Unlock()
-	if len(rpm.policyConfigs) == 0 {
+	if rpm.closed || len(rpm.policyConfigs) == 0 {
 		return
 	}
 
@@ -212,7 +1013,7 @@ func (rpm *Manager) OnAddPod(pod *slimcorev1.Pod) {
 	rpm.mutex.Lock()
 	defer rpm.mutex.Unlock()
 
-	if len(rpm.policyConfigs) == 0 {
+	if rpm.closed || len(rpm.policyConfigs) == 0 {
 		return
 	}
 	// If the pod already exists in the internal cache, ignore all the subsequent
@@ -240,24 +1041,78 @@ func (rpm *Manager) OnUpdatePodLocked(pod *slimcorev1.Pod) {
 	}
 	podData := rpm.getPodMetadata(pod, podIPs)
 
+	// affected tracks every policy whose backend set may have changed as a
+	// result of this pod event, so their zero-backend state can be
+	// re-evaluated once the dust settles below.
+	affected := make(map[policyID]*LRPConfig)
+
 	// Check if the pod was previously selected by any of the policies.
 	if policies, ok := rpm.policyPods[podData.id]; ok {
 		for _, podInfo := range policies {
 			config := rpm.policyConfigs[podInfo.policyID]
-			rpm.deletePolicyBackends(config, podInfo.backends...)
+			rpm.deletePolicyBackends(config, podInfo.podUID, podInfo.backends...)
+			affected[podInfo.policyID] = config
 		}
+		// The pod's selection is about to be recomputed below; drop the
+		// stale entries so hasReadyBackend doesn't see outdated state.
+		delete(rpm.policyPods, podData.id)
 	}
-	// Check if any of the current redirect policies select this pod.
-	for _, config := range rpm.policyConfigs {
+	// Check if any of the current redirect policies select this pod. Iterate
+	// in a deterministic order so that, when multiple policies target
+	// overlapping frontends, the same policy always wins the upsert.
+	ids := make([]policyID, 0, len(rpm.policyConfigs))
+	for id := range rpm.policyConfigs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+
+	for _, id := range ids {
+		config := rpm.policyConfigs[id]
+		if !config.policyConfigSelectsPod(podData) {
+			continue
+		}
+		// A terminating pod is only admitted as a backend if no other pod is
+		// currently backing the policy while ready.
+		if podData.terminating && rpm.hasReadyBackend(config.id, podData.id) {
+			continue
+		}
+		rpm.upsertConfig(config, podData)
+		affected[id] = config
+	}
+	for _, config := range affected {
+		var pods []*podMetadata
 		if config.policyConfigSelectsPod(podData) {
-			rpm.upsertConfig(config, podData)
+			pods = []*podMetadata{podData}
+		}
+		rpm.checkZeroBackends(config, pods)
+	}
+	// Covers the case where the pod no longer matches any policy, which
+	// wouldn't otherwise trigger a reconcile via upsertConfig above.
+	rpm.reconcileSkipRedirectBackends()
+}
+
+// hasReadyBackend reports whether a pod other than excludePod is currently
+// backing the given policy while Ready and not terminating.
+func (rpm *Manager) hasReadyBackend(id policyID, excludePod podID) bool {
+	for pID, infos := range rpm.policyPods {
+		if pID == excludePod {
+			continue
+		}
+		for _, info := range infos {
+			if info.policyID == id && info.ready {
+				return true
+			}
 		}
 	}
+	return false
 }
 
 func (rpm *Manager) OnUpdatePod(pod *slimcorev1.Pod) {
 	rpm.mutex.Lock()
 	defer rpm.mutex.Unlock()
+	if rpm.closed {
+		return
+	}
 	// TODO add unit test to validate that we get callbacks only for relevant events
 	rpm.OnUpdatePodLocked(pod)
 }
@@ -265,7 +1120,7 @@ func (rpm *Manager) OnUpdatePod(pod *slimcorev1.Pod) {
 func (rpm *Manager) OnDeletePod(pod *slimcorev1.Pod) {
 	rpm.mutex.Lock()
 	defer rpm.mutex.Unlock()
-	if len(rpm.policyConfigs) == 0 {
+	if rpm.closed || len(rpm.policyConfigs) == 0 {
 		return
 	}
 	id := k8s.ServiceID{
@@ -276,9 +1131,21 @@ func (rpm *Manager) OnDeletePod(pod *slimcorev1.Pod) {
 	if policies, ok := rpm.policyPods[id]; ok {
 		for _, podInfo := range policies {
 			config := rpm.policyConfigs[podInfo.policyID]
-			rpm.deletePolicyBackends(config, podInfo.backends...)
+			rpm.deletePolicyBackends(config, podInfo.podUID, podInfo.backends...)
+			rpm.checkZeroBackends(config, nil)
 		}
 		delete(rpm.policyPods, id)
+		rpm.reconcileSkipRedirectBackends()
+	}
+
+	// A pod whose every backend was unhealthy has no entry in policyPods
+	// (recordPodOwnership only records it once at least one backend is
+	// healthy), so its pendingHealthBackends entries, if any, wouldn't
+	// otherwise be cleaned up here.
+	for hash, pending := range rpm.pendingHealthBackends {
+		if pending.podID == id {
+			delete(rpm.pendingHealthBackends, hash)
+		}
 	}
 }
 
@@ -286,81 +1153,367 @@ func (rpm *Manager) OnDeletePod(pod *slimcorev1.Pod) {
 type podPolicyInfo struct {
 	policyID policyID
 	backends []backend
+	// podUID is the UID of the pod that owned backends at the time they were
+	// recorded, so that a later removal can be matched against the pod that
+	// currently owns the same IP:port, not just the IP:port itself.
+	podUID k8sTypes.UID
+	// ready records whether the pod was Ready and not terminating when these
+	// backends were recorded, so hasReadyBackend can tell whether any other
+	// pod is still backing the policy normally.
+	ready bool
+}
+
+// pendingHealthBackend holds enough information about a single pod-reported
+// backend that failed a health check to install it once
+// Manager.BackendHealthChanged reports it healthy again, without having to
+// re-derive it from the pod store.
+type pendingHealthBackend struct {
+	config          *LRPConfig
+	frontendMapping *feMapping
+	podID           podID
+	podUID          k8sTypes.UID
+	ready           bool
+	weight          uint16
+	zone            string
+	portName        string
+	backend         backend
 }
 
 // podMetadata stores relevant metadata associated with a pod that's updated during pod
 // add/update events
 type podMetadata struct {
 	labels map[string]string
+	// annotations are the pod's annotations, consulted by
+	// backendAnnotationSelector instead of labels.
+	annotations map[string]string
 	// id the pod's name and namespace
 	id podID
+	// uid is the pod's UID, used to disambiguate backends when a deleted
+	// pod's IP is quickly reused by a new pod.
+	uid k8sTypes.UID
 	// ips are pod's unique IPs
 	ips []string
+	// annotationIPs are the IPs parsed from the pod's Multus network-status
+	// annotation, used instead of ips when the selecting config has opted
+	// into backendsFromNetworkStatus.
+	annotationIPs []string
 	// namedPorts stores pod port and protocol indexed by the port name
 	namedPorts serviceStore.PortConfiguration
+	// serviceAccount is the name of the ServiceAccount the pod runs under
+	serviceAccount string
+	// hostNetwork is true if the pod uses the host's network namespace.
+	hostNetwork bool
+	// ready is true if the pod's Ready condition is True.
+	ready bool
+	// terminating is true if the pod has a DeletionTimestamp set.
+	terminating bool
+	// weight is the backend weight this pod requests relative to its
+	// policy's other backends, per backendWeightAnnotation, and is passed on
+	// as lb.Backend.Weight. 0 means equal weight with every other backend.
+	weight uint16
+	// zone is the topology zone reported for this pod's backends; see
+	// podZone. Empty if neither topologyZoneLabel nor
+	// backendZoneAnnotation is present.
+	zone string
 }
 
 // Note: Following functions need to be called with the redirect policy manager lock.
 
-// getAndUpsertPolicySvcConfig gets service frontends for the given config service
-// and upserts the service frontends.
-func (rpm *Manager) getAndUpsertPolicySvcConfig(config *LRPConfig, svcCache *k8s.ServiceCache, podStore cache.Store) {
+// getAndUpsertPolicySvcConfig gets service frontends for the given config
+// service and upserts the service frontends, returning the pods it found
+// selected by config's backend selector.
+func (rpm *Manager) getAndUpsertPolicySvcConfig(config *LRPConfig, svcCache *k8s.ServiceCache, podStore cache.Store) []*podMetadata {
+	if svcCache == nil {
+		log.WithFields(logrus.Fields{logfields.LRPName: config.id.Name}).
+			Debug("Skipping local redirect policy service update: service cache is not available")
+		metrics.LRPDroppedEventsTotal.Inc()
+		config.unresolvedReason = "service cache is not available"
+		return nil
+	}
+
+	config.sessionAffinity, config.sessionAffinityTimeoutSec = svcCache.GetServiceAffinity(*config.serviceID)
+
+	// ClusterIP is always redirected; additionalFrontendTypes optionally
+	// extends this to NodePort and/or LoadBalancer frontends of the service.
+	svcTypes := append([]lb.SVCType{lb.SVCTypeClusterIP}, config.additionalFrontendTypes...)
+
+	// portTemplates is captured before the switch below overwrites
+	// config.frontendMappings, so that resolveHeadlessServiceEndpoints still
+	// has the parse-time port name/protocol to match endpoint ports against.
+	portTemplates := config.frontendMappings
+
 	var svcFrontends []*frontend
 	switch config.frontendType {
 	case svcFrontendAll:
-		// Get all the service frontends.
-		addrsByPort := svcCache.GetServiceAddrsWithType(*config.serviceID,
-			lb.SVCTypeClusterIP)
-		config.frontendMappings = make([]*feMapping, 0, len(addrsByPort))
-		for p, addr := range addrsByPort {
-			feM := &feMapping{
-				feAddr: addr,
-				fePort: string(p),
+		// Get all the service frontends for each of the configured types.
+		config.frontendMappings = config.frontendMappings[:0]
+		for _, svcType := range svcTypes {
+			addrsByPort := svcCache.GetServiceAddrsWithType(*config.serviceID, svcType)
+			for p, addr := range addrsByPort {
+				feM := &feMapping{
+					feAddr: addr,
+					fePort: string(p),
+				}
+				config.frontendMappings = append(config.frontendMappings, feM)
+				svcFrontends = append(svcFrontends, addr)
 			}
-			config.frontendMappings = append(config.frontendMappings, feM)
-			svcFrontends = append(svcFrontends, addr)
 		}
 		for _, addr := range svcFrontends {
 			rpm.updateConfigSvcFrontend(config, addr)
 		}
 
 	case svcFrontendSinglePort:
-		// Get service frontend with the clusterIP and the policy config (unnamed) port.
-		ip := svcCache.GetServiceFrontendIP(*config.serviceID, lb.SVCTypeClusterIP)
-		config.frontendMappings[0].feAddr.IP = ip
-		rpm.updateConfigSvcFrontend(config, config.frontendMappings[0].feAddr)
+		// Get a service frontend, on the policy config's (unnamed) port, for
+		// each of the configured types and each IP GetServiceFrontendIPs
+		// returns for it. That is a single IP per type, so a dual-stack
+		// service gets a frontend in one family only.
+		fePort := config.frontendMappings[0].fePort
+		portTemplates := map[portName]*frontend{fePort: config.frontendMappings[0].feAddr.DeepCopy()}
+		newMappings := expandFrontendMappings(svcTypes, portTemplates, func(svcType lb.SVCType) []net.IP {
+			return svcCache.GetServiceFrontendIPs(*config.serviceID, svcType)
+		})
+		config.frontendMappings = newMappings
+		for _, feM := range newMappings {
+			svcFrontends = append(svcFrontends, feM.feAddr)
+		}
+		for _, addr := range svcFrontends {
+			rpm.updateConfigSvcFrontend(config, addr)
+		}
 
 	case svcFrontendNamedPorts:
-		// Get service frontends with the clusterIP and the policy config named ports.
-		ports := make([]string, len(config.frontendMappings))
-		for i, mapping := range config.frontendMappings {
-			ports[i] = mapping.fePort
+		// Get a service frontend, on each of the policy config's named
+		// ports, for each of the configured types and each IP
+		// GetServiceFrontendIPs returns for it. That is a single IP per
+		// type, so a dual-stack service gets frontends in one family only.
+		portTemplates := make(map[portName]*frontend, len(config.frontendMappings))
+		for _, feM := range config.frontendMappings {
+			if _, ok := portTemplates[feM.fePort]; !ok {
+				portTemplates[feM.fePort] = feM.feAddr.DeepCopy()
+			}
+		}
+		newMappings := expandFrontendMappings(svcTypes, portTemplates, func(svcType lb.SVCType) []net.IP {
+			return svcCache.GetServiceFrontendIPs(*config.serviceID, svcType)
+		})
+		config.frontendMappings = newMappings
+		for _, feM := range newMappings {
+			svcFrontends = append(svcFrontends, feM.feAddr)
+		}
+		for _, addr := range svcFrontends {
+			rpm.updateConfigSvcFrontend(config, addr)
+		}
+	}
+
+	if len(svcFrontends) == 0 && config.resolveHeadlessEndpoints {
+		newMappings := resolveHeadlessServiceEndpoints(svcCache, *config.serviceID, portTemplates)
+		if len(newMappings) > 0 {
+			config.frontendMappings = newMappings
+			for _, feM := range newMappings {
+				rpm.updateConfigSvcFrontend(config, feM.feAddr)
+				svcFrontends = append(svcFrontends, feM.feAddr)
+			}
+		}
+	}
+
+	if len(svcFrontends) == 0 {
+		config.unresolvedReason = unresolvedServiceReason(svcCache, *config.serviceID, svcTypes)
+	} else {
+		config.unresolvedReason = ""
+	}
+
+	pods := rpm.getLocalPodsForPolicy(config, podStore)
+	if len(pods) > 0 {
+		rpm.upsertConfig(config, pods...)
+	}
+
+	return pods
+}
+
+// unresolvedServiceReason returns a human-readable explanation for why
+// resolving svcID against svcTypes produced no frontend at all, for
+// LRPConfig.UnresolvedReason.
+func unresolvedServiceReason(svcCache *k8s.ServiceCache, svcID k8s.ServiceID, svcTypes []lb.SVCType) string {
+	svc, found := svcCache.GetServiceByID(svcID)
+	switch {
+	case !found:
+		return fmt.Sprintf("service %s not found", svcID)
+	case !svcTypeIn(svc.Type, svcTypes):
+		return fmt.Sprintf("service %s is of type %s, not one of %v required by this policy", svcID, svc.Type, svcTypes)
+	case svc.FrontendIP == nil:
+		return fmt.Sprintf("service %s has no ClusterIP (likely headless)", svcID)
+	default:
+		return fmt.Sprintf("service %s has no frontend for the configured port(s)", svcID)
+	}
+}
+
+// svcTypeIn reports whether t is one of types.
+func svcTypeIn(t lb.SVCType, types []lb.SVCType) bool {
+	for _, want := range types {
+		if t == want {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveHeadlessServiceEndpoints builds one feMapping per ready endpoint of
+// svcID, for a policy config that opted into redirectEndpoints, matching
+// each endpoint's ports against the named-port templates the config was
+// parsed with. It returns nil if svcID has no known endpoints, so that a
+// service which merely hasn't synced endpoints yet is reported as
+// unresolved rather than redirected with zero backends.
+func resolveHeadlessServiceEndpoints(svcCache *k8s.ServiceCache, svcID k8s.ServiceID, portTemplates []*feMapping) []*feMapping {
+	endpoints := svcCache.GetEndpointsOfService(svcID)
+	if endpoints == nil || len(endpoints.Backends) == 0 {
+		return nil
+	}
+
+	epIPs := make([]string, 0, len(endpoints.Backends))
+	for ip := range endpoints.Backends {
+		epIPs = append(epIPs, ip)
+	}
+	sort.Strings(epIPs)
+
+	var feMappings []*feMapping
+	for _, ip := range epIPs {
+		backend := endpoints.Backends[ip]
+		for _, tmpl := range portTemplates {
+			l4Addr, ok := backend.Ports[string(tmpl.fePort)]
+			if !ok {
+				continue
+			}
+			feM := &feMapping{
+				feAddr: lb.NewL3n4Addr(l4Addr.Protocol, net.ParseIP(ip), l4Addr.Port, lb.ScopeExternal),
+				fePort: tmpl.fePort,
+			}
+			feMappings = append(feMappings, feM)
+		}
+	}
+	return feMappings
+}
+
+// expandFrontendMappings builds a feMapping, from the given per-port
+// templates, for every IP that ipsForType returns for each of svcTypes. This
+// produces more than one mapping per port once ipsForType starts returning
+// more than one IP for a given type, e.g. for a dual-stack service with both
+// a v4 and a v6 clusterIP.
+func expandFrontendMappings(svcTypes []lb.SVCType, portTemplates map[portName]*frontend, ipsForType func(lb.SVCType) []net.IP) []*feMapping {
+	var newMappings []*feMapping
+	for _, svcType := range svcTypes {
+		for _, ip := range ipsForType(svcType) {
+			for fePort, template := range portTemplates {
+				feAddr := template.DeepCopy()
+				feAddr.IP = ip
+				newMappings = append(newMappings, &feMapping{feAddr: feAddr, fePort: fePort})
+			}
+		}
+	}
+	return newMappings
+}
+
+// interfaceFrontendControllerName returns the name of the controller that
+// re-resolves interface- or node-bound frontends for the given policy.
+func interfaceFrontendControllerName(id policyID) string {
+	return "lrp-resolve-interface-frontends-" + id.Namespace + "/" + id.Name
+}
+
+// ensureInterfaceFrontends resolves the current address of every
+// interface- or node-bound frontend of config, and if any are present,
+// starts a background job that keeps retrying the resolution so that an
+// interface or node address that isn't available yet, or that later
+// changes, is picked up without requiring the policy to be re-added.
+func (rpm *Manager) ensureInterfaceFrontends(config *LRPConfig, podStore cache.Store) {
+	var hasDeferredFrontend bool
+	for _, feM := range config.frontendMappings {
+		if feM.ifName != "" || feM.resolveFromNode {
+			hasDeferredFrontend = true
+			break
+		}
+	}
+	if !hasDeferredFrontend {
+		return
+	}
+
+	rpm.resolveInterfaceFrontends(config)
+
+	rpm.controllers.UpdateController(interfaceFrontendControllerName(config.id),
+		controller.ControllerParams{
+			RunInterval: interfaceResolveInterval,
+			DoFunc: func(ctx context.Context) error {
+				rpm.mutex.Lock()
+				defer rpm.mutex.Unlock()
+
+				current, ok := rpm.policyConfigs[config.id]
+				if !ok {
+					// Policy was deleted in the meantime.
+					return nil
+				}
+				if !rpm.resolveInterfaceFrontends(current) {
+					return nil
+				}
+				if pods := rpm.getLocalPodsForPolicy(current, podStore); len(pods) > 0 {
+					rpm.upsertConfig(current, pods...)
+				}
+				return nil
+			},
+		})
+}
+
+// resolveInterfaceFrontends attempts to resolve the primary address of every
+// interface- or node-bound frontend in config, updating the frontend hash
+// index as addresses are learned, change, or disappear. Must be called with
+// rpm.mutex held. Returns true if any frontend's resolved address changed.
+func (rpm *Manager) resolveInterfaceFrontends(config *LRPConfig) bool {
+	var changed bool
+	for _, feM := range config.frontendMappings {
+		if feM.ifName == "" && !feM.resolveFromNode {
+			continue
 		}
-		ip := svcCache.GetServiceFrontendIP(*config.serviceID, lb.SVCTypeClusterIP)
-		for _, feM := range config.frontendMappings {
-			feM.feAddr.IP = ip
-			svcFrontends = append(svcFrontends, feM.feAddr)
+		oldHash := frontendHash(feM.feAddr)
+		ip, err := rpm.resolveDeferredFrontendAddr(feM)
+		if err != nil {
+			if len(feM.feAddr.IP) > 0 {
+				// The interface or node address disappeared; drop the stale
+				// frontend so a service pointing at a now-invalid address
+				// isn't left behind.
+				delete(rpm.policyFrontendsByHash, oldHash)
+				feM.feAddr.IP = net.IP{}
+				changed = true
+			}
+			log.WithError(err).WithField(logfields.Interface, feM.ifName).
+				Debug("Deferring local redirect policy frontend resolution")
+			continue
 		}
-		for _, addr := range svcFrontends {
-			rpm.updateConfigSvcFrontend(config, addr)
+		if feM.feAddr.IP.Equal(ip) {
+			continue
 		}
+		delete(rpm.policyFrontendsByHash, oldHash)
+		feM.feAddr.IP = ip
+		rpm.policyFrontendsByHash[frontendHash(feM.feAddr)] = config.id
+		changed = true
 	}
+	return changed
+}
 
-	pods := rpm.getLocalPodsForPolicy(config, podStore)
-	if len(pods) > 0 {
-		rpm.upsertConfig(config, pods...)
+// resolveDeferredFrontendAddr resolves feM's frontend address when it isn't
+// specified literally: from the named interface's primary address, or from
+// the local node's own primary address for the nodeFrontendIP sentinel.
+func (rpm *Manager) resolveDeferredFrontendAddr(feM *feMapping) (net.IP, error) {
+	if feM.resolveFromNode {
+		return resolveNodeAddr()
 	}
-
+	return resolveInterfaceAddr(rpm.linkGetter, feM.ifName)
 }
 
 // storePolicyConfig stores various state for the given policy config.
 func (rpm *Manager) storePolicyConfig(config LRPConfig) {
+	rpm.cancelPendingPolicyDelete(config.id)
 	rpm.policyConfigs[config.id] = &config
+	rpm.policyStats[config.id] = &policyStats{createdAt: time.Now()}
 
 	switch config.lrpType {
 	case lrpConfigTypeAddr:
 		for _, feM := range config.frontendMappings {
-			rpm.policyFrontendsByHash[feM.feAddr.Hash()] = config.id
+			rpm.policyFrontendsByHash[frontendHash(feM.feAddr)] = config.id
 		}
 	case lrpConfigTypeSvc:
 		rpm.policyServices[*config.serviceID] = config.id
@@ -372,73 +1525,145 @@ func (rpm *Manager) deletePolicyConfig(config *LRPConfig) {
 	switch config.lrpType {
 	case lrpConfigTypeAddr:
 		for _, feM := range config.frontendMappings {
-			delete(rpm.policyFrontendsByHash, feM.feAddr.Hash())
+			delete(rpm.policyFrontendsByHash, frontendHash(feM.feAddr))
 		}
 	case lrpConfigTypeSvc:
 		delete(rpm.policyServices, *config.serviceID)
 	}
 	delete(rpm.policyConfigs, config.id)
+	delete(rpm.policyStats, config.id)
+	delete(rpm.reportedNoBackends, config.id)
+	rpm.deleteSnapshot(config.id)
 }
 
 func (rpm *Manager) updateConfigSvcFrontend(config *LRPConfig, frontends ...*frontend) {
 	for _, f := range frontends {
-		rpm.policyFrontendsByHash[f.Hash()] = config.id
+		rpm.policyFrontendsByHash[frontendHash(f)] = config.id
 	}
 	rpm.policyConfigs[config.id] = config
 }
 
-func (rpm *Manager) filterBackends(fe *feMapping, backends ...backend) []backend {
+// filterBackends returns fe.backends with the given backends removed, unless
+// a backend's IP:port has since been reused by a pod other than ownerUID, in
+// which case it's retained since it no longer belongs to the pod being
+// removed.
+func (rpm *Manager) filterBackends(fe *feMapping, ownerUID k8sTypes.UID, backends ...backend) []backend {
+	remove := make(map[string]struct{}, len(backends))
+	for _, removeBk := range backends {
+		remove[removeBk.StringWithProtocol()] = struct{}{}
+	}
+
 	var newBackends []backend
 	for _, currBk := range fe.backends {
-		for _, removeBk := range backends {
-			if removeBk.StringWithProtocol() != currBk.StringWithProtocol() {
-				newBackends = append(newBackends, currBk)
-			}
+		hash := currBk.StringWithProtocol()
+		if _, ok := remove[hash]; !ok {
+			newBackends = append(newBackends, currBk)
+			continue
+		}
+		if owner, ok := rpm.backendOwners[hash]; ok && owner != ownerUID {
+			// The IP:port was already reused by a different pod; keep it.
+			newBackends = append(newBackends, currBk)
+			continue
 		}
+		delete(rpm.backendOwners, hash)
+		delete(rpm.backendWeights, hash)
+		delete(rpm.backendZones, hash)
+		delete(rpm.backendPortNames, hash)
 	}
 	return newBackends
 }
 
-func (rpm *Manager) deletePolicyBackends(config *LRPConfig, backends ...backend) {
+func (rpm *Manager) deletePolicyBackends(config *LRPConfig, ownerUID k8sTypes.UID, backends ...backend) {
 	// Currently, we expect number of LRP backends to be a single digit number.
 	// If this scales up, we might need to optimize this using sets.
 	for _, fe := range config.frontendMappings {
-		fe.backends = rpm.filterBackends(fe, backends...)
+		fe.backends = rpm.filterBackends(fe, ownerUID, backends...)
 		rpm.notifyPolicyBackendDelete(config, fe)
 	}
 }
 
-// Deletes service entry for the specified frontend.
-func (rpm *Manager) deletePolicyFrontend(config *LRPConfig, frontend *frontend) {
+// Deletes service entry for the specified frontend, returning any error the
+// service manager reported.
+func (rpm *Manager) deletePolicyFrontend(config *LRPConfig, frontend *frontend) error {
+	rpm.cancelPendingFrontendDelete(frontendHash(frontend))
+
 	found, err := rpm.svcManager.DeleteService(*frontend)
-	delete(rpm.policyFrontendsByHash, frontend.Hash())
+	delete(rpm.policyFrontendsByHash, frontendHash(frontend))
 	if !found || err != nil {
 		log.WithError(err).Debugf("Local redirect service for policy %v not deleted",
 			config.id)
+		metrics.LRPUpsertErrorsTotal.WithLabelValues(config.id.Namespace, config.id.Name, "delete").Inc()
 	}
+	metrics.LRPBackendCount.WithLabelValues(config.id.Namespace, config.id.Name).Set(0)
+	return err
 }
 
 // Updates service manager with the new set of backends now configured in 'config'.
 func (rpm *Manager) notifyPolicyBackendDelete(config *LRPConfig, frontendMapping *feMapping) {
+	hash := frontendHash(frontendMapping.feAddr)
+
 	if len(frontendMapping.backends) > 0 {
+		rpm.cancelPendingFrontendDelete(hash)
 		rpm.upsertService(config, frontendMapping)
-	} else {
-		// No backends so remove the service entry.
-		found, err := rpm.svcManager.DeleteService(*frontendMapping.feAddr)
-		if !found || err != nil {
-			log.WithError(err).Errorf("Local redirect service for policy (%v)"+
-				" with frontend (%v) not deleted", config.id, frontendMapping.feAddr)
-		}
+		return
+	}
+
+	if config.restartGracePeriod > 0 {
+		// Hold the service in place for a short window in case it's about
+		// to regain a backend, e.g. a single-backend pod that's being
+		// recreated under a new UID with the same name rather than torn
+		// down for good. cancelPendingFrontendDelete above cancels this if
+		// a backend is added back before the timer fires.
+		rpm.cancelPendingFrontendDelete(hash)
+		rpm.pendingFrontendDeletes[hash] = time.AfterFunc(config.restartGracePeriod, func() {
+			rpm.mutex.Lock()
+			defer rpm.mutex.Unlock()
+			delete(rpm.pendingFrontendDeletes, hash)
+			if len(frontendMapping.backends) > 0 {
+				// A backend was added back in the meantime.
+				return
+			}
+			rpm.deleteFrontendService(config, frontendMapping)
+		})
+		return
+	}
+
+	rpm.deleteFrontendService(config, frontendMapping)
+}
+
+// deleteFrontendService removes the local redirect service entry for
+// frontendMapping because it currently has no backends. The caller must
+// hold rpm.mutex.
+func (rpm *Manager) deleteFrontendService(config *LRPConfig, frontendMapping *feMapping) {
+	found, err := rpm.svcManager.DeleteService(*frontendMapping.feAddr)
+	if !found || err != nil {
+		log.WithError(err).Errorf("Local redirect service for policy (%v)"+
+			" with frontend (%v) not deleted", config.id, frontendMapping.feAddr)
+		metrics.LRPUpsertErrorsTotal.WithLabelValues(config.id.Namespace, config.id.Name, "delete").Inc()
 	}
+	metrics.LRPBackendCount.WithLabelValues(config.id.Namespace, config.id.Name).Set(0)
 }
 
-// deletePolicyService deletes internal state associated with the specified service.
-func (rpm *Manager) deletePolicyService(svcID k8s.ServiceID) {
+// cancelPendingFrontendDelete stops and discards a restartGracePeriod
+// service deletion timer for the frontend identified by hash, if one is
+// outstanding. The caller must hold rpm.mutex.
+func (rpm *Manager) cancelPendingFrontendDelete(hash string) {
+	if timer, ok := rpm.pendingFrontendDeletes[hash]; ok {
+		timer.Stop()
+		delete(rpm.pendingFrontendDeletes, hash)
+	}
+}
+
+// deletePolicyService deletes internal state associated with the specified
+// service, returning any error the service manager reported while deleting
+// its frontends.
+func (rpm *Manager) deletePolicyService(svcID k8s.ServiceID) error {
+	var err error
 	if rp, ok := rpm.policyServices[svcID]; ok {
 		// Get the policy config that selects this service.
 		config := rpm.policyConfigs[rp]
 		for _, m := range config.frontendMappings {
-			rpm.deletePolicyFrontend(config, m.feAddr)
+			err = errors.Join(err, rpm.deletePolicyFrontend(config, m.feAddr))
 			switch config.frontendType {
 			case svcFrontendAll:
 				config.frontendMappings = nil
@@ -451,6 +1676,7 @@ func (rpm *Manager) deletePolicyService(svcID k8s.ServiceID) {
 			}
 		}
 	}
+	return err
 }
 
 // upsertService upserts a service entry for the given policy config that's ready.
@@ -463,44 +1689,399 @@ func (rpm *Manager) upsertService(config *LRPConfig, frontendMapping *feMapping)
 	for _, be := range frontendMapping.backends {
 		backendAddrs = append(backendAddrs, lb.Backend{
 			NodeName: nodeTypes.GetName(),
+			Weight:   rpm.backendWeights[be.StringWithProtocol()],
+			Zone:     rpm.backendZones[be.StringWithProtocol()],
+			PortName: rpm.backendPortNames[be.StringWithProtocol()],
 			L3n4Addr: be,
 		})
 	}
 	p := &lb.SVC{
-		Name:          config.id.Name + localRedirectSvcStr,
-		Namespace:     config.id.Namespace,
-		Type:          lb.SVCTypeLocalRedirect,
-		Frontend:      frontendAddr,
-		Backends:      backendAddrs,
-		TrafficPolicy: lb.SVCTrafficPolicyCluster,
+		Name:                      config.id.Name + localRedirectSvcStr,
+		Namespace:                 config.id.Namespace,
+		Type:                      lb.SVCTypeLocalRedirect,
+		Frontend:                  frontendAddr,
+		Backends:                  backendAddrs,
+		TrafficPolicy:             lb.SVCTrafficPolicyCluster,
+		SessionAffinity:           config.sessionAffinity,
+		SessionAffinityTimeoutSec: config.sessionAffinityTimeoutSec,
 	}
 
 	if _, _, err := rpm.svcManager.UpsertService(p); err != nil {
 		log.WithError(err).Error("Error while inserting service in LB map")
+		metrics.LRPUpsertErrorsTotal.WithLabelValues(config.id.Namespace, config.id.Name, "upsert").Inc()
+		return
 	}
+	metrics.LRPBackendCount.WithLabelValues(config.id.Namespace, config.id.Name).Set(float64(len(backendAddrs)))
 }
 
-// Returns a slice of endpoint pods metadata that are selected by the given policy config.
-func (rpm *Manager) getLocalPodsForPolicy(config *LRPConfig, podStore cache.Store) []*podMetadata {
-	var retPods []*podMetadata
+// podUnbackedReason describes a pod that matches a policy's backend
+// selector but did not end up contributing a backend, along with why.
+type podUnbackedReason struct {
+	PodID  podID
+	Reason string
+}
+
+// SelectedButUnbacked returns the pods that match the given policy's
+// backend selector but currently produce no backend for it, along with a
+// human-readable reason for each, to help debug "why isn't my pod a
+// backend" reports.
+func (rpm *Manager) SelectedButUnbacked(id policyID, podStore cache.Store) []podUnbackedReason {
+	rpm.mutex.RLock()
+	defer rpm.mutex.RUnlock()
+
+	config, ok := rpm.policyConfigs[id]
+	if !ok {
+		return nil
+	}
 
+	var result []podUnbackedReason
 	for _, podItem := range podStore.List() {
 		pod, ok := podItem.(*slimcorev1.Pod)
-		if !ok || !config.checkNamespace(pod.GetNamespace()) {
+		if !ok || !config.selectsBackendNamespace(pod.GetNamespace()) {
 			continue
 		}
+		pID := podID{Name: pod.GetName(), Namespace: pod.GetNamespace()}
+
 		podIPs, err := k8sUtils.ValidIPs(pod.Status)
 		if err != nil {
+			podInfo := &podMetadata{
+				labels:         pod.GetLabels(),
+				annotations:    pod.GetAnnotations(),
+				id:             pID,
+				serviceAccount: pod.Spec.ServiceAccountName,
+				hostNetwork:    pod.Spec.HostNetwork,
+				ready:          isPodReady(pod.Status),
+				terminating:    pod.ObjectMeta.DeletionTimestamp != nil,
+			}
+			if config.policyConfigSelectsPod(podInfo) {
+				result = append(result, podUnbackedReason{PodID: pID, Reason: "pod has no valid IP addresses"})
+			}
 			continue
 		}
+
 		podInfo := rpm.getPodMetadata(pod, podIPs)
+		if podInfo == nil {
+			result = append(result, podUnbackedReason{PodID: pID, Reason: "pod has an invalid named port configuration"})
+			continue
+		}
+		if !config.policyConfigSelectsPod(podInfo) {
+			continue
+		}
+		if podHasBackend(rpm.policyPods[pID], config.id) {
+			continue
+		}
+
+		result = append(result, podUnbackedReason{PodID: pID, Reason: unbackedReason(config, podInfo)})
+	}
+
+	return result
+}
+
+// ServiceBackendCoverage reports, for a service-type local redirect policy,
+// the node-local backends the policy is currently redirecting traffic to
+// alongside the full set of endpoints backing the underlying Kubernetes
+// service, so operators can judge what fraction of service traffic stays
+// node-local.
+type ServiceBackendCoverage struct {
+	LRPBackends      []backend
+	ServiceEndpoints *k8s.Endpoints
+}
+
+// GetServiceBackendCoverage returns the LRP backend set and the full service
+// endpoint set for the given service-type policy.
+func (rpm *Manager) GetServiceBackendCoverage(id policyID, svcCache *k8s.ServiceCache) (*ServiceBackendCoverage, error) {
+	rpm.mutex.RLock()
+	defer rpm.mutex.RUnlock()
+
+	config, ok := rpm.policyConfigs[id]
+	if !ok {
+		return nil, fmt.Errorf("local redirect policy %v not found", id)
+	}
+	if config.lrpType != lrpConfigTypeSvc {
+		return nil, fmt.Errorf("local redirect policy %v is not a service-type policy", id)
+	}
+
+	var lrpBackends []backend
+	for _, feM := range config.frontendMappings {
+		lrpBackends = append(lrpBackends, feM.backends...)
+	}
+
+	return &ServiceBackendCoverage{
+		LRPBackends:      lrpBackends,
+		ServiceEndpoints: svcCache.GetEndpointsOfService(*config.serviceID),
+	}, nil
+}
+
+// GetPolicies returns a deep copy of every local redirect policy config
+// currently installed, for inspection by debug tooling. Mutating the
+// returned configs does not affect the manager's internal state.
+func (rpm *Manager) GetPolicies() []*LRPConfig {
+	rpm.mutex.RLock()
+	defer rpm.mutex.RUnlock()
+
+	policies := make([]*LRPConfig, 0, len(rpm.policyConfigs))
+	for _, config := range rpm.policyConfigs {
+		policies = append(policies, config.deepCopy())
+	}
+	return policies
+}
+
+// GetPolicyBackends returns a deep copy of the backends currently
+// programmed for each frontend of the given policy, indexed by the
+// frontend's address. Mutating the returned map or its slices does not
+// affect the manager's internal state.
+func (rpm *Manager) GetPolicyBackends(id policyID) map[string][]backend {
+	rpm.mutex.RLock()
+	defer rpm.mutex.RUnlock()
+
+	config, ok := rpm.policyConfigs[id]
+	if !ok {
+		return nil
+	}
+
+	backendsByFrontend := make(map[string][]backend, len(config.frontendMappings))
+	for _, feM := range config.frontendMappings {
+		backendsByFrontend[feM.feAddr.String()] = append([]backend{}, feM.backends...)
+	}
+	return backendsByFrontend
+}
+
+// AllFrontends returns a deep copy of every frontend currently tracked in
+// policyFrontendsByHash, across all installed policies, for use in a
+// datapath-wide audit of LRP-programmed frontends.
+func (rpm *Manager) AllFrontends() []lb.L3n4Addr {
+	rpm.mutex.RLock()
+	defer rpm.mutex.RUnlock()
+
+	frontends := make([]lb.L3n4Addr, 0, len(rpm.policyFrontendsByHash))
+	for _, config := range rpm.policyConfigs {
+		for _, feM := range config.frontendMappings {
+			if _, ok := rpm.policyFrontendsByHash[frontendHash(feM.feAddr)]; ok {
+				frontends = append(frontends, *feM.feAddr.DeepCopy())
+			}
+		}
+	}
+	return frontends
+}
+
+// RebuildFrontendIndex clears and repopulates policyFrontendsByHash from the
+// frontends currently stored in policyConfigs. This is useful after an
+// in-memory upgrade that changes the hashing scheme (e.g. to also cover
+// protocol), and as a consistency repair if the index and the configs have
+// drifted apart for any other reason.
+func (rpm *Manager) RebuildFrontendIndex() {
+	rpm.mutex.Lock()
+	defer rpm.mutex.Unlock()
+
+	rpm.policyFrontendsByHash = make(map[string]policyID, len(rpm.policyFrontendsByHash))
+	for id, config := range rpm.policyConfigs {
+		for _, feM := range config.frontendMappings {
+			rpm.policyFrontendsByHash[frontendHash(feM.feAddr)] = id
+		}
+	}
+}
+
+// OrphanedServices returns the frontends of every SVCTypeLocalRedirect
+// service svc currently has programmed that no longer corresponds to a live
+// policy in policyFrontendsByHash, e.g. left behind by a crash between
+// upserting a service and recording its owning policy. Operators can use
+// this to detect and clean up leaked LB state.
+func (rpm *Manager) OrphanedServices(svc svcManager) []lb.L3n4Addr {
+	rpm.mutex.RLock()
+	defer rpm.mutex.RUnlock()
+
+	var orphaned []lb.L3n4Addr
+	for _, s := range svc.GetDeepCopyServices() {
+		if s.Type != lb.SVCTypeLocalRedirect {
+			continue
+		}
+		if _, ok := rpm.policyFrontendsByHash[frontendHash(&s.Frontend.L3n4Addr)]; !ok {
+			orphaned = append(orphaned, s.Frontend.L3n4Addr)
+		}
+	}
+	return orphaned
+}
+
+// podHasBackend reports whether infos contains a non-empty backend set for
+// the given policy.
+func podHasBackend(infos []podPolicyInfo, id policyID) bool {
+	for _, info := range infos {
+		if info.policyID == id && len(info.backends) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// wantedFamilies returns the address families that config's frontends
+// require a backend to have. Service-type frontends haven't resolved an
+// address yet when this is called before their first sync, so in that case
+// it falls back to the globally enabled address families.
+func wantedFamilies(config *LRPConfig) (wantV4, wantV6 bool) {
+	for _, feM := range config.frontendMappings {
+		if feM.feAddr.IP.To4() != nil {
+			wantV4 = true
+		} else if len(feM.feAddr.IP) > 0 {
+			wantV6 = true
+		}
+	}
+	if !wantV4 && !wantV6 {
+		wantV4, wantV6 = option.Config.EnableIPv4, option.Config.EnableIPv6
+	}
+	return wantV4, wantV6
+}
+
+// podFamilies returns the address families among pod's backend IPs for
+// config.
+func podFamilies(config *LRPConfig, pod *podMetadata) (hasV4, hasV6 bool) {
+	for _, ip := range pod.backendIPs(config) {
+		if parsed := net.ParseIP(ip); parsed != nil {
+			if parsed.To4() != nil {
+				hasV4 = true
+			} else {
+				hasV6 = true
+			}
+		}
+	}
+	return hasV4, hasV6
+}
+
+// backendFamilyMismatch reports whether pod matches config's selector but is
+// excluded as a backend purely because it has no address in the single
+// family config's frontends require, e.g. a v4-only frontend selecting only
+// v6-only pods.
+func backendFamilyMismatch(config *LRPConfig, pod *podMetadata) bool {
+	wantV4, wantV6 := wantedFamilies(config)
+	hasV4, hasV6 := podFamilies(config, pod)
+	return (wantV4 && !wantV6 && !hasV4) || (wantV6 && !wantV4 && !hasV6)
+}
+
+// unbackedReason returns a human-readable explanation for why a pod that
+// matches config's selector produced no backend.
+func unbackedReason(config *LRPConfig, pod *podMetadata) string {
+	wantV4, wantV6 := wantedFamilies(config)
+	hasV4, hasV6 := podFamilies(config, pod)
+
+	switch {
+	case wantV4 && !wantV6 && !hasV4:
+		return "pod has no IPv4 address, but the frontend/backend is IPv4-only"
+	case wantV6 && !wantV4 && !hasV6:
+		return "pod has no IPv6 address, but the frontend/backend is IPv6-only"
+	default:
+		return "pod matched the selector but did not produce a usable backend"
+	}
+}
+
+// Returns a slice of endpoint pods metadata that are selected by the given policy config.
+func (rpm *Manager) getLocalPodsForPolicy(config *LRPConfig, podStore cache.Store) []*podMetadata {
+	if podStore == nil {
+		log.WithFields(logrus.Fields{logfields.LRPName: config.id.Name}).
+			Debug("Skipping local redirect policy pod lookup: pod store is not available")
+		metrics.LRPDroppedEventsTotal.Inc()
+		return nil
+	}
+	return rpm.podsSelectedByConfig(config, rpm.buildLocalPodCache(podStore))
+}
+
+// getLocalPodsForPolicies returns, for each of configs, the slice of pods it
+// selects as backends. Unlike calling getLocalPodsForPolicy once per config,
+// podStore is only listed and parsed into podMetadata once in total, which
+// matters when many policies are evaluated together, e.g. during a resync.
+func (rpm *Manager) getLocalPodsForPolicies(configs []*LRPConfig, podStore cache.Store) map[policyID][]*podMetadata {
+	if podStore == nil {
+		log.Debug("Skipping local redirect policy pod lookup: pod store is not available")
+		metrics.LRPDroppedEventsTotal.Inc()
+		return nil
+	}
+
+	cache := rpm.buildLocalPodCache(podStore)
+	selected := make(map[policyID][]*podMetadata, len(configs))
+	for _, config := range configs {
+		selected[config.id] = rpm.podsSelectedByConfig(config, cache)
+	}
+	return selected
+}
+
+// buildLocalPodCache lists podStore and parses every pod into a podMetadata
+// exactly once, so the result can be filtered against any number of policy
+// configs without re-listing or re-parsing the store for each one.
+func (rpm *Manager) buildLocalPodCache(podStore cache.Store) []*podMetadata {
+	items := podStore.List()
+	cache := make([]*podMetadata, 0, len(items))
+	for _, podItem := range items {
+		pod, ok := podItem.(*slimcorev1.Pod)
+		if !ok {
+			continue
+		}
+		podIPs, err := k8sUtils.ValidIPs(pod.Status)
+		if err != nil {
+			continue
+		}
+		cache = append(cache, rpm.getPodMetadata(pod, podIPs))
+	}
+	return cache
+}
+
+// podsSelectedByConfig filters an already-built local pod cache down to the
+// pods config selects as backends, applying the same ready-over-terminating
+// preference, deterministic ordering, and maxBackends cap as
+// getLocalPodsForPolicy.
+func (rpm *Manager) podsSelectedByConfig(config *LRPConfig, cache []*podMetadata) []*podMetadata {
+	var readyPods, terminatingPods []*podMetadata
+
+	for _, podInfo := range cache {
 		if !config.policyConfigSelectsPod(podInfo) {
 			continue
 		}
-		retPods = append(retPods, podInfo)
+		if podInfo.terminating {
+			terminatingPods = append(terminatingPods, podInfo)
+			continue
+		}
+		readyPods = append(readyPods, podInfo)
+	}
+
+	sortPodsByID(readyPods)
+	sortPodsByID(terminatingPods)
+
+	// Terminating pods are only used as backends as a last resort, when no
+	// ready backend is otherwise available for the policy.
+	if len(readyPods) == 0 {
+		return rpm.capPods(config, terminatingPods)
+	}
+	return rpm.capPods(config, readyPods)
+}
+
+// sortPodsByID orders pods by namespace and then name, so that backend
+// selection is deterministic and stable across repeated calls and manager
+// restarts, regardless of the pod store's iteration order. The slim pod type
+// used here doesn't carry a creation timestamp, so name is the ordering key.
+func sortPodsByID(pods []*podMetadata) {
+	sort.Slice(pods, func(i, j int) bool {
+		if pods[i].id.Namespace != pods[j].id.Namespace {
+			return pods[i].id.Namespace < pods[j].id.Namespace
+		}
+		return pods[i].id.Name < pods[j].id.Name
+	})
+}
+
+// capPods enforces config's maxBackends cap on an already deterministically
+// ordered pod slice, logging which pods were excluded for exceeding the cap.
+func (rpm *Manager) capPods(config *LRPConfig, pods []*podMetadata) []*podMetadata {
+	if config.maxBackends <= 0 || len(pods) <= config.maxBackends {
+		return pods
+	}
+
+	excluded := make([]string, 0, len(pods)-config.maxBackends)
+	for _, pod := range pods[config.maxBackends:] {
+		excluded = append(excluded, pod.id.String())
 	}
+	log.WithFields(logrus.Fields{
+		logfields.LRPName: config.id.Name,
+		"maxBackends":     config.maxBackends,
+		"excludedPods":    excluded,
+	}).Info("Local redirect policy backend count exceeds maxBackends; excluding some pods")
 
-	return retPods
+	return pods[:config.maxBackends]
 }
 
 // isValidConfig validates the given policy config for duplicates.
@@ -508,9 +2089,16 @@ func (rpm *Manager) getLocalPodsForPolicy(config *LRPConfig, podStore cache.Stor
 func (rpm *Manager) isValidConfig(config LRPConfig) error {
 	switch config.lrpType {
 	case lrpConfigTypeAddr:
+		seen := make(map[string]*lb.L3n4Addr, len(config.frontendMappings))
 		for _, feM := range config.frontendMappings {
 			fe := feM.feAddr
-			id, ok := rpm.policyFrontendsByHash[fe.Hash()]
+			if dup, ok := seen[frontendHash(fe)]; ok {
+				return fmt.Errorf("CiliumLocalRedirectPolicy %v declares the "+
+					"same frontend %v more than once", config.id.Name, dup)
+			}
+			seen[frontendHash(fe)] = fe
+
+			id, ok := rpm.policyFrontendsByHash[frontendHash(fe)]
 			if ok && config.id.Name != id.Name {
 				return fmt.Errorf("CiliumLocalRedirectPolicy for"+
 					"frontend %v already exists : %v", fe, config.id.Name)
@@ -518,13 +2106,34 @@ func (rpm *Manager) isValidConfig(config LRPConfig) error {
 		}
 
 	case lrpConfigTypeSvc:
+		if !config.checkNamespace(config.serviceID.Namespace) {
+			return fmt.Errorf("CiliumLocalRedirectPolicy %v service matcher"+
+				" namespace %v does not match policy namespace %v", config.id.Name,
+				config.serviceID.Namespace, config.id.Namespace)
+		}
+
 		p, ok := rpm.policyServices[*config.serviceID]
-		// Only 1 serviceMatcher policy is allowed for a service name within a namespace.
-		if ok && config.id.Namespace != "" &&
-			config.id.Namespace == rpm.policyConfigs[p].id.Namespace {
-			return fmt.Errorf("CiliumLocalRedirectPolicy for"+
-				" service %v already exists in namespace %v", config.serviceID,
-				config.id.Namespace)
+		if ok {
+			existing, existingOK := rpm.policyConfigs[p]
+			if !existingOK {
+				// policyServices references a policy no longer in
+				// policyConfigs. isValidConfig is also called from the
+				// read-only ValidatePolicy path under just an RLock, so it
+				// can't drop the stale entry itself the way
+				// reconcileServiceConfigLocked does; just log and treat it
+				// as no conflict.
+				log.WithFields(logrus.Fields{
+					logfields.K8sSvcID: *config.serviceID,
+					logfields.LRPName:  p,
+				}).Warning("policyServices references a local redirect policy no longer in policyConfigs; ignoring the stale entry")
+			} else if p != config.id && config.id.Namespace != "" && config.id.Namespace == existing.id.Namespace {
+				// Only 1 serviceMatcher policy is allowed for a service name within a namespace.
+				// A policy being updated to still point at the same service it already
+				// owns is not a conflict.
+				return fmt.Errorf("CiliumLocalRedirectPolicy for"+
+					" service %v already exists in namespace %v", config.serviceID,
+					config.id.Namespace)
+			}
 		}
 	}
 
@@ -532,6 +2141,8 @@ func (rpm *Manager) isValidConfig(config LRPConfig) error {
 }
 
 func (rpm *Manager) upsertConfig(config *LRPConfig, pods ...*podMetadata) {
+	rpm.recordPolicyEvent(config.id)
+
 	switch config.frontendType {
 	case svcFrontendSinglePort:
 		fallthrough
@@ -560,114 +2171,321 @@ func (rpm *Manager) upsertConfig(config *LRPConfig, pods ...*podMetadata) {
 // If a pod has multiple IPs, then there will be multiple backend entries created
 // for the pod with common <port, protocol>.
 func (rpm *Manager) upsertConfigWithSinglePort(config *LRPConfig, pods ...*podMetadata) {
-	var bes4 []backend
-	var bes6 []backend
-
-	// Generate and map pod backends to the policy frontend. The policy config
-	// is already sanitized, and has matching backend and frontend port protocol.
-	// We currently don't check which backends are updated before upserting a
-	// a service with the corresponding frontend. This can be optimized when LRPs
-	// are scaled up.
-	bePort := config.backendPorts[0]
-	feM := config.frontendMappings[0]
+	// Generate and map pod backends to the policy's frontend(s). The policy
+	// config is already sanitized, and has matching backend and frontend port
+	// protocol. Each pod's ownership is recorded individually, but each
+	// frontend mapping's backend set is only diffed and pushed to the
+	// service manager once, after every pod in the batch has been accounted
+	// for.
+	//
+	// A config may carry more than one frontendMapping here (e.g. an
+	// IPv4 and an IPv6 mapping for a dual-stack frontend, or the TCP and
+	// UDP mappings a wildcard ANY frontend port expands into), so every
+	// mapping is wired independently rather than assuming
+	// frontendMappings[0] is the only one that matters; a pod backs a given
+	// mapping only through the backend IPs that actually match that
+	// mapping's address family, paired with that mapping's own backend
+	// port (feM.bePortIndex).
+	for _, feM := range config.frontendMappings {
+		bePort := config.backendPorts[feM.bePortIndex]
+		rpm.upsertSinglePortFrontend(config, feM, bePort, pods)
+	}
+}
+
+// upsertSinglePortFrontend wires pods' backends matching bePort to feM,
+// selecting only the backend IPs whose address family matches feM's own.
+func (rpm *Manager) upsertSinglePortFrontend(config *LRPConfig, feM *feMapping, bePort bePortInfo, pods []*podMetadata) {
+	wantV4 := feM.feAddr.IP.To4() != nil
+	if wantV4 && !option.Config.EnableIPv4 {
+		return
+	}
+	if !wantV4 && !option.Config.EnableIPv6 {
+		return
+	}
+
+	var bes []backend
 	for _, pod := range pods {
-		for _, ip := range pod.ips {
+		var podBes []backend
+		for _, ip := range pod.backendIPs(config) {
 			beIP := net.ParseIP(ip)
-			if beIP == nil {
+			if beIP == nil || (beIP.To4() != nil) != wantV4 {
 				continue
 			}
-			be := backend{
-				IP: net.ParseIP(ip),
+			podBes = append(podBes, backend{
+				IP: beIP,
 				L4Addr: lb.L4Addr{
 					Protocol: bePort.l4Addr.Protocol,
 					Port:     bePort.l4Addr.Port,
 				},
-			}
-			if feM.feAddr.IP.To4() != nil {
-				if option.Config.EnableIPv4 {
-					bes4 = append(bes4, be)
-				}
-			} else {
-				if option.Config.EnableIPv6 {
-					bes6 = append(bes6, be)
-				}
-			}
+			})
 		}
-		if len(bes4) > 0 {
-			rpm.upsertServiceWithBackends(config, feM, pod.id, bes4)
-		} else if len(bes6) > 0 {
-			rpm.upsertServiceWithBackends(config, feM, pod.id, bes6)
+		if len(podBes) > 0 {
+			healthy, unhealthy := rpm.recordPodOwnership(config, pod.id, pod.uid, !pod.terminating, pod.weight, pod.zone, bePort.name, podBes)
+			rpm.trackPendingHealthBackends(config, feM, pod.id, pod.uid, !pod.terminating, pod.weight, pod.zone, bePort.name, unhealthy)
+			bes = append(bes, healthy...)
 		}
 	}
-	return
+
+	if len(bes) > 0 {
+		rpm.syncFrontendBackends(config, feM, bes)
+	}
 }
 
 // upsertConfigWithNamedPorts upserts policy config frontends to the corresponding
 // backends matched by port names.
 func (rpm *Manager) upsertConfigWithNamedPorts(config *LRPConfig, pods ...*podMetadata) {
 	// Generate backends for the policy config's backend named ports, and then
-	// map the backends to policy frontends based on the named ports.
-	// We currently don't check which backends are updated before upserting a
-	// a service with the corresponding frontend. This can be optimized if LRPs
-	// are scaled up.
+	// map the backends to policy frontends based on the named ports. As with
+	// upsertConfigWithSinglePort, each pod's ownership is recorded
+	// individually, but each frontend's backend set is only diffed and
+	// pushed to the service manager once per call, regardless of how many
+	// pods back it.
 	for _, feM := range config.frontendMappings {
 		namedPort := feM.fePort
-		var (
-			bes4   []backend
-			bes6   []backend
-			bePort *bePortInfo
-			ok     bool
-		)
-		if bePort, ok = config.backendPortsByPortName[namedPort]; !ok {
+		bePort, ok := config.backendPortsByPortName[namedPort]
+		if !ok {
 			// The frontend named port not found in the backend ports map.
 			continue
 		}
+
+		wantV4 := feM.feAddr.IP.To4() != nil
+		if wantV4 && !option.Config.EnableIPv4 {
+			continue
+		}
+		if !wantV4 && !option.Config.EnableIPv6 {
+			continue
+		}
+
+		var bes []backend
+		seen := make(map[string]struct{})
 		for _, pod := range pods {
-			if _, ok = pod.namedPorts[namedPort]; ok {
-				// Generate pod backends.
-				for _, ip := range pod.ips {
-					beIP := net.ParseIP(ip)
-					if beIP == nil || bePort.l4Addr.Protocol != feM.feAddr.Protocol {
-						continue
-					}
-					be := backend{
-						IP: net.ParseIP(ip),
-						L4Addr: lb.L4Addr{
-							Protocol: bePort.l4Addr.Protocol,
-							Port:     bePort.l4Addr.Port,
-						},
-					}
-					if feM.feAddr.IP.To4() != nil {
-						if option.Config.EnableIPv4 {
-							bes4 = append(bes4, be)
-						}
-					} else {
-						if option.Config.EnableIPv6 {
-							bes6 = append(bes6, be)
-						}
-					}
+			if _, ok := pod.namedPorts[namedPort]; !ok {
+				continue
+			}
+			var podBes []backend
+			for _, ip := range pod.backendIPs(config) {
+				beIP := net.ParseIP(ip)
+				if beIP == nil || (beIP.To4() != nil) != wantV4 {
+					continue
 				}
+				if feM.feAddr.Protocol != lb.NONE && bePort.l4Addr.Protocol != feM.feAddr.Protocol {
+					continue
+				}
+				be := backend{
+					IP: beIP,
+					L4Addr: lb.L4Addr{
+						Protocol: bePort.l4Addr.Protocol,
+						Port:     bePort.l4Addr.Port,
+					},
+				}
+				if _, ok := seen[be.StringWithProtocol()]; ok {
+					// Another pod already contributed this IP:port (e.g.
+					// hostNetwork sidecars sharing the node IP); keep only
+					// the first one so the frontend doesn't end up with a
+					// duplicate backend and policyPods doesn't record two
+					// pods as owning the same backend.
+					continue
+				}
+				seen[be.StringWithProtocol()] = struct{}{}
+				podBes = append(podBes, be)
 			}
-			if len(bes4) > 0 {
-				rpm.upsertServiceWithBackends(config, feM, pod.id, bes4)
-			} else if len(bes6) > 0 {
-				rpm.upsertServiceWithBackends(config, feM, pod.id, bes6)
+			if len(podBes) > 0 {
+				healthy, unhealthy := rpm.recordPodOwnership(config, pod.id, pod.uid, !pod.terminating, pod.weight, pod.zone, namedPort, podBes)
+				rpm.trackPendingHealthBackends(config, feM, pod.id, pod.uid, !pod.terminating, pod.weight, pod.zone, namedPort, unhealthy)
+				bes = append(bes, healthy...)
 			}
 		}
+
+		if len(bes) > 0 {
+			rpm.syncFrontendBackends(config, feM, bes)
+		}
 	}
 }
 
 // upsertServiceWithBackends updates policy config internal state and upserts
 // service with the given pod backends.
-func (rpm *Manager) upsertServiceWithBackends(config *LRPConfig, frontendMapping *feMapping, podID podID, backends []backend) {
+func (rpm *Manager) upsertServiceWithBackends(config *LRPConfig, frontendMapping *feMapping, podID podID, podUID k8sTypes.UID, ready bool, weight uint16, zone string, portName string, backends []backend) {
+	sortBackends(backends)
+	healthy, unhealthy := rpm.recordPodOwnership(config, podID, podUID, ready, weight, zone, portName, backends)
+	rpm.trackPendingHealthBackends(config, frontendMapping, podID, podUID, ready, weight, zone, portName, unhealthy)
+
+	log.WithFields(logrus.Fields{
+		logfields.LRPName:     config.id.Name,
+		logfields.LRPFrontend: frontendMapping.feAddr.String(),
+		logfields.LRPBackends: backendStrings(healthy),
+	}).Debug("Upserting local redirect policy backends")
+
+	rpm.syncFrontendBackends(config, frontendMapping, healthy)
+}
+
+// backendStrings returns the IP:port:protocol representation of each
+// backend in bes, for logging.
+func backendStrings(bes []backend) []string {
+	out := make([]string, 0, len(bes))
+	for _, be := range bes {
+		out = append(out, be.StringWithProtocol())
+	}
+	return out
+}
+
+// recordPodOwnership records that podID's pod (identified by podUID) backs
+// config with the given pod-specific backends, claiming ownership of each
+// healthy one (per rpm.healthChecker, or all of them if none is configured)
+// in backendOwners and recording its requested weight in backendWeights,
+// topology zone in backendZones, and matched backend port name (for
+// named-port policies) in backendPortNames. An unhealthy backend is treated
+// as though the pod hadn't reported it at all: it isn't recorded as owned,
+// and is returned separately as unhealthy so the caller can track it via
+// trackPendingHealthBackends instead of installing it. Unlike
+// upsertServiceWithBackends, it does not touch frontendMapping or the
+// service manager, so callers backing a single frontend with several pods
+// can record each pod's ownership individually and defer the (possibly
+// batched) service update to a single call to syncFrontendBackends.
+func (rpm *Manager) recordPodOwnership(config *LRPConfig, podID podID, podUID k8sTypes.UID, ready bool, weight uint16, zone string, portName string, backends []backend) (healthy, unhealthy []backend) {
+	healthy, unhealthy = rpm.filterHealthyBackends(backends)
+	for _, be := range healthy {
+		hash := be.StringWithProtocol()
+		rpm.backendOwners[hash] = podUID
+		rpm.backendWeights[hash] = weight
+		rpm.backendZones[hash] = zone
+		rpm.backendPortNames[hash] = portName
+		delete(rpm.pendingHealthBackends, hash)
+	}
+	if len(healthy) > 0 {
+		rpm.policyPods[podID] = append(rpm.policyPods[podID], podPolicyInfo{
+			policyID: config.id,
+			backends: healthy,
+			podUID:   podUID,
+			ready:    ready,
+		})
+	}
+	return healthy, unhealthy
+}
+
+// filterHealthyBackends partitions backends into those that pass
+// rpm.healthChecker and those that don't. Every backend is considered
+// healthy if no healthChecker is configured, which is the default.
+func (rpm *Manager) filterHealthyBackends(backends []backend) (healthy, unhealthy []backend) {
+	if rpm.healthChecker == nil {
+		return backends, nil
+	}
+	for _, be := range backends {
+		if rpm.healthChecker.IsHealthy(be.IP, be.L4Addr) {
+			healthy = append(healthy, be)
+		} else {
+			unhealthy = append(unhealthy, be)
+		}
+	}
+	return healthy, unhealthy
+}
+
+// trackPendingHealthBackends records enough information about each of a
+// pod's backends that failed a health check to install it later, once
+// BackendHealthChanged reports it healthy again, without having to
+// re-derive it from the pod store.
+func (rpm *Manager) trackPendingHealthBackends(config *LRPConfig, frontendMapping *feMapping, podID podID, podUID k8sTypes.UID, ready bool, weight uint16, zone string, portName string, unhealthy []backend) {
+	for _, be := range unhealthy {
+		rpm.pendingHealthBackends[be.StringWithProtocol()] = pendingHealthBackend{
+			config:          config,
+			frontendMapping: frontendMapping,
+			podID:           podID,
+			podUID:          podUID,
+			ready:           ready,
+			weight:          weight,
+			zone:            zone,
+			portName:        portName,
+			backend:         be,
+		}
+	}
+}
+
+// BackendHealthChanged notifies the manager that ip:port's health has
+// changed, as reported by the configured BackendHealthChecker. If that
+// backend was previously excluded from its owning pod's installed backend
+// set because it failed a health check, and rpm.healthChecker now reports it
+// healthy, it's installed and its frontend is re-upserted. It has no effect
+// if the backend isn't currently pending (e.g. it was never excluded, its
+// pod has since been removed, or it's still unhealthy).
+func (rpm *Manager) BackendHealthChanged(ip net.IP, port lb.L4Addr) {
+	rpm.mutex.Lock()
+	defer rpm.mutex.Unlock()
+
+	be := backend{IP: ip, L4Addr: port}
+	hash := be.StringWithProtocol()
+	pending, ok := rpm.pendingHealthBackends[hash]
+	if !ok {
+		return
+	}
+	if rpm.healthChecker == nil || !rpm.healthChecker.IsHealthy(ip, port) {
+		return
+	}
+
+	healthy, _ := rpm.recordPodOwnership(pending.config, pending.podID, pending.podUID, pending.ready, pending.weight, pending.zone, pending.portName, []backend{pending.backend})
+	if len(healthy) == 0 {
+		return
+	}
+	bes := append(append([]backend{}, pending.frontendMapping.backends...), healthy...)
+	rpm.syncFrontendBackends(pending.config, pending.frontendMapping, bes)
+}
+
+// syncFrontendBackends diffs backends against frontendMapping's currently
+// programmed set and, if anything actually changed, replaces the set and
+// upserts the service once with the full new backend list. This avoids
+// pushing one redundant intermediate update per pod when a frontend is
+// backed by several pods at once.
+func (rpm *Manager) syncFrontendBackends(config *LRPConfig, frontendMapping *feMapping, backends []backend) {
+	sortBackends(backends)
+	added, removed := diffBackends(frontendMapping.backends, backends)
+
 	frontendMapping.backends = backends
-	rpm.policyPods[podID] = append(rpm.policyPods[podID], podPolicyInfo{
-		policyID: config.id,
-		backends: backends,
-	})
+	rpm.reconcileSkipRedirectBackends()
+	rpm.recordFirstBackend(config)
+
+	if len(added) == 0 && len(removed) == 0 {
+		// The backend set is logically the same as before, just reordered;
+		// skip the redundant datapath write.
+		return
+	}
+	rpm.recordBackendChurn(config.id)
 	rpm.upsertService(config, frontendMapping)
 }
 
+// sortBackends orders backends deterministically so that a logically
+// unchanged backend set is always presented to the LB map in the same
+// order, and so that diffBackends can rely on a stable ordering.
+func sortBackends(backends []backend) {
+	sort.Slice(backends, func(i, j int) bool {
+		return backends[i].StringWithProtocol() < backends[j].StringWithProtocol()
+	})
+}
+
+// diffBackends reports which of cur's backends are not present in old
+// (added) and which of old's backends are no longer present in cur
+// (removed), ignoring order. Both added and removed are nil if the two sets
+// are identical.
+func diffBackends(old, cur []backend) (added, removed []backend) {
+	oldSet := make(map[string]struct{}, len(old))
+	for _, be := range old {
+		oldSet[be.StringWithProtocol()] = struct{}{}
+	}
+	curSet := make(map[string]struct{}, len(cur))
+	for _, be := range cur {
+		curSet[be.StringWithProtocol()] = struct{}{}
+	}
+
+	for _, be := range cur {
+		if _, ok := oldSet[be.StringWithProtocol()]; !ok {
+			added = append(added, be)
+		}
+	}
+	for _, be := range old {
+		if _, ok := curSet[be.StringWithProtocol()]; !ok {
+			removed = append(removed, be)
+		}
+	}
+	return added, removed
+}
+
 // TODO This function along with podMetadata can potentially be removed. We
 // can directly reference the relevant pod metedata on-site.
 func (rpm *Manager) getPodMetadata(pod *slimcorev1.Pod, podIPs []string) *podMetadata {
@@ -688,12 +2506,50 @@ func (rpm *Manager) getPodMetadata(pod *slimcorev1.Pod, podIPs []string) *podMet
 		}
 	}
 	return &podMetadata{
-		ips:        podIPs,
-		labels:     pod.GetLabels(),
-		namedPorts: namedPorts,
+		ips:            podIPs,
+		annotationIPs:  annotatedPodIPs(pod),
+		labels:         pod.GetLabels(),
+		annotations:    pod.GetAnnotations(),
+		namedPorts:     namedPorts,
+		uid:            pod.GetUID(),
+		serviceAccount: pod.Spec.ServiceAccountName,
+		hostNetwork:    pod.Spec.HostNetwork,
+		ready:          isPodReady(pod.Status),
+		terminating:    pod.ObjectMeta.DeletionTimestamp != nil,
+		weight:         podBackendWeight(pod),
+		zone:           podZone(pod),
 		id: k8s.ServiceID{
 			Name:      pod.GetName(),
 			Namespace: pod.GetNamespace(),
 		},
 	}
 }
+
+// backendIPs returns the IPs config should consider when building backends
+// for pod: its Multus network-status annotation IPs when config has opted
+// into backendsFromNetworkStatus, or its regular PodIPs otherwise.
+func (pod *podMetadata) backendIPs(config *LRPConfig) []string {
+	if config.backendsFromNetworkStatus {
+		return pod.annotationIPs
+	}
+	return pod.ips
+}
+
+// fieldSet returns pod's spec fields as a fields.Set, for evaluation against
+// a policy's backendFieldSelector. Only fields listed in
+// supportedBackendFieldSelectors are populated.
+func (pod *podMetadata) fieldSet() fields.Set {
+	return fields.Set{
+		"spec.hostNetwork": strconv.FormatBool(pod.hostNetwork),
+	}
+}
+
+// isPodReady reports whether the pod's Ready condition is currently True.
+func isPodReady(status slimcorev1.PodStatus) bool {
+	for _, cond := range status.Conditions {
+		if cond.Type == slimcorev1.PodReady {
+			return cond.Status == slimcorev1.ConditionTrue
+		}
+	}
+	return false
+}
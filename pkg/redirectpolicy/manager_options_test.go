@@ -0,0 +1,100 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestNewRedirectPolicyManagerWithOptions verifies that WithLinkGetter and
+// WithObserver, passed together to NewRedirectPolicyManager, both take
+// effect on the constructed Manager.
+func TestNewRedirectPolicyManagerWithOptions(t *testing.T) {
+	lg := newFakeLinkGetter()
+	lg.addLink("eth0", []net.IP{net.ParseIP("192.168.1.5")}, nil)
+	observer := &fakeBackendIPObserver{}
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil,
+		WithLinkGetter(lg),
+		WithObserver(observer),
+	)
+
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.IP{}, 80, lb.ScopeExternal),
+		ifName: "eth0",
+	}
+	config := &LRPConfig{
+		id:               policyID{Name: "test", Namespace: "default"},
+		lrpType:          lrpConfigTypeAddr,
+		frontendType:     addrFrontendSinglePort,
+		frontendMappings: []*feMapping{feM},
+	}
+	rpm.storePolicyConfig(*config)
+
+	if changed := rpm.resolveInterfaceFrontends(config); !changed {
+		t.Fatal("expected the frontend to resolve against the injected linkGetter")
+	}
+	if !feM.feAddr.IP.Equal(net.ParseIP("192.168.1.5")) {
+		t.Fatalf("expected the frontend IP to be resolved from eth0, got %v", feM.feAddr.IP)
+	}
+
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "backend-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.0.0.5",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.0.0.5"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	podStore.Add(pod)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	skipConfig := LRPConfig{
+		id:           policyID{Name: "skip", Namespace: "default"},
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		}},
+		backendSelector:         selector,
+		skipRedirectFromBackend: true,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	if ok, err := rpm.AddRedirectPolicy(skipConfig, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	if len(observer.added) != 1 || !observer.added[0].Equal(net.ParseIP("10.0.0.5")) {
+		t.Fatalf("expected the injected observer to be notified of the backend pod IP, got %v", observer.added)
+	}
+}
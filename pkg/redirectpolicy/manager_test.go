@@ -0,0 +1,86 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+)
+
+// fakeSvcManager is a fake svcManager that records every upsert/delete for
+// assertions in tests.
+type fakeSvcManager struct {
+	upsertCount  int
+	deleteCount  int
+	lastUpserted *lb.SVC
+	// services seeds GetDeepCopyServices, e.g. with orphaned entries a test
+	// wants to simulate. UpsertService also appends to it, so tests exercising
+	// the full AddRedirectPolicy flow see their own programmed services here.
+	services []*lb.SVC
+}
+
+func (f *fakeSvcManager) DeleteService(frontend lb.L3n4Addr) (bool, error) {
+	f.deleteCount++
+	return true, nil
+}
+
+func (f *fakeSvcManager) UpsertService(svc *lb.SVC) (bool, lb.ID, error) {
+	f.upsertCount++
+	f.lastUpserted = svc
+	f.services = append(f.services, svc)
+	return true, lb.ID(0), nil
+}
+
+func (f *fakeSvcManager) GetDeepCopyServices() []*lb.SVC {
+	return f.services
+}
+
+func TestUpsertServiceWithBackendsReorderOnly(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+
+	config := &LRPConfig{
+		id:      policyID{Name: "test", Namespace: "default"},
+		lrpType: lrpConfigTypeAddr,
+	}
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+
+	be1 := backend{IP: net.ParseIP("10.1.1.1"), L4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}}
+	be2 := backend{IP: net.ParseIP("10.1.1.2"), L4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}}
+
+	podA := podID{Name: "a", Namespace: "default"}
+	rpm.upsertServiceWithBackends(config, feM, podA, "uid-a", true, 0, "", "", []backend{be1, be2})
+	if svc.upsertCount != 1 {
+		t.Fatalf("expected 1 upsert for the initial backend set, got %d", svc.upsertCount)
+	}
+
+	// Same backends, reordered: should not trigger another upsert.
+	podB := podID{Name: "b", Namespace: "default"}
+	rpm.upsertServiceWithBackends(config, feM, podB, "uid-b", true, 0, "", "", []backend{be2, be1})
+	if svc.upsertCount != 1 {
+		t.Fatalf("expected reorder-only update to skip the upsert, got %d total upserts", svc.upsertCount)
+	}
+
+	// A genuinely different backend set should still trigger an upsert.
+	be3 := backend{IP: net.ParseIP("10.1.1.3"), L4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}}
+	rpm.upsertServiceWithBackends(config, feM, podA, "uid-a", true, 0, "", "", []backend{be1, be3})
+	if svc.upsertCount != 2 {
+		t.Fatalf("expected a changed backend set to trigger an upsert, got %d total upserts", svc.upsertCount)
+	}
+}
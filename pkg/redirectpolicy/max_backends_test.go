@@ -0,0 +1,131 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+func addMaxBackendsTestPod(t *testing.T, podStore cache.Store, name, ip string) {
+	t.Helper()
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  ip,
+			PodIPs: []slimcorev1.PodIP{{IP: ip}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	if err := podStore.Add(pod); err != nil {
+		t.Fatalf("failed to add pod %s: %v", name, err)
+	}
+}
+
+// TestMaxBackendsCapsSelection verifies that a policy with maxBackends set
+// only installs up to that many backends, even when more pods match.
+func TestMaxBackendsCapsSelection(t *testing.T) {
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := LRPConfig{
+		id:           policyID{Name: "test", Namespace: "default"},
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		}},
+		backendSelector: selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+		maxBackends: 2,
+	}
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	for i := 0; i < 5; i++ {
+		addMaxBackendsTestPod(t, podStore, fmt.Sprintf("pod-%d", i), fmt.Sprintf("10.1.1.%d", i))
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	stored := rpm.policyConfigs[config.id]
+	if got := totalBackends(stored); got != 2 {
+		t.Fatalf("expected maxBackends to cap the backend count at 2, got %d", got)
+	}
+}
+
+// TestGetLocalPodsForPolicyStableOrdering verifies that the pods returned
+// for a policy are ordered deterministically and that repeated calls over
+// the same (unordered) store return the same result.
+func TestGetLocalPodsForPolicyStableOrdering(t *testing.T) {
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := &LRPConfig{
+		id:              policyID{Name: "test", Namespace: "default"},
+		backendSelector: selector,
+	}
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	names := []string{"zeta", "alpha", "mu", "beta"}
+	for i, name := range names {
+		addMaxBackendsTestPod(t, podStore, name, fmt.Sprintf("10.1.1.%d", i))
+	}
+
+	var lastOrder []string
+	for i := 0; i < 3; i++ {
+		pods := rpm.getLocalPodsForPolicy(config, podStore)
+		if len(pods) != len(names) {
+			t.Fatalf("expected %d pods, got %d", len(names), len(pods))
+		}
+		order := make([]string, len(pods))
+		for j, p := range pods {
+			order[j] = p.id.Name
+		}
+		if i == 0 {
+			lastOrder = order
+			continue
+		}
+		for j := range order {
+			if order[j] != lastOrder[j] {
+				t.Fatalf("pod ordering changed across calls: %v vs %v", lastOrder, order)
+			}
+		}
+	}
+
+	expected := []string{"alpha", "beta", "mu", "zeta"}
+	for i, name := range expected {
+		if lastOrder[i] != name {
+			t.Fatalf("expected pods ordered by name, got %v", lastOrder)
+		}
+	}
+}
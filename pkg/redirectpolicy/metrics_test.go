@@ -0,0 +1,239 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/metrics"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestLRPBackendCountTracksPodLifecycle verifies that the LRP backend count
+// gauge follows a pod being added and then removed as a policy backend.
+func TestLRPBackendCountTracksPodLifecycle(t *testing.T) {
+	origGaugeVec := metrics.LRPBackendCount
+	gaugeVec := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "test_lrp_backend_count",
+	}, []string{metrics.LabelPolicyNamespace, metrics.LabelPolicyName})
+	metrics.LRPBackendCount = gaugeVec
+	defer func() { metrics.LRPBackendCount = origGaugeVec }()
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	id := policyID{Name: "test", Namespace: "default"}
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := LRPConfig{
+		id:           id,
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		}},
+		backendSelector: selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	rpm.storePolicyConfig(config)
+
+	gauge := gaugeVec.WithLabelValues(id.Namespace, id.Name)
+	if got := testutil.ToFloat64(gauge); got != 0 {
+		t.Fatalf("expected gauge to start at 0, got %v", got)
+	}
+
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	if err := podStore.Add(pod); err != nil {
+		t.Fatalf("failed to add pod to store: %v", err)
+	}
+
+	rpm.OnAddPod(pod)
+	if got := testutil.ToFloat64(gauge); got != 1 {
+		t.Fatalf("expected gauge to be 1 after OnAddPod, got %v", got)
+	}
+
+	rpm.OnDeletePod(pod)
+	if got := testutil.ToFloat64(gauge); got != 0 {
+		t.Fatalf("expected gauge to be 0 after OnDeletePod, got %v", got)
+	}
+}
+
+// TestLRPTimeToFirstBackendObservedOnce verifies that an observation is
+// recorded once a policy's first backend is programmed, and that a
+// subsequent backend update doesn't add a second observation.
+func TestLRPTimeToFirstBackendObservedOnce(t *testing.T) {
+	origHistogramVec := metrics.LRPTimeToFirstBackend
+	histogramVec := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "test_lrp_time_to_first_backend_seconds",
+	}, []string{metrics.LabelPolicyNamespace, metrics.LabelPolicyName})
+	metrics.LRPTimeToFirstBackend = histogramVec
+	defer func() { metrics.LRPTimeToFirstBackend = origHistogramVec }()
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	id := policyID{Name: "test", Namespace: "default"}
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := LRPConfig{
+		id:           id,
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		}},
+		backendSelector: selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	if got := testutil.CollectAndCount(histogramVec); got != 0 {
+		t.Fatalf("expected no observation before any backend exists, got %d", got)
+	}
+
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	if err := podStore.Add(pod); err != nil {
+		t.Fatalf("failed to add pod to store: %v", err)
+	}
+
+	rpm.OnAddPod(pod)
+	if got := testutil.CollectAndCount(histogramVec); got != 1 {
+		t.Fatalf("expected 1 observation after the first backend is programmed, got %d", got)
+	}
+
+	// A second pod extends the backend set; the first-backend observation
+	// must not be recorded again.
+	pod2 := pod.DeepCopy()
+	pod2.Name = "bar-pod"
+	pod2.Status.PodIP = "10.1.1.2"
+	pod2.Status.PodIPs = []slimcorev1.PodIP{{IP: "10.1.1.2"}}
+	if err := podStore.Add(pod2); err != nil {
+		t.Fatalf("failed to add pod to store: %v", err)
+	}
+	rpm.OnAddPod(pod2)
+	if got := testutil.CollectAndCount(histogramVec); got != 1 {
+		t.Fatalf("expected still only 1 observation after a second backend is programmed, got %d", got)
+	}
+}
+
+// TestLRPFamilyMismatchCountedForV6OnlyPodBehindV4Frontend verifies that the
+// family mismatch counter increments when a policy's only selected pod is
+// v6-only but its frontend is v4, and that it does not increment for an
+// unrelated zero-backend cause (a selector matching no pods at all).
+func TestLRPFamilyMismatchCountedForV6OnlyPodBehindV4Frontend(t *testing.T) {
+	origCounterVec := metrics.LRPFamilyMismatchTotal
+	counterVec := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "test_lrp_family_mismatch_total",
+	}, []string{metrics.LabelPolicyNamespace, metrics.LabelPolicyName})
+	metrics.LRPFamilyMismatchTotal = counterVec
+	defer func() { metrics.LRPFamilyMismatchTotal = origCounterVec }()
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	id := policyID{Name: "test", Namespace: "default"}
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := LRPConfig{
+		id:           id,
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		}},
+		backendSelector: selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+
+	// No pods selected at all: not a family mismatch.
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	counter := counterVec.WithLabelValues(id.Namespace, id.Name)
+	if got := testutil.ToFloat64(counter); got != 0 {
+		t.Fatalf("expected no family mismatch counted without any selected pods, got %v", got)
+	}
+
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "fd00::1",
+			PodIPs: []slimcorev1.PodIP{{IP: "fd00::1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	if err := podStore.Add(pod); err != nil {
+		t.Fatalf("failed to add pod to store: %v", err)
+	}
+
+	rpm.OnAddPod(pod)
+	if got := testutil.ToFloat64(counter); got != 1 {
+		t.Fatalf("expected the family mismatch to be counted once the v6-only pod is selected, got %v", got)
+	}
+
+	// Must not double-count while the policy stays in the mismatched state.
+	rpm.OnUpdatePod(pod)
+	if got := testutil.ToFloat64(counter); got != 1 {
+		t.Fatalf("expected no additional count while still mismatched, got %v", got)
+	}
+}
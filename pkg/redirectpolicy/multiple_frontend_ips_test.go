@@ -0,0 +1,79 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+)
+
+// TestExpandFrontendMappingsMultipleIPs verifies that expandFrontendMappings
+// produces one feMapping per IP returned for a given service type, not just
+// the first. GetServiceFrontendIPs can only ever return a single IP in this
+// tree today, since the vendored Kubernetes Service type has no ClusterIPs
+// field, so this exercises the loop with a stub returning two IPs (as a
+// dual-stack service's clusterIP lookup would) rather than a real
+// ServiceCache.
+func TestExpandFrontendMappingsMultipleIPs(t *testing.T) {
+	v4 := net.ParseIP("172.0.20.1")
+	v6 := net.ParseIP("fd00::1")
+	template := lb.NewL3n4Addr(lb.TCP, nil, 80, lb.ScopeExternal)
+
+	mappings := expandFrontendMappings(
+		[]lb.SVCType{lb.SVCTypeClusterIP},
+		map[portName]*frontend{"": template},
+		func(svcType lb.SVCType) []net.IP {
+			return []net.IP{v4, v6}
+		},
+	)
+
+	if len(mappings) != 2 {
+		t.Fatalf("expected 2 frontend mappings, one per IP family, got %v", mappings)
+	}
+	seen := map[string]bool{}
+	for _, m := range mappings {
+		seen[m.feAddr.IP.String()] = true
+		if m.fePort != "" {
+			t.Errorf("expected the unnamed port, got %q", m.fePort)
+		}
+	}
+	if !seen[v4.String()] || !seen[v6.String()] {
+		t.Fatalf("expected both %s and %s among the mappings, got %v", v4, v6, mappings)
+	}
+}
+
+// TestExpandFrontendMappingsNoIPs verifies that a service type with no
+// frontend IP (e.g. a type the service doesn't expose) contributes no
+// mappings, rather than a mapping with a nil IP.
+func TestExpandFrontendMappingsNoIPs(t *testing.T) {
+	template := lb.NewL3n4Addr(lb.TCP, nil, 80, lb.ScopeExternal)
+
+	mappings := expandFrontendMappings(
+		[]lb.SVCType{lb.SVCTypeClusterIP, lb.SVCTypeNodePort},
+		map[portName]*frontend{"": template},
+		func(svcType lb.SVCType) []net.IP {
+			if svcType == lb.SVCTypeNodePort {
+				return []net.IP{net.ParseIP("172.0.20.1")}
+			}
+			return nil
+		},
+	)
+
+	if len(mappings) != 1 {
+		t.Fatalf("expected 1 frontend mapping from the NodePort type only, got %v", mappings)
+	}
+}
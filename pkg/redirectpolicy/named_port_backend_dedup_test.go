@@ -0,0 +1,110 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestNamedPortsDeduplicatesSharedPodIP verifies that when two pods (e.g.
+// hostNetwork sidecars, with the policy opting into allowHostNetworkBackends)
+// report the same backend IP:port for a named-port policy, only a single
+// backend is installed, and only one of the two pods is recorded as owning
+// it in policyPods.
+func TestNamedPortsDeduplicatesSharedPodIP(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	for _, name := range []string{"foo-pod-1", "foo-pod-2"} {
+		pod := &slimcorev1.Pod{
+			ObjectMeta: slim_metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Labels:    map[string]string{"app": "foo"},
+			},
+			Spec: slimcorev1.PodSpec{
+				HostNetwork: true,
+				Containers: []slimcorev1.Container{{
+					Ports: []slimcorev1.ContainerPort{{
+						Name:          "web",
+						Protocol:      slimcorev1.ProtocolTCP,
+						ContainerPort: 8080,
+					}},
+				}},
+			},
+			Status: slimcorev1.PodStatus{
+				PodIP:  "10.1.1.1",
+				PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+				Conditions: []slimcorev1.PodCondition{
+					{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+				},
+			},
+		}
+		if err := podStore.Add(pod); err != nil {
+			t.Fatalf("failed to add pod %s: %v", name, err)
+		}
+	}
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		fePort: "web",
+	}
+	config := LRPConfig{
+		id:                       policyID{Name: "test", Namespace: "default"},
+		lrpType:                  lrpConfigTypeAddr,
+		frontendType:             addrFrontendNamedPorts,
+		frontendMappings:         []*feMapping{feM},
+		backendSelector:          selector,
+		allowHostNetworkBackends: true,
+		backendPortsByPortName: map[portName]*bePortInfo{
+			"web": {l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 8080}, name: "web"},
+		},
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	if len(feM.backends) != 1 {
+		t.Fatalf("expected exactly one deduplicated backend, got %v", feM.backends)
+	}
+	if svc.lastUpserted == nil || len(svc.lastUpserted.Backends) != 1 {
+		t.Fatalf("expected exactly one upserted backend, got %+v", svc.lastUpserted)
+	}
+
+	var owningPods int
+	for podID, infos := range rpm.policyPods {
+		for _, info := range infos {
+			if info.policyID == config.id && len(info.backends) > 0 {
+				owningPods++
+				t.Logf("pod %s recorded as owning backend(s) %v", podID, info.backends)
+			}
+		}
+	}
+	if owningPods != 1 {
+		t.Fatalf("expected exactly one pod to be recorded as owning the shared backend, got %d", owningPods)
+	}
+}
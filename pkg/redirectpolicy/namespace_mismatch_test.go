@@ -0,0 +1,73 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"testing"
+
+	"github.com/cilium/cilium/pkg/k8s"
+	v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/policy/api"
+)
+
+// TestServiceMatcherNamespaceMismatchRejected verifies that a service-matcher
+// policy whose service namespace differs from the policy's own namespace is
+// rejected at sanitization, since an LRP can't redirect a service in another
+// namespace without explicit opt-in.
+func TestServiceMatcherNamespaceMismatchRejected(t *testing.T) {
+	spec := v2.CiliumLocalRedirectPolicySpec{
+		RedirectFrontend: v2.RedirectFrontend{
+			ServiceMatcher: &v2.ServiceInfo{
+				Name:      "my-service",
+				Namespace: "other-namespace",
+			},
+		},
+		RedirectBackend: v2.RedirectBackend{
+			LocalEndpointSelector: slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "proxy"}},
+			ToPorts: []v2.PortInfo{
+				{Port: "53", Protocol: api.ProtoUDP},
+			},
+		},
+	}
+
+	if _, err := getSanitizedLRPConfig("svc-lrp", "default", "", spec); err == nil {
+		t.Fatal("expected a service matcher namespace mismatch to be rejected")
+	}
+}
+
+// TestIsValidConfigRejectsServiceNamespaceMismatch verifies that
+// isValidConfig (exercised via AddRedirectPolicy) rejects a service-type
+// config whose serviceID.Namespace differs from the policy's own namespace,
+// even when the mismatch wasn't already caught by sanitization, e.g. because
+// the LRPConfig was constructed directly rather than parsed from a CRD.
+func TestIsValidConfigRejectsServiceNamespaceMismatch(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+
+	config := LRPConfig{
+		id:      policyID{Name: "svc-lrp", Namespace: "default"},
+		lrpType: lrpConfigTypeSvc,
+		serviceID: &k8s.ServiceID{
+			Name:      "my-service",
+			Namespace: "other-namespace",
+		},
+		backendSelector: api.NewESFromK8sLabelSelector("",
+			&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "proxy"}}),
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, nil); ok || err == nil {
+		t.Fatal("expected AddRedirectPolicy to reject a service namespace mismatch")
+	}
+}
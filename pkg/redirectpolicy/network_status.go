@@ -0,0 +1,70 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"encoding/json"
+	"net"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+)
+
+// networkStatusAnnotation is the Multus annotation reporting the status,
+// including assigned IPs, of every network interface attached to a pod.
+// See https://github.com/k8snetworkplumbingwg/multus-cni for the format.
+const networkStatusAnnotation = "k8s.v1.cni.cncf.io/network-status"
+
+// networkStatusEntry is the subset of a single Multus network-status entry
+// that we care about.
+type networkStatusEntry struct {
+	Interface string   `json:"interface"`
+	IPs       []string `json:"ips"`
+	// Default marks the entry for the pod's primary interface, already
+	// reflected in pod.Status.PodIPs.
+	Default bool `json:"default"`
+}
+
+// annotatedPodIPs returns the valid IPs reported across the secondary
+// interfaces in the pod's Multus network-status annotation, for pods whose
+// intended backend address lives on an interface not reflected in
+// pod.Status.PodIPs. Returns nil if the annotation is absent, malformed, or
+// carries no secondary interface.
+func annotatedPodIPs(pod *slimcorev1.Pod) []string {
+	raw, ok := pod.GetAnnotations()[networkStatusAnnotation]
+	if !ok {
+		return nil
+	}
+
+	var entries []networkStatusEntry
+	if err := json.Unmarshal([]byte(raw), &entries); err != nil {
+		log.WithError(err).WithField(logfields.K8sPodName, pod.GetName()).
+			Warn("Failed to parse Multus network-status annotation")
+		return nil
+	}
+
+	var ips []string
+	for _, entry := range entries {
+		if entry.Default {
+			continue
+		}
+		for _, ip := range entry.IPs {
+			if net.ParseIP(ip) != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	return ips
+}
@@ -0,0 +1,132 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestBackendFromNetworkStatusAnnotation verifies that a config with
+// backendsFromNetworkStatus set picks the backend IP from the pod's Multus
+// network-status annotation rather than its primary PodIPs.
+func TestBackendFromNetworkStatusAnnotation(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "multus-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+			Annotations: map[string]string{
+				networkStatusAnnotation: `[
+					{"name":"cbr0","interface":"eth0","ips":["10.0.0.5"],"default":true},
+					{"name":"macvlan-conf","interface":"net1","ips":["192.168.1.100"]}
+				]`,
+			},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.0.0.5",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.0.0.5"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	podStore.Add(pod)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+	config := LRPConfig{
+		id:                        policyID{Name: "test", Namespace: "default"},
+		lrpType:                   lrpConfigTypeAddr,
+		frontendType:              addrFrontendSinglePort,
+		frontendMappings:          []*feMapping{feM},
+		backendSelector:           selector,
+		backendsFromNetworkStatus: true,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	stored := rpm.policyConfigs[config.id]
+	backends := stored.frontendMappings[0].backends
+	if len(backends) != 1 || !backends[0].IP.Equal(net.ParseIP("192.168.1.100")) {
+		t.Fatalf("expected the secondary interface IP from the annotation to become the backend, got %v", backends)
+	}
+}
+
+// TestBackendFromNetworkStatusAnnotationMissing verifies that a pod lacking
+// the network-status annotation produces no backend when the config opts
+// into backendsFromNetworkStatus.
+func TestBackendFromNetworkStatusAnnotationMissing(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "plain-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.0.0.5",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.0.0.5"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	podStore.Add(pod)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+	config := LRPConfig{
+		id:                        policyID{Name: "test", Namespace: "default"},
+		lrpType:                   lrpConfigTypeAddr,
+		frontendType:              addrFrontendSinglePort,
+		frontendMappings:          []*feMapping{feM},
+		backendSelector:           selector,
+		backendsFromNetworkStatus: true,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	stored := rpm.policyConfigs[config.id]
+	if len(stored.frontendMappings[0].backends) != 0 {
+		t.Fatalf("expected no backends for a pod without a network-status annotation, got %v", stored.frontendMappings[0].backends)
+	}
+}
@@ -0,0 +1,131 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// fakeEventRecorder is a fake eventRecorder that records every event fired
+// against it.
+type fakeEventRecorder struct {
+	events []string
+}
+
+func (f *fakeEventRecorder) Eventf(objRef *corev1.ObjectReference, eventtype, reason, messageFmt string, args ...interface{}) {
+	f.events = append(f.events, reason)
+}
+
+func newNoBackendsTestConfig(selector api.EndpointSelector) LRPConfig {
+	return LRPConfig{
+		id:           policyID{Name: "test", Namespace: "default"},
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		}},
+		backendSelector: selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+}
+
+// TestNoLocalBackendsEventFiresOncePerTransition verifies that a
+// NoLocalBackends event is recorded when a policy ends up with no backends,
+// that it isn't repeated while the policy stays backend-less, and that it
+// fires again the next time the policy transitions back into that state.
+func TestNoLocalBackendsEventFiresOncePerTransition(t *testing.T) {
+	recorder := &fakeEventRecorder{}
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, recorder)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := newNoBackendsTestConfig(selector)
+
+	// No pods selected at creation: one NoLocalBackends event.
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	if len(recorder.events) != 1 || recorder.events[0] != "NoLocalBackends" {
+		t.Fatalf("expected exactly one NoLocalBackends event, got %v", recorder.events)
+	}
+
+	// Repeated evaluations of the same zero-backend config must not spam
+	// additional events.
+	storedConfig := rpm.policyConfigs[config.id]
+	rpm.checkZeroBackends(storedConfig, nil)
+	rpm.checkZeroBackends(storedConfig, nil)
+	if len(recorder.events) != 1 {
+		t.Fatalf("expected no additional events while still backend-less, got %v", recorder.events)
+	}
+
+	// Adding a matching pod clears the debounced state without emitting a
+	// "resolved" event.
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	if err := podStore.Add(pod); err != nil {
+		t.Fatalf("failed to add pod to store: %v", err)
+	}
+	rpm.OnAddPod(pod)
+	if len(recorder.events) != 2 || recorder.events[1] != "Programmed" {
+		t.Fatalf("expected a Programmed event once a backend is programmed, got %v", recorder.events)
+	}
+
+	// Deleting the only backend transitions the policy back into the
+	// zero-backend state: a third event is expected.
+	rpm.OnDeletePod(pod)
+	if len(recorder.events) != 3 || recorder.events[2] != "NoLocalBackends" {
+		t.Fatalf("expected a second NoLocalBackends event after the backend was removed, got %v", recorder.events)
+	}
+}
+
+// TestNoLocalBackendsEventSkippedWithoutRecorder verifies that a manager
+// without an eventRecorder configured doesn't panic or otherwise misbehave
+// when a policy ends up with no backends.
+func TestNoLocalBackendsEventSkippedWithoutRecorder(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := newNoBackendsTestConfig(selector)
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+}
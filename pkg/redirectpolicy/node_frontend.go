@@ -0,0 +1,35 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/cilium/cilium/pkg/node"
+)
+
+// resolveNodeAddr returns the local node's primary address, preferring IPv4
+// and falling back to IPv6, as resolved from the node's default route at
+// agent startup.
+func resolveNodeAddr() (net.IP, error) {
+	if ip := node.GetExternalIPv4(); ip != nil {
+		return ip, nil
+	}
+	if ip := node.GetIPv6(); ip != nil {
+		return ip, nil
+	}
+	return nil, fmt.Errorf("local node address is not yet resolved")
+}
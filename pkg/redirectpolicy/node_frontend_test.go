@@ -0,0 +1,145 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/node"
+	"github.com/cilium/cilium/pkg/policy/api"
+)
+
+// TestResolveNodeAddr verifies that resolveNodeAddr prefers the node's
+// external IPv4 address, falling back to its IPv6 address, and errors out
+// when neither is known yet.
+func TestResolveNodeAddr(t *testing.T) {
+	defer node.SetExternalIPv4(node.GetExternalIPv4())
+	defer node.SetIPv6(node.GetIPv6())
+	node.SetExternalIPv4(nil)
+	node.SetIPv6(nil)
+
+	if _, err := resolveNodeAddr(); err == nil {
+		t.Error("expected an error before any node address is known")
+	}
+
+	node.SetIPv6(net.ParseIP("fd00::1"))
+	ip, err := resolveNodeAddr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ip.Equal(net.ParseIP("fd00::1")) {
+		t.Errorf("got %v, want fd00::1 (IPv6 fallback)", ip)
+	}
+
+	node.SetExternalIPv4(net.ParseIP("192.168.1.10"))
+	ip, err = resolveNodeAddr()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ip.Equal(net.ParseIP("192.168.1.10")) {
+		t.Errorf("got %v, want 192.168.1.10 (IPv4 should be preferred)", ip)
+	}
+}
+
+// TestResolveInterfaceFrontendsFromNode verifies that a frontend resolved
+// from the node sentinel picks up the node's address, defers resolution
+// while that address is unknown, and reacts to the address changing.
+func TestResolveInterfaceFrontendsFromNode(t *testing.T) {
+	defer node.SetExternalIPv4(node.GetExternalIPv4())
+	node.SetExternalIPv4(nil)
+
+	rpm := NewRedirectPolicyManager(nil, nil)
+
+	feM := &feMapping{
+		feAddr:          loadbalancer.NewL3n4Addr(loadbalancer.TCP, net.IP{}, 80, loadbalancer.ScopeExternal),
+		resolveFromNode: true,
+	}
+	config := &LRPConfig{
+		id:               policyID{Name: "test", Namespace: "default"},
+		lrpType:          lrpConfigTypeAddr,
+		frontendType:     addrFrontendSinglePort,
+		frontendMappings: []*feMapping{feM},
+	}
+	rpm.storePolicyConfig(*config)
+
+	// The node's address isn't known yet: resolution should be deferred,
+	// not error out.
+	if changed := rpm.resolveInterfaceFrontends(config); changed {
+		t.Error("expected no change while the node address is unknown")
+	}
+	if len(feM.feAddr.IP) != 0 {
+		t.Errorf("expected no IP to be assigned, got %v", feM.feAddr.IP)
+	}
+
+	node.SetExternalIPv4(net.ParseIP("10.0.0.8"))
+
+	if changed := rpm.resolveInterfaceFrontends(config); !changed {
+		t.Fatal("expected resolution to report a change once the node address is known")
+	}
+	if !feM.feAddr.IP.Equal(net.ParseIP("10.0.0.8")) {
+		t.Errorf("got %v, want 10.0.0.8", feM.feAddr.IP)
+	}
+	if id, ok := rpm.policyFrontendsByHash[frontendHash(feM.feAddr)]; !ok || id != config.id {
+		t.Error("expected the frontend hash index to be updated to the resolved address")
+	}
+
+	node.SetExternalIPv4(net.ParseIP("10.0.0.9"))
+	if changed := rpm.resolveInterfaceFrontends(config); !changed {
+		t.Fatal("expected resolution to report a change when the node address changes")
+	}
+	if !feM.feAddr.IP.Equal(net.ParseIP("10.0.0.9")) {
+		t.Errorf("got %v, want 10.0.0.9", feM.feAddr.IP)
+	}
+}
+
+// TestParseAddrMatcherNodeSentinel verifies that an AddressMatcher IP of
+// "node" parses into a frontend deferred to the local node's address,
+// rather than a literal IP.
+func TestParseAddrMatcherNodeSentinel(t *testing.T) {
+	spec := v2.CiliumLocalRedirectPolicySpec{
+		RedirectFrontend: v2.RedirectFrontend{
+			AddressMatcher: &v2.Frontend{
+				IP: nodeFrontendIP,
+				ToPorts: []v2.PortInfo{
+					{Port: "80", Protocol: api.ProtoTCP},
+				},
+			},
+		},
+		RedirectBackend: v2.RedirectBackend{
+			LocalEndpointSelector: slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}},
+			ToPorts: []v2.PortInfo{
+				{Port: "80", Protocol: api.ProtoTCP},
+			},
+		},
+	}
+	config, err := getSanitizedLRPConfig("test", "default", "", spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(config.frontendMappings) != 1 {
+		t.Fatalf("expected a single frontend mapping, got %d", len(config.frontendMappings))
+	}
+	feM := config.frontendMappings[0]
+	if !feM.resolveFromNode {
+		t.Error("expected resolveFromNode to be set for the node sentinel")
+	}
+	if len(feM.feAddr.IP) != 0 {
+		t.Errorf("expected no literal IP to be set, got %v", feM.feAddr.IP)
+	}
+}
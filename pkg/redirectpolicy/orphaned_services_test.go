@@ -0,0 +1,65 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+)
+
+// TestOrphanedServicesReportsUnbackedLocalRedirectEntry verifies that a
+// SVCTypeLocalRedirect entry with no corresponding live policy is reported
+// as orphaned, while a policy-backed one and a non-LocalRedirect service are
+// not.
+func TestOrphanedServicesReportsUnbackedLocalRedirectEntry(t *testing.T) {
+	liveFrontend := lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal)
+	orphanedFrontend := lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.2"), 80, lb.ScopeExternal)
+	clusterIPFrontend := lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.3"), 80, lb.ScopeExternal)
+
+	svc := &fakeSvcManager{
+		services: []*lb.SVC{
+			{
+				Frontend: lb.L3n4AddrID{L3n4Addr: *liveFrontend},
+				Type:     lb.SVCTypeLocalRedirect,
+			},
+			{
+				Frontend: lb.L3n4AddrID{L3n4Addr: *orphanedFrontend},
+				Type:     lb.SVCTypeLocalRedirect,
+			},
+			{
+				Frontend: lb.L3n4AddrID{L3n4Addr: *clusterIPFrontend},
+				Type:     lb.SVCTypeClusterIP,
+			},
+		},
+	}
+
+	rpm := NewRedirectPolicyManager(svc, nil)
+	id := policyID{Name: "live-policy", Namespace: "default"}
+	rpm.policyConfigs[id] = &LRPConfig{
+		id:               id,
+		frontendMappings: []*feMapping{{feAddr: liveFrontend}},
+	}
+	rpm.policyFrontendsByHash[frontendHash(liveFrontend)] = id
+
+	orphaned := rpm.OrphanedServices(svc)
+	if len(orphaned) != 1 {
+		t.Fatalf("expected exactly one orphaned service, got %d: %v", len(orphaned), orphaned)
+	}
+	if !orphaned[0].IP.Equal(orphanedFrontend.IP) {
+		t.Fatalf("expected the orphaned frontend to be reported, got %v", orphaned[0])
+	}
+}
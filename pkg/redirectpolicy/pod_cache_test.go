@@ -0,0 +1,107 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"fmt"
+	"testing"
+
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestGetLocalPodsForPoliciesMatchesPerPolicyLookup verifies that batching
+// several policies through getLocalPodsForPolicies, which scans podStore
+// once, returns exactly the same selection per policy as calling
+// getLocalPodsForPolicy once for each of them individually.
+func TestGetLocalPodsForPoliciesMatchesPerPolicyLookup(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	for i := 0; i < 10; i++ {
+		app := "foo"
+		if i%2 == 0 {
+			app = "bar"
+		}
+		addTestPod(t, podStore, fmt.Sprintf("pod-%d", i), fmt.Sprintf("10.1.1.%d", i+1),
+			map[string]string{"app": app})
+	}
+
+	newConfig := func(name, app string) *LRPConfig {
+		return &LRPConfig{
+			id: policyID{Name: name, Namespace: "default"},
+			backendSelector: api.NewESFromK8sLabelSelector("",
+				&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}),
+		}
+	}
+	configs := []*LRPConfig{newConfig("foo-policy", "foo"), newConfig("bar-policy", "bar")}
+
+	batched := rpm.getLocalPodsForPolicies(configs, podStore)
+
+	for _, config := range configs {
+		individual := rpm.getLocalPodsForPolicy(config, podStore)
+		got := batched[config.id]
+		if len(got) != len(individual) {
+			t.Fatalf("policy %s: expected %d pods from the batched lookup, got %d",
+				config.id.Name, len(individual), len(got))
+		}
+		for i := range individual {
+			if got[i].id != individual[i].id {
+				t.Fatalf("policy %s: expected pod %v at position %d, got %v",
+					config.id.Name, individual[i].id, i, got[i].id)
+			}
+		}
+	}
+}
+
+// BenchmarkGetLocalPodsForPolicies measures how parsing cost scales with the
+// number of policies evaluated together: getLocalPodsForPolicies must parse
+// the pod store once in total, not once per policy.
+func BenchmarkGetLocalPodsForPolicies(b *testing.B) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	const numPods = 200
+	for i := 0; i < numPods; i++ {
+		addTestPod(b, podStore, fmt.Sprintf("pod-%d", i), fmt.Sprintf("10.1.1.%d", i+1),
+			map[string]string{"app": "foo"})
+	}
+
+	const numPolicies = 50
+	configs := make([]*LRPConfig, numPolicies)
+	for i := range configs {
+		configs[i] = &LRPConfig{
+			id: policyID{Name: fmt.Sprintf("policy-%d", i), Namespace: "default"},
+			backendSelector: api.NewESFromK8sLabelSelector("",
+				&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}}),
+		}
+	}
+
+	b.Run("PerPolicyLookup", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, config := range configs {
+				rpm.getLocalPodsForPolicy(config, podStore)
+			}
+		}
+	})
+
+	b.Run("BatchedLookup", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			rpm.getLocalPodsForPolicies(configs, podStore)
+		}
+	})
+}
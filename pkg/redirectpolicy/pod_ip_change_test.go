@@ -0,0 +1,91 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestOnUpdatePodLockedIPChange verifies that when a selected pod's IP
+// changes between two updates, OnUpdatePodLocked removes the backend for
+// the pod's old IP and installs only the backend for its new IP, leaving no
+// stale entry behind.
+func TestOnUpdatePodLockedIPChange(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	labels := map[string]string{"app": "foo"}
+	addTestPod(t, podStore, "foo-pod", "10.1.1.1", labels)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: labels})
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+	config := LRPConfig{
+		id:               policyID{Name: "test", Namespace: "default"},
+		lrpType:          lrpConfigTypeAddr,
+		frontendType:     addrFrontendSinglePort,
+		frontendMappings: []*feMapping{feM},
+		backendSelector:  selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	stored := rpm.policyConfigs[config.id]
+	fem := stored.frontendMappings[0]
+	if len(fem.backends) != 1 || !fem.backends[0].IP.Equal(net.ParseIP("10.1.1.1")) {
+		t.Fatalf("expected the pod's initial IP to be installed as a backend, got %v", fem.backends)
+	}
+
+	// The pod is reassigned a new IP; update the store and replay the
+	// update through the same path the k8s watcher uses.
+	updatedPod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo-pod",
+			Namespace: "default",
+			Labels:    labels,
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.2",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.2"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	if err := podStore.Update(updatedPod); err != nil {
+		t.Fatalf("failed to update pod in store: %v", err)
+	}
+	rpm.OnUpdatePodLocked(updatedPod)
+
+	fem = rpm.policyConfigs[config.id].frontendMappings[0]
+	if len(fem.backends) != 1 || !fem.backends[0].IP.Equal(net.ParseIP("10.1.1.2")) {
+		t.Fatalf("expected only the pod's new IP to remain as a backend after the IP change, got %v", fem.backends)
+	}
+}
@@ -0,0 +1,141 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestAddRedirectPolicyOutcomeRejected verifies that an invalid config is
+// reported as PolicyAddRejected.
+func TestAddRedirectPolicyOutcomeRejected(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	// An address-matcher config declaring the same frontend twice is invalid.
+	feAddr := lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal)
+	config := LRPConfig{
+		id:      policyID{Name: "test", Namespace: "default"},
+		lrpType: lrpConfigTypeAddr,
+		frontendMappings: []*feMapping{
+			{feAddr: feAddr.DeepCopy()},
+			{feAddr: feAddr.DeepCopy()},
+		},
+	}
+
+	outcome, err := rpm.AddRedirectPolicyOutcome(config, nil, podStore)
+	if outcome != PolicyAddRejected || err == nil {
+		t.Fatalf("expected PolicyAddRejected with an error, got outcome=%v err=%v", outcome, err)
+	}
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); ok || err == nil {
+		t.Fatalf("expected the backward-compatible wrapper to also reject, got ok=%v err=%v", ok, err)
+	}
+}
+
+// TestAddRedirectPolicyOutcomeInstalledNoBackends verifies that a valid
+// config whose selector matches no pods is reported as
+// PolicyAddInstalledNoBackends, and that the bool wrapper still treats it as
+// accepted.
+func TestAddRedirectPolicyOutcomeInstalledNoBackends(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "never-matches"}})
+	config := newNoBackendsTestConfig(selector)
+
+	outcome, err := rpm.AddRedirectPolicyOutcome(config, nil, podStore)
+	if outcome != PolicyAddInstalledNoBackends || err != nil {
+		t.Fatalf("expected PolicyAddInstalledNoBackends, got outcome=%v err=%v", outcome, err)
+	}
+}
+
+// TestAddRedirectPolicyOutcomeInstalledWithBackends verifies that a valid
+// config with a matching, ready backend pod is reported as
+// PolicyAddInstalledWithBackends.
+func TestAddRedirectPolicyOutcomeInstalledWithBackends(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := newNoBackendsTestConfig(selector)
+
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	if err := podStore.Add(pod); err != nil {
+		t.Fatalf("failed to add pod: %v", err)
+	}
+
+	outcome, err := rpm.AddRedirectPolicyOutcome(config, nil, podStore)
+	if outcome != PolicyAddInstalledWithBackends || err != nil {
+		t.Fatalf("expected PolicyAddInstalledWithBackends, got outcome=%v err=%v", outcome, err)
+	}
+}
+
+// TestAddRedirectPolicyOutcomeUnchanged verifies that resubmitting an
+// identical config is reported as PolicyAddUnchanged.
+func TestAddRedirectPolicyOutcomeUnchanged(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "never-matches"}})
+	config := newNoBackendsTestConfig(selector)
+
+	if outcome, err := rpm.AddRedirectPolicyOutcome(config, nil, podStore); outcome != PolicyAddInstalledNoBackends || err != nil {
+		t.Fatalf("expected the initial add to be PolicyAddInstalledNoBackends, got outcome=%v err=%v", outcome, err)
+	}
+
+	outcome, err := rpm.AddRedirectPolicyOutcome(config, nil, podStore)
+	if outcome != PolicyAddUnchanged || err != nil {
+		t.Fatalf("expected resubmitting an identical config to report PolicyAddUnchanged, got outcome=%v err=%v", outcome, err)
+	}
+}
+
+func TestPolicyAddOutcomeString(t *testing.T) {
+	cases := map[PolicyAddOutcome]string{
+		PolicyAddRejected:             "Rejected",
+		PolicyAddUnchanged:            "Unchanged",
+		PolicyAddInstalledNoBackends:  "InstalledNoBackends",
+		PolicyAddInstalledWithBackends: "InstalledWithBackends",
+		PolicyAddOutcome(42):          "Unknown",
+	}
+	for outcome, want := range cases {
+		if got := outcome.String(); got != want {
+			t.Fatalf("expected %v.String() to be %q, got %q", int(outcome), want, got)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// orderRecordingSvcManager is a fake svcManager that records the frontend IP
+// of every UpsertService call, in the order they were made.
+type orderRecordingSvcManager struct {
+	frontends []string
+}
+
+func (f *orderRecordingSvcManager) DeleteService(frontend lb.L3n4Addr) (bool, error) {
+	return true, nil
+}
+
+func (f *orderRecordingSvcManager) UpsertService(svc *lb.SVC) (bool, lb.ID, error) {
+	f.frontends = append(f.frontends, svc.Frontend.IP.String())
+	return true, lb.ID(0), nil
+}
+
+func (f *orderRecordingSvcManager) GetDeepCopyServices() []*lb.SVC {
+	return nil
+}
+
+// TestOnUpdatePodLockedDeterministicOrder verifies that OnUpdatePodLocked
+// always upserts matching policies in the same order, regardless of the
+// (randomized) iteration order of the underlying policyConfigs map.
+func TestOnUpdatePodLockedDeterministicOrder(t *testing.T) {
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+
+	newConfig := func(name string, ip string) LRPConfig {
+		return LRPConfig{
+			id:               policyID{Name: name, Namespace: "default"},
+			lrpType:          lrpConfigTypeAddr,
+			frontendType:     addrFrontendSinglePort,
+			frontendMappings: []*feMapping{{feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP(ip), 80, lb.ScopeExternal)}},
+			backendSelector:  selector,
+			backendPorts:     []bePortInfo{{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}}},
+		}
+	}
+
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "backend-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+
+	// Policy IDs are intentionally added in reverse-sorted order, so that
+	// relying on map insertion order would produce a different result than
+	// sorting by policy ID.
+	policyFrontends := map[string]string{
+		"z-policy": "10.0.0.3",
+		"m-policy": "10.0.0.2",
+		"a-policy": "10.0.0.1",
+	}
+
+	var want []string
+	for run := 0; run < 5; run++ {
+		svc := &orderRecordingSvcManager{}
+		rpm := NewRedirectPolicyManager(svc, nil)
+		podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+		for _, name := range []string{"z-policy", "m-policy", "a-policy"} {
+			config := newConfig(name, policyFrontends[name])
+			if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+				t.Fatalf("AddRedirectPolicy(%s) failed: ok=%v err=%v", name, ok, err)
+			}
+		}
+
+		// All three policies now exist with no matching pods; adding the pod
+		// exercises OnUpdatePodLocked's map iteration over policyConfigs.
+		podStore.Add(pod)
+		rpm.OnUpdatePod(pod)
+
+		got := append([]string(nil), svc.frontends...)
+		if expected := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}; !reflect.DeepEqual(got, expected) {
+			t.Fatalf("run %d: expected policies upserted in ID order %v, got %v", run, expected, got)
+		}
+		if want == nil {
+			want = got
+		} else if !reflect.DeepEqual(got, want) {
+			t.Fatalf("run %d: upsert order %v does not match first run's order %v", run, got, want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+func newReadinessTestPod(name, ip string, ready bool, deleted bool) *slimcorev1.Pod {
+	status := slimcorev1.ConditionFalse
+	if ready {
+		status = slimcorev1.ConditionTrue
+	}
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  ip,
+			PodIPs: []slimcorev1.PodIP{{IP: ip}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: status},
+			},
+		},
+	}
+	if deleted {
+		now := slim_metav1.Now()
+		pod.ObjectMeta.DeletionTimestamp = &now
+	}
+	return pod
+}
+
+// TestNotReadyPodExcludedFromBackends verifies that a pod that hasn't
+// reported Ready yet is never selected as a backend.
+func TestNotReadyPodExcludedFromBackends(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+	config := LRPConfig{
+		id:               policyID{Name: "test", Namespace: "default"},
+		lrpType:          lrpConfigTypeAddr,
+		frontendType:     addrFrontendSinglePort,
+		frontendMappings: []*feMapping{feM},
+		backendSelector:  selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	rpm.storePolicyConfig(config)
+
+	pod := newReadinessTestPod("starting-pod", "10.1.1.1", false, false)
+	podStore.Add(pod)
+	rpm.OnAddPod(pod)
+
+	stored := rpm.policyConfigs[config.id]
+	if len(stored.frontendMappings[0].backends) != 0 {
+		t.Fatalf("expected no backends for a not-ready pod, got %v", stored.frontendMappings[0].backends)
+	}
+
+	// Once the pod turns Ready, it must be added as a backend.
+	pod.Status.Conditions[0].Status = slimcorev1.ConditionTrue
+	rpm.OnUpdatePod(pod)
+	if len(stored.frontendMappings[0].backends) != 1 {
+		t.Fatalf("expected 1 backend once the pod became ready, got %v", stored.frontendMappings[0].backends)
+	}
+}
+
+// TestTerminatingPodDroppedWhenOtherBackendReady verifies that a pod that
+// starts terminating while another ready backend exists is removed, and is
+// not re-admitted as a backend by default.
+func TestTerminatingPodDroppedWhenOtherBackendReady(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	podA := newReadinessTestPod("pod-a", "10.1.1.1", true, false)
+	podB := newReadinessTestPod("pod-b", "10.1.1.2", true, false)
+	podStore.Add(podA)
+	podStore.Add(podB)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+	config := LRPConfig{
+		id:               policyID{Name: "test", Namespace: "default"},
+		lrpType:          lrpConfigTypeAddr,
+		frontendType:     addrFrontendSinglePort,
+		frontendMappings: []*feMapping{feM},
+		backendSelector:  selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	stored := rpm.policyConfigs[config.id]
+	if len(stored.frontendMappings[0].backends) != 2 {
+		t.Fatalf("expected 2 ready backends, got %v", stored.frontendMappings[0].backends)
+	}
+
+	// pod-a starts terminating while pod-b is still ready: pod-a must be
+	// dropped as a backend and not replaced, since terminating backends are
+	// opt-in.
+	now := slim_metav1.Now()
+	podA.ObjectMeta.DeletionTimestamp = &now
+	rpm.OnUpdatePod(podA)
+
+	if len(stored.frontendMappings[0].backends) != 1 || !stored.frontendMappings[0].backends[0].IP.Equal(net.ParseIP("10.1.1.2")) {
+		t.Fatalf("expected only pod-b's backend to remain, got %v", stored.frontendMappings[0].backends)
+	}
+}
+
+// TestTerminatingPodKeptAsLastResortBackend verifies that with
+// includeTerminatingBackends enabled, a terminating pod is kept as a backend
+// only once no ready backend remains for the policy.
+func TestTerminatingPodKeptAsLastResortBackend(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+	config := LRPConfig{
+		id:                         policyID{Name: "test", Namespace: "default"},
+		lrpType:                    lrpConfigTypeAddr,
+		frontendType:               addrFrontendSinglePort,
+		frontendMappings:           []*feMapping{feM},
+		backendSelector:            selector,
+		includeTerminatingBackends: true,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	rpm.storePolicyConfig(config)
+
+	pod := newReadinessTestPod("solo-pod", "10.1.1.1", true, false)
+	podStore.Add(pod)
+	rpm.OnAddPod(pod)
+
+	stored := rpm.policyConfigs[config.id]
+	if len(stored.frontendMappings[0].backends) != 1 {
+		t.Fatalf("expected 1 ready backend, got %v", stored.frontendMappings[0].backends)
+	}
+
+	// The only backend starts terminating: since no other ready backend
+	// exists, it must be kept as a last resort.
+	now := slim_metav1.Now()
+	pod.ObjectMeta.DeletionTimestamp = &now
+	rpm.OnUpdatePod(pod)
+
+	if len(stored.frontendMappings[0].backends) != 1 || !stored.frontendMappings[0].backends[0].IP.Equal(net.ParseIP("10.1.1.1")) {
+		t.Fatalf("expected the terminating pod to be kept as a last-resort backend, got %v", stored.frontendMappings[0].backends)
+	}
+}
@@ -0,0 +1,59 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+)
+
+// TestRebuildFrontendIndexRestoresCorruptedIndex verifies that
+// RebuildFrontendIndex discards whatever policyFrontendsByHash previously
+// held and repopulates it purely from the frontends in policyConfigs.
+func TestRebuildFrontendIndexRestoresCorruptedIndex(t *testing.T) {
+	frontendA := lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal)
+	frontendB := lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.2"), 80, lb.ScopeExternal)
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	id := policyID{Name: "lrp", Namespace: "default"}
+	rpm.policyConfigs[id] = &LRPConfig{
+		id: id,
+		frontendMappings: []*feMapping{
+			{feAddr: frontendA},
+			{feAddr: frontendB},
+		},
+	}
+
+	// Corrupt the index: drop frontendB's entry and add a stale one that no
+	// longer corresponds to any stored config.
+	rpm.policyFrontendsByHash[frontendHash(frontendA)] = id
+	rpm.policyFrontendsByHash["stale-hash"] = policyID{Name: "gone", Namespace: "default"}
+
+	rpm.RebuildFrontendIndex()
+
+	if len(rpm.policyFrontendsByHash) != 2 {
+		t.Fatalf("expected the index to contain exactly 2 entries, got %d: %v", len(rpm.policyFrontendsByHash), rpm.policyFrontendsByHash)
+	}
+	for _, fe := range []*lb.L3n4Addr{frontendA, frontendB} {
+		if got, ok := rpm.policyFrontendsByHash[frontendHash(fe)]; !ok || got != id {
+			t.Errorf("expected %v to map to %v in the rebuilt index, got %v (present: %v)", fe, id, got, ok)
+		}
+	}
+	if _, ok := rpm.policyFrontendsByHash["stale-hash"]; ok {
+		t.Errorf("expected the stale entry to be dropped by the rebuild")
+	}
+}
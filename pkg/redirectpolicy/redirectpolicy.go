@@ -17,18 +17,31 @@ package redirectpolicy
 import (
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/cilium/cilium/pkg/k8s"
 	v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
 	"github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/labels"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
 	k8sUtils "github.com/cilium/cilium/pkg/k8s/utils"
 	"github.com/cilium/cilium/pkg/loadbalancer"
 	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/option"
 	"github.com/cilium/cilium/pkg/policy/api"
 
+	datapathOption "github.com/cilium/cilium/pkg/datapath/option"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/types"
 )
 
+// supportedBackendFieldSelectors is the set of pod spec fields a policy's
+// backend field selector is allowed to reference. It's deliberately small:
+// each entry must have a corresponding fields.Set entry populated in
+// podMetadata.fieldSet.
+var supportedBackendFieldSelectors = map[string]struct{}{
+	"spec.hostNetwork": {},
+}
+
 type lrpConfigType = int
 
 const (
@@ -55,6 +68,11 @@ const (
 	addrFrontendNamedPorts
 )
 
+// nodeFrontendIP is the AddressMatcher.IP sentinel value that resolves an
+// address-type LRP's frontend to the local node's own primary address,
+// instead of requiring operators to hardcode it.
+const nodeFrontendIP = "node"
+
 // LRPConfig is the internal representation of Cilium Local Redirect Policy.
 type LRPConfig struct {
 	// id is the parsed config name and namespace
@@ -72,11 +90,152 @@ type LRPConfig struct {
 	serviceID *k8s.ServiceID
 	// backendSelector is an endpoint selector generated from the parsed policy selector
 	backendSelector api.EndpointSelector
+	// backendExcludeSelector, if its LabelSelector is non-nil, excludes pods
+	// that match it from backend selection, even if they match
+	// backendSelector. Its zero value matches no pods, so it's a no-op when
+	// unset.
+	backendExcludeSelector api.EndpointSelector
+	// backendAnnotationSelector, if its LabelSelector is non-nil, further
+	// restricts backend selection to pods whose annotations (rather than
+	// labels) match it, in addition to backendSelector. Its zero value
+	// matches every pod, so it's a no-op when unset.
+	backendAnnotationSelector api.EndpointSelector
+	// backendFieldSelector, if non-nil, further restricts backend selection
+	// to pods whose spec fields match, in addition to backendSelector. Label
+	// selection via backendSelector remains the primary selection path; this
+	// is an optional, additional constraint.
+	backendFieldSelector fields.Selector
+	// backendNamespace, if non-empty, selects backend pods from this
+	// namespace instead of the policy's own namespace (id.Namespace). Only
+	// valid for address-matcher policies.
+	backendNamespace string
 	// backendPorts is a slice of backend port and protocol along with the port name
 	backendPorts []bePortInfo
 	// backendPortsByPortName is a map indexed by port name with the value as
 	// a pointer to bePortInfo for easy lookup into backendPorts
 	backendPortsByPortName map[portName]*bePortInfo
+	// sessionAffinity is true if the backing Kubernetes service (for
+	// service-type policies) has ClientIP session affinity enabled, and is
+	// propagated onto the local redirect service so that affinity is
+	// preserved across the redirect.
+	sessionAffinity bool
+	// sessionAffinityTimeoutSec is the affinity timeout of the backing
+	// service, valid only when sessionAffinity is true.
+	sessionAffinityTimeoutSec uint32
+	// serviceAccount, if non-empty, restricts backend selection to pods
+	// running under the given ServiceAccount, in addition to the backend
+	// selector.
+	serviceAccount string
+	// includeTerminatingBackends allows a terminating pod (one with a
+	// DeletionTimestamp set) to be selected as a backend, but only while no
+	// ready, non-terminating backend is available for the policy. This
+	// mirrors Kubernetes' graceful-termination behavior for Services.
+	includeTerminatingBackends bool
+	// additionalFrontendTypes lists service frontend types, beyond the
+	// always-included ClusterIP, whose addresses should also be redirected
+	// to node-local backends for a service-matcher policy. Empty by default
+	// to preserve the original ClusterIP-only behavior.
+	additionalFrontendTypes []lb.SVCType
+	// resolveHeadlessEndpoints allows this service-matcher policy to
+	// redirect traffic for a headless service (one with no ClusterIP): one
+	// frontend is created per endpoint instead of the usual single
+	// service-wide frontend. Only honored for svcFrontendSinglePort and
+	// svcFrontendNamedPorts, since a headless service has no concrete
+	// frontend port to enumerate without an explicit ToPorts. False by
+	// default to preserve the original ClusterIP-required behavior.
+	resolveHeadlessEndpoints bool
+	// backendsFromNetworkStatus selects backend IPs from a pod's Multus
+	// network-status annotation instead of pod.Status.PodIPs, for pods that
+	// expose the intended backend address on a secondary interface attached
+	// through CNI chaining. Pods without a usable annotation produce no
+	// backend. False by default to preserve the original PodIPs-only
+	// behavior.
+	backendsFromNetworkStatus bool
+	// skipRedirectFromBackend marks backend pod IPs so that their own
+	// originated traffic bypasses this policy's redirect, letting a backend
+	// reach the real service it backs (e.g. node-local DNS talking to the
+	// upstream resolver). False by default, preserving the original
+	// behavior of redirecting backend-originated traffic like any other.
+	skipRedirectFromBackend bool
+	// allowHostNetworkBackends allows a hostNetwork pod to be selected as a
+	// backend. False by default: a hostNetwork pod's containerPort is the
+	// host port and its pod IP is the node's own address, which may already
+	// be a frontend, creating a redirect loop if it's installed as a
+	// backend unconditionally.
+	allowHostNetworkBackends bool
+	// maxBackends, if greater than zero, caps the number of backend pods
+	// selected for this policy. Candidate pods are ordered deterministically
+	// (by namespace, then name) before the cap is applied, so the chosen
+	// subset is stable across manager restarts. Zero means unlimited.
+	maxBackends int
+	// restartGracePeriod, if greater than zero, is how long a deleted
+	// backend pod's entries are held in place before actually being removed,
+	// so that a pod recreated under a new UID with the same name within the
+	// window never observes its service torn down and rebuilt. Zero
+	// disables the grace period, preserving the original immediate-removal
+	// behavior.
+	restartGracePeriod time.Duration
+	// deleteDrainPeriod, if greater than zero, is how long the policy's
+	// local redirect service is held in place, with its backends' weight
+	// reset to zero (the default weight), after the policy itself is
+	// deleted, before the service is actually removed. Zero disables the drain period, preserving the
+	// original immediate-removal behavior.
+	deleteDrainPeriod time.Duration
+	// unresolvedReason is a human-readable explanation for why this policy's
+	// backing Kubernetes service currently can't be resolved to a frontend,
+	// set by getAndUpsertPolicySvcConfig for service-matcher policies. Empty
+	// once resolution succeeds, and always empty for address-matcher
+	// policies, which have no service to resolve.
+	unresolvedReason string
+}
+
+// UnresolvedReason returns a human-readable explanation for why c's backing
+// Kubernetes service currently can't be resolved to a frontend, e.g. because
+// the service doesn't exist yet or isn't of a type c's frontendType can use.
+// It is empty once resolution succeeds, and always empty for address-matcher
+// policies.
+func (c *LRPConfig) UnresolvedReason() string {
+	return c.unresolvedReason
+}
+
+// deepCopy returns a copy of c whose frontend mappings, backend ports and
+// backends do not share any underlying arrays or maps with c, so that
+// callers outside the package can't mutate the manager's internal state
+// through the returned config.
+func (c *LRPConfig) deepCopy() *LRPConfig {
+	out := *c
+
+	if c.frontendMappings != nil {
+		out.frontendMappings = make([]*feMapping, len(c.frontendMappings))
+		for i, feM := range c.frontendMappings {
+			feMCopy := *feM
+			feMCopy.feAddr = feM.feAddr.DeepCopy()
+			feMCopy.backends = append([]backend{}, feM.backends...)
+			out.frontendMappings[i] = &feMCopy
+		}
+	}
+
+	if c.serviceID != nil {
+		svcID := *c.serviceID
+		out.serviceID = &svcID
+	}
+
+	if c.backendPorts != nil {
+		out.backendPorts = append([]bePortInfo{}, c.backendPorts...)
+	}
+
+	if c.backendPortsByPortName != nil {
+		out.backendPortsByPortName = make(map[portName]*bePortInfo, len(c.backendPortsByPortName))
+		for i := range out.backendPorts {
+			out.backendPortsByPortName[out.backendPorts[i].name] = &out.backendPorts[i]
+		}
+	}
+
+	if c.additionalFrontendTypes != nil {
+		out.additionalFrontendTypes = append([]lb.SVCType{}, c.additionalFrontendTypes...)
+	}
+
+	return &out
 }
 
 type frontend = loadbalancer.L3n4Addr
@@ -90,6 +249,21 @@ type feMapping struct {
 	feAddr   *frontend
 	backends []backend
 	fePort   portName
+	// ifName is set when the frontend IP is resolved from a local
+	// interface's primary address rather than specified literally.
+	ifName string
+	// resolveFromNode is set when the frontend IP is resolved from the
+	// local node's own primary address (the nodeFrontendIP sentinel)
+	// rather than specified literally.
+	resolveFromNode bool
+	// bePortIndex is the index into the config's backendPorts of the
+	// backend port this mapping pairs with. It is 0 for every ordinary
+	// single-port frontend mapping, matching the implicit backendPorts[0]
+	// every such mapping has always shared; it's only set to something
+	// else for one of the mappings an ANY (wildcard) protocol frontend
+	// port expands into, each of which pairs with its own protocol-specific
+	// backend port (see expandWildcardProtocolMappings).
+	bePortIndex int
 }
 
 type bePortInfo struct {
@@ -142,6 +316,8 @@ func getSanitizedLRPConfig(name, namespace string, uid types.UID, spec v2.Cilium
 		feMappings     []*feMapping
 		bePorts        []bePortInfo
 		bePortsMap     = make(map[portName]*bePortInfo)
+
+		resolveHeadlessEndpoints bool
 	)
 
 	// Parse frontend config
@@ -154,10 +330,46 @@ func getSanitizedLRPConfig(name, namespace string, uid types.UID, spec v2.Cilium
 			" matchers can not be specified")
 	case addrMatcher != nil:
 		// LRP specifies IP/port tuple config for traffic that needs to be redirected.
-		ip := net.ParseIP(addrMatcher.IP)
-		if ip == nil {
-			return nil, fmt.Errorf("invalid address matcher IP %v",
-				addrMatcher.IP)
+		var (
+			ips             []net.IP
+			ifName          string
+			resolveFromNode bool
+		)
+		switch {
+		case addrMatcher.CIDR != "" && (addrMatcher.IP != "" || addrMatcher.Interface != ""):
+			return nil, fmt.Errorf("only one of address matcher IP, interface" +
+				" and CIDR can be specified")
+		case addrMatcher.CIDR != "":
+			// The frontend expands into one address per IP contained in the
+			// CIDR, capped by expandCIDRFrontendAddresses to keep a typo'd,
+			// overly broad CIDR from blowing up policyFrontendsByHash.
+			expanded, err := expandCIDRFrontendAddresses(addrMatcher.CIDR)
+			if err != nil {
+				return nil, err
+			}
+			ips = expanded
+		case addrMatcher.IP != "" && addrMatcher.Interface != "":
+			return nil, fmt.Errorf("only one of address matcher IP and" +
+				" interface can be specified")
+		case addrMatcher.Interface != "":
+			// The frontend IP is resolved from the interface's primary
+			// address by the redirect policy manager, possibly deferred
+			// until the interface shows up.
+			ifName = addrMatcher.Interface
+			ips = []net.IP{{}}
+		case addrMatcher.IP == nodeFrontendIP:
+			// The frontend IP is resolved from the local node's own
+			// primary address by the redirect policy manager, possibly
+			// deferred until that address is known.
+			resolveFromNode = true
+			ips = []net.IP{{}}
+		default:
+			ip := net.ParseIP(addrMatcher.IP)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address matcher IP %v",
+					addrMatcher.IP)
+			}
+			ips = []net.IP{ip}
 		}
 		if len(addrMatcher.ToPorts) > 1 {
 			// If there are multiple ports, then the ports must be named.
@@ -166,19 +378,27 @@ func getSanitizedLRPConfig(name, namespace string, uid types.UID, spec v2.Cilium
 		} else if len(addrMatcher.ToPorts) == 1 {
 			frontendType = addrFrontendSinglePort
 		}
-		feMappings = make([]*feMapping, len(addrMatcher.ToPorts))
-		for i, portInfo := range addrMatcher.ToPorts {
+		feMappings = make([]*feMapping, 0, len(addrMatcher.ToPorts)*len(ips))
+		for _, portInfo := range addrMatcher.ToPorts {
 			p, pName, proto, err := portInfo.SanitizePortInfo(checkNamedPort)
 			if err != nil {
 				return nil, fmt.Errorf("invalid address matcher port %v", err)
 			}
-			// Set the scope to ScopeExternal as the externalTrafficPolicy is set to Cluster.
-			fe = loadbalancer.NewL3n4Addr(proto, ip, p, loadbalancer.ScopeExternal)
-			feM := &feMapping{
-				feAddr: fe,
-				fePort: pName,
+			// Whether an ANY (wildcard) proto is actually allowed here
+			// depends on how the backend ports are specified below, so the
+			// check is deferred to expandWildcardProtocolMappings, once
+			// those are known.
+			for _, ip := range ips {
+				// Set the scope to ScopeExternal as the externalTrafficPolicy is set to Cluster.
+				fe = loadbalancer.NewL3n4Addr(proto, ip, p, loadbalancer.ScopeExternal)
+				feM := &feMapping{
+					feAddr:          fe,
+					fePort:          pName,
+					ifName:          ifName,
+					resolveFromNode: resolveFromNode,
+				}
+				feMappings = append(feMappings, feM)
 			}
-			feMappings[i] = feM
 		}
 		lrpType = lrpConfigTypeAddr
 	case svcMatcher != nil:
@@ -204,6 +424,13 @@ func getSanitizedLRPConfig(name, namespace string, uid types.UID, spec v2.Cilium
 			frontendType = svcFrontendNamedPorts
 			checkNamedPort = true
 		}
+		if svcMatcher.RedirectEndpoints {
+			if frontendType == svcFrontendAll {
+				return nil, fmt.Errorf("redirectEndpoints requires toPorts to be set, " +
+					"since a headless service has no ClusterIP frontend port to enumerate")
+			}
+			resolveHeadlessEndpoints = true
+		}
 		feMappings = make([]*feMapping, len(svcMatcher.ToPorts))
 		for i, portInfo := range svcMatcher.ToPorts {
 			p, pName, proto, err := portInfo.SanitizePortInfo(checkNamedPort)
@@ -228,6 +455,11 @@ func getSanitizedLRPConfig(name, namespace string, uid types.UID, spec v2.Cilium
 		return nil, fmt.Errorf("invalid local redirect policy %v", spec)
 	}
 
+	if redirectTo.BackendNamespace != "" && lrpType == lrpConfigTypeSvc {
+		return nil, fmt.Errorf("backendNamespace is not supported for a service matcher policy; " +
+			"its backends must stay in the namespace of the redirected service")
+	}
+
 	// Parse backend config
 	bePorts = make([]bePortInfo, len(redirectTo.ToPorts))
 	if len(redirectTo.ToPorts) > 1 {
@@ -240,6 +472,9 @@ func getSanitizedLRPConfig(name, namespace string, uid types.UID, spec v2.Cilium
 		if err != nil {
 			return nil, fmt.Errorf("invalid backend port %v", err)
 		}
+		if proto == loadbalancer.NONE {
+			return nil, fmt.Errorf("wildcard protocol ANY is only valid for a frontend port")
+		}
 		beP := bePortInfo{
 			l4Addr: lb.L4Addr{
 				Protocol: proto,
@@ -255,26 +490,75 @@ func getSanitizedLRPConfig(name, namespace string, uid types.UID, spec v2.Cilium
 		}
 	}
 	// When a single port is specified in the LRP frontend, the protocol for frontend and
-	// backend must match.
+	// backend must match, unless the frontend protocol is the ANY wildcard, in which case
+	// it matches backends of any protocol.
 	if len(feMappings) == 1 {
-		if bePorts[0].l4Addr.Protocol != feMappings[0].feAddr.Protocol {
+		fePort := feMappings[0].feAddr.Protocol
+		if fePort != loadbalancer.NONE && bePorts[0].l4Addr.Protocol != fePort {
 			return nil, fmt.Errorf("backend protocol must match with " +
 				"frontend protocol")
 		}
 	}
 
+	if lrpType == lrpConfigTypeAddr {
+		expanded, err := expandWildcardProtocolMappings(feMappings, frontendType, bePorts)
+		if err != nil {
+			return nil, err
+		}
+		feMappings = expanded
+	}
+
 	// Get an EndpointSelector from the passed policy labelSelector for optimized matching.
 	selector := api.NewESFromK8sLabelSelector("", &redirectTo.LocalEndpointSelector)
 
+	var excludeSelector api.EndpointSelector
+	if redirectTo.BackendExcludeSelector != nil {
+		if err := validateBackendExcludeSelector(&redirectTo.LocalEndpointSelector, redirectTo.BackendExcludeSelector); err != nil {
+			return nil, err
+		}
+		excludeSelector = api.NewESFromK8sLabelSelector("", redirectTo.BackendExcludeSelector)
+	}
+
+	var annotationSelector api.EndpointSelector
+	if redirectTo.BackendAnnotationSelector != nil {
+		annotationSelector = api.NewESFromK8sLabelSelector("", redirectTo.BackendAnnotationSelector)
+	}
+
+	fieldSelector, err := parseBackendFieldSelector(redirectTo.FieldSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	if redirectTo.MaxBackends < 0 {
+		return nil, fmt.Errorf("maxBackends must not be negative")
+	}
+
+	if redirectTo.RestartGracePeriodSeconds < 0 {
+		return nil, fmt.Errorf("restartGracePeriodSeconds must not be negative")
+	}
+
+	if redirectTo.DeleteDrainPeriodSeconds < 0 {
+		return nil, fmt.Errorf("deleteDrainPeriodSeconds must not be negative")
+	}
+
 	return &LRPConfig{
-		uid:                    uid,
-		serviceID:              k8sSvc,
-		frontendMappings:       feMappings,
-		backendSelector:        selector,
-		backendPorts:           bePorts,
-		backendPortsByPortName: bePortsMap,
-		lrpType:                lrpType,
-		frontendType:           frontendType,
+		uid:                       uid,
+		serviceID:                 k8sSvc,
+		frontendMappings:          feMappings,
+		backendSelector:           selector,
+		backendExcludeSelector:    excludeSelector,
+		backendAnnotationSelector: annotationSelector,
+		backendFieldSelector:      fieldSelector,
+		backendNamespace:          redirectTo.BackendNamespace,
+		allowHostNetworkBackends:  redirectTo.AllowHostNetworkBackends,
+		backendPorts:              bePorts,
+		backendPortsByPortName:    bePortsMap,
+		lrpType:                   lrpType,
+		frontendType:              frontendType,
+		maxBackends:               redirectTo.MaxBackends,
+		restartGracePeriod:        time.Duration(redirectTo.RestartGracePeriodSeconds) * time.Second,
+		deleteDrainPeriod:         time.Duration(redirectTo.DeleteDrainPeriodSeconds) * time.Second,
+		resolveHeadlessEndpoints:  resolveHeadlessEndpoints,
 		id: k8s.ServiceID{
 			Name:      name,
 			Namespace: namespace,
@@ -282,9 +566,176 @@ func getSanitizedLRPConfig(name, namespace string, uid types.UID, spec v2.Cilium
 	}, nil
 }
 
+// parseBackendFieldSelector parses raw as a Kubernetes field selector
+// restricted to supportedBackendFieldSelectors, returning a nil Selector if
+// raw is empty.
+func parseBackendFieldSelector(raw string) (fields.Selector, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	selector, err := fields.ParseSelector(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backend field selector %q: %w", raw, err)
+	}
+	for _, req := range selector.Requirements() {
+		if _, ok := supportedBackendFieldSelectors[req.Field]; !ok {
+			return nil, fmt.Errorf("unsupported backend field selector field %q", req.Field)
+		}
+	}
+	return selector, nil
+}
+
+// validateBackendExcludeSelector rejects an exclude selector whose
+// MatchLabels are a subset of (or equal to) include's, since every pod
+// satisfying include's constraints would then also satisfy exclude's, and
+// no backend could ever be selected. MatchExpressions aren't considered;
+// this is a best-effort check against the common case of label equality,
+// not a full selector-satisfiability solver.
+func validateBackendExcludeSelector(include, exclude *slim_metav1.LabelSelector) error {
+	for k, v := range exclude.MatchLabels {
+		if include.MatchLabels[k] != v {
+			return nil
+		}
+	}
+	return fmt.Errorf("backendExcludeSelector %v fully overlaps with localEndpointSelector %v; "+
+		"it would exclude every selected backend", exclude, include)
+}
+
+// validateWildcardProtocolFrontend returns an error if proto is the ANY
+// wildcard protocol and the configured datapath mode can't support a
+// protocol-agnostic frontend. Only the veth datapath mode's tc-based
+// redirection is protocol-agnostic; ipvlan mode is not.
+func validateWildcardProtocolFrontend(proto lb.L4Type) error {
+	if proto != loadbalancer.NONE {
+		return nil
+	}
+	if option.Config.DatapathMode != datapathOption.DatapathModeVeth {
+		return fmt.Errorf("wildcard protocol ANY frontend requires %q datapath mode, got %q",
+			datapathOption.DatapathModeVeth, option.Config.DatapathMode)
+	}
+	return nil
+}
+
+// expandWildcardProtocolMappings expands each ANY (wildcard) protocol
+// frontend mapping in feMappings into separate TCP and UDP mappings when the
+// backend declares one port for each protocol (e.g. DNS, which is served
+// over both), so traffic for each protocol is redirected through its own
+// concrete frontend rather than relying on the datapath to be
+// protocol-agnostic. It only does so for a single-port frontend; a wildcard
+// mapping within a named-ports frontend, or backed by a single backend port
+// regardless of protocol, keeps the existing tc-based semantics enforced by
+// validateWildcardProtocolFrontend.
+func expandWildcardProtocolMappings(feMappings []*feMapping, frontendType frontendConfigType, bePorts []bePortInfo) ([]*feMapping, error) {
+	expanded := make([]*feMapping, 0, len(feMappings))
+	for _, feM := range feMappings {
+		if feM.feAddr.Protocol != loadbalancer.NONE {
+			expanded = append(expanded, feM)
+			continue
+		}
+		if frontendType != addrFrontendSinglePort || len(bePorts) == 1 {
+			if err := validateWildcardProtocolFrontend(loadbalancer.NONE); err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, feM)
+			continue
+		}
+		tcpIdx, udpIdx, err := tcpAndUDPBackendPortIndexes(bePorts)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded,
+			wildcardProtocolMapping(feM, loadbalancer.TCP, tcpIdx),
+			wildcardProtocolMapping(feM, loadbalancer.UDP, udpIdx),
+		)
+	}
+	return expanded, nil
+}
+
+// tcpAndUDPBackendPortIndexes returns the indexes into bePorts of its single
+// TCP port and its single UDP port, or an error if bePorts doesn't contain
+// exactly one of each.
+func tcpAndUDPBackendPortIndexes(bePorts []bePortInfo) (tcpIdx, udpIdx int, err error) {
+	tcpIdx, udpIdx = -1, -1
+	for i := range bePorts {
+		switch bePorts[i].l4Addr.Protocol {
+		case loadbalancer.TCP:
+			if tcpIdx != -1 {
+				return 0, 0, fmt.Errorf("wildcard protocol ANY frontend with multiple backend ports requires exactly one TCP port")
+			}
+			tcpIdx = i
+		case loadbalancer.UDP:
+			if udpIdx != -1 {
+				return 0, 0, fmt.Errorf("wildcard protocol ANY frontend with multiple backend ports requires exactly one UDP port")
+			}
+			udpIdx = i
+		default:
+			return 0, 0, fmt.Errorf("wildcard protocol ANY frontend with multiple backend ports requires each to be TCP or UDP")
+		}
+	}
+	if tcpIdx == -1 || udpIdx == -1 {
+		return 0, 0, fmt.Errorf("wildcard protocol ANY frontend with multiple backend ports requires one TCP port and one UDP port")
+	}
+	return tcpIdx, udpIdx, nil
+}
+
+// wildcardProtocolMapping returns a copy of feM with its frontend address's
+// protocol set to proto and paired with the backend port at bePortIndex, for
+// one of the concrete mappings a wildcard ANY frontend port expands into.
+func wildcardProtocolMapping(feM *feMapping, proto lb.L4Type, bePortIndex int) *feMapping {
+	fe := *feM.feAddr
+	fe.Protocol = proto
+	return &feMapping{
+		feAddr:          &fe,
+		fePort:          feM.fePort,
+		ifName:          feM.ifName,
+		resolveFromNode: feM.resolveFromNode,
+		bePortIndex:     bePortIndex,
+	}
+}
+
 // policyConfigSelectsPod determines if the given pod is selected by the policy
-// config based on matching labels of config and pod.
+// config based on matching labels of config and pod, and, if set, the
+// policy's serviceAccount constraint. A pod that isn't Ready yet (e.g. still
+// starting) is never selected. A terminating pod (DeletionTimestamp set) is
+// only selected when the config opts into includeTerminatingBackends, and
+// even then only as a fallback -- callers must additionally confirm no ready
+// backend remains before admitting a terminating pod as a backend. A
+// hostNetwork pod is never selected unless the config opts into
+// allowHostNetworkBackends, since its pod IP is the node's own address and
+// may already be a frontend; this default-exclusion only applies when the
+// config has no backendFieldSelector, since the only field that selector
+// supports is spec.hostNetwork, so a policy that sets one has already made
+// an explicit choice about hostNetwork pods. A pod matching
+// backendExcludeSelector is never selected, even if it matches
+// backendSelector. If backendAnnotationSelector is set, a pod must also
+// match it against its annotations, in addition to backendSelector against
+// its labels.
 func (config *LRPConfig) policyConfigSelectsPod(podInfo *podMetadata) bool {
+	if !config.selectsBackendNamespace(podInfo.id.Namespace) {
+		return false
+	}
+	if podInfo.hostNetwork && !config.allowHostNetworkBackends && config.backendFieldSelector == nil {
+		return false
+	}
+	if config.serviceAccount != "" && config.serviceAccount != podInfo.serviceAccount {
+		return false
+	}
+	if podInfo.terminating {
+		if !config.includeTerminatingBackends {
+			return false
+		}
+	} else if !podInfo.ready {
+		return false
+	}
+	if config.backendFieldSelector != nil && !config.backendFieldSelector.Matches(podInfo.fieldSet()) {
+		return false
+	}
+	if config.backendExcludeSelector.LabelSelector != nil && config.backendExcludeSelector.Matches(labels.Set(podInfo.labels)) {
+		return false
+	}
+	if config.backendAnnotationSelector.LabelSelector != nil && !config.backendAnnotationSelector.Matches(labels.Set(podInfo.annotations)) {
+		return false
+	}
 	return config.backendSelector.Matches(labels.Set(podInfo.labels))
 }
 
@@ -296,3 +747,13 @@ func (config *LRPConfig) checkNamespace(namespace string) bool {
 	}
 	return true
 }
+
+// selectsBackendNamespace returns true if namespace is the namespace backend
+// pods are selected from: backendNamespace when the policy explicitly set
+// one, or the policy's own namespace (via checkNamespace) otherwise.
+func (config *LRPConfig) selectsBackendNamespace(namespace string) bool {
+	if config.backendNamespace != "" {
+		return namespace == config.backendNamespace
+	}
+	return config.checkNamespace(namespace)
+}
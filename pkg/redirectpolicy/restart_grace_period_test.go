@@ -0,0 +1,159 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+	"github.com/cilium/cilium/pkg/testutils"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+func newRestartGracePeriodTestPod(ip string) *slimcorev1.Pod {
+	return &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo-pod",
+			Namespace: "default",
+			UID:       "uid-1",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  ip,
+			PodIPs: []slimcorev1.PodIP{{IP: ip}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+}
+
+// TestRestartGracePeriodHoldsServiceAcrossQuickPodRecreation verifies that a
+// policy with a restartGracePeriod doesn't tear down its service when its
+// only backend pod is deleted and a same-named replacement is added again
+// before the grace period elapses.
+func TestRestartGracePeriodHoldsServiceAcrossQuickPodRecreation(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := LRPConfig{
+		id:           policyID{Name: "test", Namespace: "default"},
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		}},
+		backendSelector: selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+		restartGracePeriod: time.Hour,
+	}
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	pod := newRestartGracePeriodTestPod("10.1.1.1")
+	if err := podStore.Add(pod); err != nil {
+		t.Fatalf("failed to add pod to store: %v", err)
+	}
+	rpm.OnAddPod(pod)
+	if svc.upsertCount != 1 {
+		t.Fatalf("expected 1 upsert after the initial pod is added, got %d", svc.upsertCount)
+	}
+
+	// The pod is deleted (e.g. crash restart); because of its policy's
+	// restartGracePeriod, the service must not be torn down immediately.
+	podStore.Delete(pod)
+	rpm.OnDeletePod(pod)
+	if svc.deleteCount != 0 {
+		t.Fatalf("expected no service deletion within the restart grace period, got %d deletions", svc.deleteCount)
+	}
+
+	// A same-named replacement pod, with a new UID, reappears before the
+	// grace period elapses: the pending deletion must be canceled and the
+	// service's backend replaced in place.
+	replacement := newRestartGracePeriodTestPod("10.1.1.2")
+	replacement.UID = "uid-2"
+	if err := podStore.Add(replacement); err != nil {
+		t.Fatalf("failed to add replacement pod to store: %v", err)
+	}
+	rpm.OnAddPod(replacement)
+
+	if svc.deleteCount != 0 {
+		t.Fatalf("expected no service deletion after the pod was recreated in time, got %d deletions", svc.deleteCount)
+	}
+	if svc.upsertCount != 2 {
+		t.Fatalf("expected a second upsert for the replacement pod's backend, got %d", svc.upsertCount)
+	}
+	if svc.lastUpserted == nil || len(svc.lastUpserted.Backends) != 1 || svc.lastUpserted.Backends[0].IP.String() != "10.1.1.2" {
+		t.Fatalf("expected the service to now be backed by the replacement pod's IP, got %+v", svc.lastUpserted)
+	}
+}
+
+// TestRestartGracePeriodDeletesServiceAfterGracePeriodElapses verifies that,
+// absent a timely replacement, the grace-period timer started by OnDeletePod
+// eventually removes the service as usual.
+func TestRestartGracePeriodDeletesServiceAfterGracePeriodElapses(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := LRPConfig{
+		id:           policyID{Name: "test", Namespace: "default"},
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		}},
+		backendSelector: selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+		restartGracePeriod: time.Millisecond,
+	}
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	pod := newRestartGracePeriodTestPod("10.1.1.1")
+	if err := podStore.Add(pod); err != nil {
+		t.Fatalf("failed to add pod to store: %v", err)
+	}
+	rpm.OnAddPod(pod)
+
+	podStore.Delete(pod)
+	rpm.OnDeletePod(pod)
+
+	err := testutils.WaitUntil(func() bool {
+		rpm.mutex.RLock()
+		defer rpm.mutex.RUnlock()
+		return svc.deleteCount == 1
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("expected the service to be deleted once the grace period elapsed: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestResyncConvergesToCurrentPodStore verifies that Resync recomputes a
+// policy's backends from the current pod store rather than trusting
+// previously recorded state: a pod that has disappeared from the pod store
+// without its delete event ever reaching the manager must no longer back
+// the policy after Resync, and its now-backend-less frontend must be torn
+// down.
+func TestResyncConvergesToCurrentPodStore(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	addTestPod(t, podStore, "foo-pod", "10.1.1.1", map[string]string{"app": "foo"})
+
+	id := policyID{Name: "test", Namespace: "default"}
+	config := LRPConfig{
+		id:           id,
+		lrpType:      lrpConfigTypeAddr,
+		frontendType: addrFrontendSinglePort,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+		}},
+		backendSelector: api.NewESFromK8sLabelSelector("",
+			&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}}),
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	if got := rpm.GetPolicyBackends(id); len(got["10.0.0.1:80"]) != 1 {
+		t.Fatalf("expected one backend after the initial apply, got %v", got)
+	}
+
+	// Simulate a missed pod-delete event: the pod vanishes from the store
+	// without OnDeletePod ever being called, so the manager's recorded
+	// backend for it goes stale.
+	for _, item := range podStore.List() {
+		if err := podStore.Delete(item); err != nil {
+			t.Fatalf("failed to remove pod from the store: %v", err)
+		}
+	}
+
+	rpm.Resync(nil, podStore)
+
+	got := rpm.GetPolicyBackends(id)
+	if len(got["10.0.0.1:80"]) != 0 {
+		t.Fatalf("expected no backends to remain after Resync, got %v", got)
+	}
+	if svc.deleteCount == 0 {
+		t.Fatalf("expected the now-backend-less frontend to be torn down")
+	}
+}
@@ -0,0 +1,83 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/option"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestSelectedButUnbackedV6PodUnderV4OnlyConfig(t *testing.T) {
+	origV6 := option.Config.EnableIPv6
+	option.Config.EnableIPv6 = false
+	defer func() { option.Config.EnableIPv6 = origV6 }()
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+
+	id := policyID{Name: "test", Namespace: "default"}
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+	config := LRPConfig{
+		id:               id,
+		lrpType:          lrpConfigTypeAddr,
+		frontendType:     addrFrontendSinglePort,
+		frontendMappings: []*feMapping{feM},
+		backendSelector:  selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+	rpm.storePolicyConfig(config)
+
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "v6-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP: "fd00::1",
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	podStore.Add(pod)
+
+	unbacked := rpm.SelectedButUnbacked(id, podStore)
+	if len(unbacked) != 1 {
+		t.Fatalf("expected 1 unbacked pod, got %d: %+v", len(unbacked), unbacked)
+	}
+	if unbacked[0].PodID.Name != "v6-pod" {
+		t.Errorf("unexpected pod reported: %+v", unbacked[0])
+	}
+	if unbacked[0].Reason == "" {
+		t.Error("expected a non-empty reason")
+	}
+	t.Logf("reason: %s", unbacked[0].Reason)
+}
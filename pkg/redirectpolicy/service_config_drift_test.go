@@ -0,0 +1,103 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"strings"
+	"testing"
+
+	fakeDatapath "github.com/cilium/cilium/pkg/datapath/fake"
+	"github.com/cilium/cilium/pkg/k8s"
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/lock"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestOnAddServiceSurvivesPolicyServicesDrift verifies that OnAddService
+// doesn't panic when policyServices references a policy ID no longer present
+// in policyConfigs -- which can happen if the two maps are ever updated
+// non-atomically, e.g. during a concurrent delete -- and instead logs a
+// warning and drops the stale entry.
+func TestOnAddServiceSurvivesPolicyServicesDrift(t *testing.T) {
+	hook := &recordingHook{levels: []logrus.Level{logrus.WarnLevel}}
+	log.Logger.AddHook(hook)
+	defer func() {
+		hooks := log.Logger.Hooks[logrus.WarnLevel]
+		for i, h := range hooks {
+			if h == hook {
+				log.Logger.Hooks[logrus.WarnLevel] = append(hooks[:i], hooks[i+1:]...)
+				break
+			}
+		}
+	}()
+
+	svcCache := k8s.NewServiceCache(fakeDatapath.NewNodeAddressing())
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	svcID := k8s.ServiceID{Name: "foo", Namespace: "bar"}
+	config := &LRPConfig{
+		id:              policyID{Name: "svc-lrp", Namespace: "bar"},
+		lrpType:         lrpConfigTypeSvc,
+		frontendType:    svcFrontendAll,
+		serviceID:       &svcID,
+		backendSelector: selector,
+	}
+
+	rpm.storePolicyConfig(*config)
+	delete(rpm.policyConfigs, config.id)
+
+	// reconcileServiceConfigLocked short-circuits entirely when policyConfigs
+	// is empty, so an unrelated policy must remain for the drift branch to
+	// be reached at all.
+	otherConfig := &LRPConfig{
+		id:      policyID{Name: "other-lrp", Namespace: "bar"},
+		lrpType: lrpConfigTypeAddr,
+	}
+	rpm.storePolicyConfig(*otherConfig)
+
+	k8sSvc := &slimcorev1.Service{
+		ObjectMeta: slim_metav1.ObjectMeta{Name: "foo", Namespace: "bar"},
+		Spec: slimcorev1.ServiceSpec{
+			ClusterIP: "172.0.20.1",
+			Type:      slimcorev1.ServiceTypeClusterIP,
+			Ports:     []slimcorev1.ServicePort{{Port: 80, Protocol: slimcorev1.ProtocolTCP}},
+		},
+	}
+	gotSvcID := svcCache.UpdateService(k8sSvc, lock.NewStoppableWaitGroup())
+
+	rpm.OnAddService(gotSvcID, &svcCache, podStore)
+
+	if _, ok := rpm.policyServices[svcID]; ok {
+		t.Fatal("expected the stale policyServices entry to be dropped")
+	}
+
+	found := false
+	for _, entry := range hook.entries {
+		if strings.Contains(entry.Message, "policyConfigs") {
+			found = true
+			break
+		}
+	}
+	if !found {
+		t.Fatal("expected a warning about the policyServices/policyConfigs drift to be logged")
+	}
+}
@@ -0,0 +1,73 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"testing"
+
+	fakeDatapath "github.com/cilium/cilium/pkg/datapath/fake"
+	"github.com/cilium/cilium/pkg/k8s"
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/lock"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestGetAndUpsertPolicySvcConfigPropagatesSessionAffinity(t *testing.T) {
+	svcCache := k8s.NewServiceCache(fakeDatapath.NewNodeAddressing())
+
+	timeout := int32(120)
+	k8sSvc := &slimcorev1.Service{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Spec: slimcorev1.ServiceSpec{
+			ClusterIP:       "172.0.20.1",
+			Type:            slimcorev1.ServiceTypeClusterIP,
+			SessionAffinity: slimcorev1.ServiceAffinityClientIP,
+			SessionAffinityConfig: &slimcorev1.SessionAffinityConfig{
+				ClientIP: &slimcorev1.ClientIPConfig{TimeoutSeconds: &timeout},
+			},
+			Ports: []slimcorev1.ServicePort{
+				{Port: 80, Protocol: slimcorev1.ProtocolTCP},
+			},
+		},
+	}
+	svcCache.UpdateService(k8sSvc, lock.NewStoppableWaitGroup())
+
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	svcID := k8s.ServiceID{Name: "foo", Namespace: "bar"}
+	config := &LRPConfig{
+		id:           policyID{Name: "lrp", Namespace: "bar"},
+		lrpType:      lrpConfigTypeSvc,
+		frontendType: svcFrontendAll,
+		serviceID:    &svcID,
+	}
+	rpm.storePolicyConfig(*config)
+
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	rpm.getAndUpsertPolicySvcConfig(rpm.policyConfigs[config.id], &svcCache, podStore)
+
+	stored := rpm.policyConfigs[config.id]
+	if !stored.sessionAffinity {
+		t.Fatal("expected the LRP config to carry the service's session affinity")
+	}
+	if stored.sessionAffinityTimeoutSec != 120 {
+		t.Errorf("expected affinity timeout 120, got %d", stored.sessionAffinityTimeoutSec)
+	}
+}
@@ -0,0 +1,52 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestAddRedirectPolicyAfterCloseIsRejected verifies that AddRedirectPolicy
+// called after Close returns errManagerClosed and leaves the manager's
+// state untouched, rather than mutating a manager that's shutting down.
+func TestAddRedirectPolicyAfterCloseIsRejected(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	rpm.Close()
+
+	config := LRPConfig{
+		id:      policyID{Name: "closed-lrp", Namespace: "default"},
+		lrpType: lrpConfigTypeAddr,
+		frontendMappings: []*feMapping{{
+			feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("172.0.20.1"), 80, lb.ScopeExternal),
+		}},
+	}
+
+	ok, err := rpm.AddRedirectPolicy(config, nil, cache.NewStore(cache.MetaNamespaceKeyFunc))
+	if ok || err != errManagerClosed {
+		t.Fatalf("expected AddRedirectPolicy to be rejected with errManagerClosed, got ok=%v err=%v", ok, err)
+	}
+
+	if len(rpm.policyConfigs) != 0 {
+		t.Fatalf("expected no state mutation after a rejected AddRedirectPolicy, got %d stored configs", len(rpm.policyConfigs))
+	}
+	if svc.upsertCount != 0 {
+		t.Fatalf("expected no service upserts after a rejected AddRedirectPolicy, got %d", svc.upsertCount)
+	}
+}
@@ -0,0 +1,80 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+)
+
+func TestResetStats(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+
+	config := LRPConfig{
+		id:      policyID{Name: "test", Namespace: "default"},
+		lrpType: lrpConfigTypeAddr,
+	}
+	rpm.storePolicyConfig(config)
+	stored := rpm.policyConfigs[config.id]
+
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+	pod := podID{Name: "a", Namespace: "default"}
+	be1 := backend{IP: net.ParseIP("10.1.1.1"), L4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}}
+	be2 := backend{IP: net.ParseIP("10.1.1.2"), L4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}}
+
+	// Two events, each introducing a genuinely different backend set, so
+	// both the event count and the churn count advance.
+	rpm.upsertConfig(stored)
+	rpm.upsertServiceWithBackends(stored, feM, pod, "uid-a", true, 0, "", "", []backend{be1})
+	rpm.upsertConfig(stored)
+	rpm.upsertServiceWithBackends(stored, feM, pod, "uid-a", true, 0, "", "", []backend{be2})
+
+	stats := rpm.policyStats[config.id]
+	if stats.eventCount != 2 {
+		t.Fatalf("expected event count 2, got %d", stats.eventCount)
+	}
+	if stats.backendChurn != 2 {
+		t.Fatalf("expected backend churn 2, got %d", stats.backendChurn)
+	}
+	if stats.lastUpdated.IsZero() {
+		t.Fatal("expected lastUpdated to be set")
+	}
+
+	if err := rpm.ResetStats(config.id); err != nil {
+		t.Fatalf("ResetStats failed: %v", err)
+	}
+
+	stats = rpm.policyStats[config.id]
+	if stats.eventCount != 0 || stats.backendChurn != 0 || !stats.lastUpdated.IsZero() {
+		t.Fatalf("expected counters to be reset, got %+v", stats)
+	}
+
+	// Programmed state must be untouched by the reset.
+	if len(feM.backends) != 1 || feM.backends[0].IP.String() != "10.1.1.2" {
+		t.Fatalf("expected backends to remain programmed after reset, got %v", feM.backends)
+	}
+	if svc.upsertCount != 2 {
+		t.Fatalf("expected 2 upserts from the programmed backend changes, got %d", svc.upsertCount)
+	}
+
+	if err := rpm.ResetStats(policyID{Name: "missing", Namespace: "default"}); err == nil {
+		t.Fatal("expected an error when resetting stats for an unknown policy")
+	}
+}
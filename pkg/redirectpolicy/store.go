@@ -0,0 +1,223 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+
+	"github.com/cilium/cilium/pkg/kvstore"
+	"github.com/cilium/cilium/pkg/kvstore/store"
+	"github.com/cilium/cilium/pkg/logging/logfields"
+)
+
+// BackendSnapshotStorePrefix is the kvstore prefix of the shared store used
+// to persist policy backend snapshots, mirroring the NodeStorePrefix
+// convention in pkg/node/store.
+//
+// WARNING - STABLE API: Changing the structure or values of this will break
+// backwards compatibility.
+var BackendSnapshotStorePrefix = path.Join(kvstore.BaseKeyPrefix, "state", "localredirectpolicies", "v1")
+
+// BackendSnapshotKeyCreator creates an empty policyBackendSnapshot for a
+// shared store to decode an entry into, for use as a store.Configuration's
+// KeyCreator.
+var BackendSnapshotKeyCreator = func() store.Key {
+	return &policyBackendSnapshot{}
+}
+
+// frontendBackendSnapshot is the persisted backend state of a single
+// frontend mapping belonging to a policy.
+type frontendBackendSnapshot struct {
+	// FrontendHash identifies the frontend mapping this snapshot belongs to,
+	// using the same hash frontendHash derives from a feMapping's feAddr, so
+	// it can be matched back up against a restored LRPConfig's
+	// frontendMappings without relying on slice order.
+	FrontendHash string
+	Backends     []backend
+}
+
+// policyBackendSnapshot is the kvstore representation of a single local
+// redirect policy's last-known resolved backends, keyed by policy
+// namespace/name. It implements store.LocalKey so it can be saved and
+// restored through a store.SharedStore, the same pattern pkg/node/store uses
+// for node objects.
+type policyBackendSnapshot struct {
+	PolicyNamespace string
+	PolicyName      string
+	Frontends       []frontendBackendSnapshot
+}
+
+// GetKeyName returns the kvstore key to use for the snapshot, namespace and
+// name joined so it's unique across policies and stable across restarts.
+func (p *policyBackendSnapshot) GetKeyName() string {
+	return path.Join(p.PolicyNamespace, p.PolicyName)
+}
+
+// DeepKeyCopy creates a deep copy of the snapshot.
+func (p *policyBackendSnapshot) DeepKeyCopy() store.LocalKey {
+	out := &policyBackendSnapshot{
+		PolicyNamespace: p.PolicyNamespace,
+		PolicyName:      p.PolicyName,
+		Frontends:       make([]frontendBackendSnapshot, len(p.Frontends)),
+	}
+	for i, feSnap := range p.Frontends {
+		out.Frontends[i] = frontendBackendSnapshot{
+			FrontendHash: feSnap.FrontendHash,
+			Backends:     append([]backend{}, feSnap.Backends...),
+		}
+	}
+	return out
+}
+
+// Marshal returns the snapshot as a JSON byte slice.
+func (p *policyBackendSnapshot) Marshal() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// Unmarshal decodes a JSON byte slice produced by Marshal back into p.
+func (p *policyBackendSnapshot) Unmarshal(data []byte) error {
+	return json.Unmarshal(data, p)
+}
+
+// backendSnapshotStore is the subset of *store.SharedStore's behavior the
+// manager needs to save and restore policy backend snapshots, narrowed down
+// so tests can substitute a fake in-memory store instead of joining a real
+// kvstore-backed SharedStore.
+type backendSnapshotStore interface {
+	UpdateLocalKeySync(ctx context.Context, key store.LocalKey) error
+	DeleteLocalKey(ctx context.Context, key store.NamedKey)
+	SharedKeysMap() map[string]store.Key
+}
+
+// WithBackendSnapshotStore configures s to receive a snapshot of each
+// policy's resolved backends whenever it's successfully installed or
+// updated, and to be consulted by RestorePolicyBackends at startup. By
+// default (no option) the manager neither persists nor restores backend
+// snapshots.
+func WithBackendSnapshotStore(s backendSnapshotStore) ManagerOption {
+	return func(rpm *Manager) {
+		rpm.backendSnapshotStore = s
+	}
+}
+
+// snapshotToKey builds the kvstore key representing config's current
+// frontend-to-backend assignments.
+func snapshotToKey(config *LRPConfig) *policyBackendSnapshot {
+	snap := &policyBackendSnapshot{
+		PolicyNamespace: config.id.Namespace,
+		PolicyName:      config.id.Name,
+		Frontends:       make([]frontendBackendSnapshot, 0, len(config.frontendMappings)),
+	}
+	for _, feM := range config.frontendMappings {
+		snap.Frontends = append(snap.Frontends, frontendBackendSnapshot{
+			FrontendHash: frontendHash(feM.feAddr),
+			Backends:     append([]backend{}, feM.backends...),
+		})
+	}
+	return snap
+}
+
+// snapshotPolicy persists config's current backends to the configured
+// backendSnapshotStore, if any. It's a no-op while config has no backends at
+// all, rather than persisting an empty snapshot: the gap between a policy
+// being re-added on startup and its first real pod event is exactly when
+// RestorePolicyBackends needs a still-intact snapshot from before the
+// restart to read, and an empty save here would otherwise race it and wipe
+// that out before it's used. It's also a best-effort save: a
+// synchronization failure is logged but otherwise ignored, since losing a
+// snapshot only means a slower recovery on the next restart, not incorrect
+// behavior now. The caller must hold rpm.mutex.
+func (rpm *Manager) snapshotPolicy(config *LRPConfig) {
+	if rpm.backendSnapshotStore == nil || totalBackends(config) == 0 {
+		return
+	}
+	if err := rpm.backendSnapshotStore.UpdateLocalKeySync(context.Background(), snapshotToKey(config)); err != nil {
+		log.WithError(err).WithField(logfields.LRPName, config.id).Warning("Failed to persist local redirect policy backend snapshot")
+	}
+}
+
+// deleteSnapshot removes any persisted backend snapshot for id from the
+// configured backendSnapshotStore, if any. The caller must hold rpm.mutex.
+func (rpm *Manager) deleteSnapshot(id policyID) {
+	if rpm.backendSnapshotStore == nil {
+		return
+	}
+	rpm.backendSnapshotStore.DeleteLocalKey(context.Background(), &policyBackendSnapshot{
+		PolicyNamespace: id.Namespace,
+		PolicyName:      id.Name,
+	})
+}
+
+// RestorePolicyBackends seeds a provisional set of backends, recovered from
+// the snapshot taken before this restart, into the frontend mappings of
+// every currently stored policy that doesn't have any backends yet --
+// typically one that was just re-added from the Kubernetes API but whose
+// selector hasn't matched any pod because the pod store hasn't finished its
+// initial sync.
+//
+// This only bridges that gap: the restored backends are installed as an LB
+// service entry directly, without touching policyPods or backendOwners, so
+// they're not mistaken for ones derived from a live pod. The normal backend
+// pipeline reconciles them away the moment real pod events arrive for the
+// policy, and a full Resync -- expected once the pod store's initial sync
+// completes -- discards any that are never confirmed by a live pod. Must be
+// called after every policy expected to be restored has already been added
+// via AddRedirectPolicy, and is a no-op unless WithBackendSnapshotStore was
+// used to configure the manager.
+func (rpm *Manager) RestorePolicyBackends() {
+	if rpm.backendSnapshotStore == nil {
+		return
+	}
+
+	rpm.mutex.Lock()
+	defer rpm.mutex.Unlock()
+
+	for _, k := range rpm.backendSnapshotStore.SharedKeysMap() {
+		snap, ok := k.(*policyBackendSnapshot)
+		if !ok {
+			continue
+		}
+		id := policyID{Namespace: snap.PolicyNamespace, Name: snap.PolicyName}
+		config, ok := rpm.policyConfigs[id]
+		if !ok || totalBackends(config) > 0 {
+			continue
+		}
+		rpm.restoreConfigBackends(config, snap)
+	}
+}
+
+// restoreConfigBackends seeds config's frontend mappings with the backends
+// recorded in snap, matching each frontend by the hash also used to index
+// policyFrontendsByHash, and upserts the resulting service. The caller must
+// hold rpm.mutex.
+func (rpm *Manager) restoreConfigBackends(config *LRPConfig, snap *policyBackendSnapshot) {
+	backendsByHash := make(map[string][]backend, len(snap.Frontends))
+	for _, feSnap := range snap.Frontends {
+		backendsByHash[feSnap.FrontendHash] = feSnap.Backends
+	}
+
+	for _, feM := range config.frontendMappings {
+		restored, ok := backendsByHash[frontendHash(feM.feAddr)]
+		if !ok || len(restored) == 0 {
+			continue
+		}
+		feM.backends = append([]backend{}, restored...)
+		rpm.upsertService(config, feM)
+	}
+
+	log.WithField(logfields.LRPName, config.id).Info("Restored local redirect policy backends from snapshot, pending reconciliation against live pods")
+}
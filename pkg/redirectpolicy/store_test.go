@@ -0,0 +1,217 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/kvstore/store"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// fakeBackendSnapshotStore is an in-memory backendSnapshotStore, so tests can
+// round-trip snapshots without a real kvstore.
+type fakeBackendSnapshotStore struct {
+	keys map[string]store.Key
+}
+
+func newFakeBackendSnapshotStore() *fakeBackendSnapshotStore {
+	return &fakeBackendSnapshotStore{keys: make(map[string]store.Key)}
+}
+
+func (f *fakeBackendSnapshotStore) UpdateLocalKeySync(ctx context.Context, key store.LocalKey) error {
+	f.keys[key.GetKeyName()] = key.DeepKeyCopy()
+	return nil
+}
+
+func (f *fakeBackendSnapshotStore) DeleteLocalKey(ctx context.Context, key store.NamedKey) {
+	delete(f.keys, key.GetKeyName())
+}
+
+func (f *fakeBackendSnapshotStore) SharedKeysMap() map[string]store.Key {
+	return f.keys
+}
+
+// TestSnapshotPolicyRoundTripsThroughFakeStore verifies that a policy's
+// backends, once installed, are persisted to the configured
+// backendSnapshotStore and can be read back in the exact shape
+// RestorePolicyBackends expects.
+func TestSnapshotPolicyRoundTripsThroughFakeStore(t *testing.T) {
+	fakeStore := newFakeBackendSnapshotStore()
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil, WithBackendSnapshotStore(fakeStore))
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := newNoBackendsTestConfig(selector)
+
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	if err := podStore.Add(pod); err != nil {
+		t.Fatalf("failed to add pod: %v", err)
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	key, ok := fakeStore.keys[snapshotKeyName(config.id.Namespace, config.id.Name)]
+	if !ok {
+		t.Fatalf("expected a snapshot to be persisted for policy %s", config.id)
+	}
+	snap := key.(*policyBackendSnapshot)
+	if len(snap.Frontends) != 1 || len(snap.Frontends[0].Backends) != 1 {
+		t.Fatalf("expected one frontend with one backend in the snapshot, got %+v", snap)
+	}
+	if !snap.Frontends[0].Backends[0].IP.Equal(net.ParseIP("10.1.1.1")) {
+		t.Fatalf("expected the snapshot to record the pod's IP, got %v", snap.Frontends[0].Backends[0].IP)
+	}
+
+	rpm.DeleteRedirectPolicy(config)
+	if _, ok := fakeStore.keys[snapshotKeyName(config.id.Namespace, config.id.Name)]; ok {
+		t.Fatal("expected the snapshot to be removed once the policy is deleted")
+	}
+}
+
+// snapshotKeyName mirrors policyBackendSnapshot.GetKeyName, so tests can look
+// a snapshot back up by policy namespace/name without hardcoding its
+// unexported key-name format.
+func snapshotKeyName(namespace, name string) string {
+	return (&policyBackendSnapshot{PolicyNamespace: namespace, PolicyName: name}).GetKeyName()
+}
+
+// TestRestorePolicyBackendsSeedsProvisionalBackends verifies that, given a
+// snapshot from before a restart, RestorePolicyBackends seeds a freshly
+// re-added policy's frontend mapping with the recorded backends before any
+// live pod has been observed, without touching policyPods.
+func TestRestorePolicyBackendsSeedsProvisionalBackends(t *testing.T) {
+	fakeStore := newFakeBackendSnapshotStore()
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := newNoBackendsTestConfig(selector)
+
+	restoredBackend := lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.1.1.1"), 8080, lb.ScopeExternal)
+	fakeStore.keys[snapshotKeyName(config.id.Namespace, config.id.Name)] = &policyBackendSnapshot{
+		PolicyNamespace: config.id.Namespace,
+		PolicyName:      config.id.Name,
+		Frontends: []frontendBackendSnapshot{{
+			FrontendHash: frontendHash(config.frontendMappings[0].feAddr),
+			Backends:     []backend{*restoredBackend},
+		}},
+	}
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil, WithBackendSnapshotStore(fakeStore))
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	// The pod store hasn't synced yet: AddRedirectPolicy stores the config
+	// with no backends.
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	if totalBackends(rpm.policyConfigs[config.id]) != 0 {
+		t.Fatalf("expected no backends before restore")
+	}
+
+	rpm.RestorePolicyBackends()
+
+	stored := rpm.policyConfigs[config.id]
+	backends := stored.frontendMappings[0].backends
+	if len(backends) != 1 || !backends[0].IP.Equal(net.ParseIP("10.1.1.1")) {
+		t.Fatalf("expected the restored backend to be seeded, got %v", backends)
+	}
+	if len(rpm.policyPods) != 0 {
+		t.Fatalf("expected restored backends to bypass policyPods bookkeeping, got %v", rpm.policyPods)
+	}
+}
+
+// TestRestorePolicyBackendsSkipsPoliciesWithLiveBackends verifies that
+// RestorePolicyBackends leaves a policy alone once it already has backends
+// derived from a live pod, so a stale snapshot can never clobber current
+// state.
+func TestRestorePolicyBackendsSkipsPoliciesWithLiveBackends(t *testing.T) {
+	fakeStore := newFakeBackendSnapshotStore()
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	config := newNoBackendsTestConfig(selector)
+
+	staleBackend := lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.1.1.9"), 8080, lb.ScopeExternal)
+	fakeStore.keys[snapshotKeyName(config.id.Namespace, config.id.Name)] = &policyBackendSnapshot{
+		PolicyNamespace: config.id.Namespace,
+		PolicyName:      config.id.Name,
+		Frontends: []frontendBackendSnapshot{{
+			FrontendHash: frontendHash(config.frontendMappings[0].feAddr),
+			Backends:     []backend{*staleBackend},
+		}},
+	}
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil, WithBackendSnapshotStore(fakeStore))
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "foo"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  "10.1.1.1",
+			PodIPs: []slimcorev1.PodIP{{IP: "10.1.1.1"}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	if err := podStore.Add(pod); err != nil {
+		t.Fatalf("failed to add pod: %v", err)
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	rpm.RestorePolicyBackends()
+
+	backends := rpm.policyConfigs[config.id].frontendMappings[0].backends
+	if len(backends) != 1 || !backends[0].IP.Equal(net.ParseIP("10.1.1.1")) {
+		t.Fatalf("expected the live pod's backend to be left untouched, got %v", backends)
+	}
+}
+
+// TestRestorePolicyBackendsNoopWithoutStore verifies that RestorePolicyBackends
+// is a no-op, rather than a panic, on a manager configured without a
+// backendSnapshotStore.
+func TestRestorePolicyBackendsNoopWithoutStore(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	rpm.RestorePolicyBackends()
+}
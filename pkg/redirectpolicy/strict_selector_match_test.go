@@ -0,0 +1,69 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"testing"
+
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestStrictSelectorMatchRejectsNeverMatchingSelector verifies that, with
+// WithStrictSelectorMatch enabled, AddRedirectPolicy returns an error and
+// records a SelectorMatchesNoPods warning event for a policy whose selector
+// matches zero pods.
+func TestStrictSelectorMatchRejectsNeverMatchingSelector(t *testing.T) {
+	recorder := &fakeEventRecorder{}
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, recorder, WithStrictSelectorMatch())
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "never-matches"}})
+	config := newNoBackendsTestConfig(selector)
+
+	ok, err := rpm.AddRedirectPolicy(config, nil, podStore)
+	if ok || err == nil {
+		t.Fatalf("expected AddRedirectPolicy to reject a never-matching selector under strict mode, got ok=%v err=%v", ok, err)
+	}
+
+	var sawWarning bool
+	for _, reason := range recorder.events {
+		if reason == "SelectorMatchesNoPods" {
+			sawWarning = true
+		}
+	}
+	if !sawWarning {
+		t.Fatalf("expected a SelectorMatchesNoPods event, got %v", recorder.events)
+	}
+}
+
+// TestStrictSelectorMatchDefaultIsLenient verifies that without
+// WithStrictSelectorMatch, a never-matching selector is still accepted, as
+// it was before strict mode was added.
+func TestStrictSelectorMatchDefaultIsLenient(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "never-matches"}})
+	config := newNoBackendsTestConfig(selector)
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("expected a never-matching selector to be accepted by default, got ok=%v err=%v", ok, err)
+	}
+}
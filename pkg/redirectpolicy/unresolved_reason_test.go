@@ -0,0 +1,141 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"strings"
+	"testing"
+
+	fakeDatapath "github.com/cilium/cilium/pkg/datapath/fake"
+	"github.com/cilium/cilium/pkg/k8s"
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/lock"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// TestGetAndUpsertPolicySvcConfigReportsServiceNotFound verifies that
+// UnresolvedReason explains that the backing service doesn't exist, when a
+// service-matcher policy references one the service cache has never seen.
+func TestGetAndUpsertPolicySvcConfigReportsServiceNotFound(t *testing.T) {
+	svcCache := k8s.NewServiceCache(fakeDatapath.NewNodeAddressing())
+
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	svcID := k8s.ServiceID{Name: "missing", Namespace: "bar"}
+	config := &LRPConfig{
+		id:           policyID{Name: "lrp", Namespace: "bar"},
+		lrpType:      lrpConfigTypeSvc,
+		frontendType: svcFrontendAll,
+		serviceID:    &svcID,
+	}
+	rpm.storePolicyConfig(*config)
+
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	rpm.getAndUpsertPolicySvcConfig(rpm.policyConfigs[config.id], &svcCache, podStore)
+
+	reason := rpm.policyConfigs[config.id].UnresolvedReason()
+	if !strings.Contains(reason, "not found") {
+		t.Fatalf("expected an unresolved reason mentioning the service wasn't found, got %q", reason)
+	}
+}
+
+// TestGetAndUpsertPolicySvcConfigReportsWrongType verifies that
+// UnresolvedReason explains a service type mismatch, when a service-matcher
+// policy references a service that exists but isn't of a type the policy
+// can redirect (here, NodePort without an additionalFrontendTypes entry for
+// it, leaving only the always-required ClusterIP type to match against).
+func TestGetAndUpsertPolicySvcConfigReportsWrongType(t *testing.T) {
+	svcCache := k8s.NewServiceCache(fakeDatapath.NewNodeAddressing())
+
+	k8sSvc := &slimcorev1.Service{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Spec: slimcorev1.ServiceSpec{
+			ClusterIP: "172.0.20.1",
+			Type:      slimcorev1.ServiceTypeNodePort,
+			Ports: []slimcorev1.ServicePort{
+				{Port: 80, Protocol: slimcorev1.ProtocolTCP},
+			},
+		},
+	}
+	svcCache.UpdateService(k8sSvc, lock.NewStoppableWaitGroup())
+
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	svcID := k8s.ServiceID{Name: "foo", Namespace: "bar"}
+	config := &LRPConfig{
+		id:           policyID{Name: "lrp", Namespace: "bar"},
+		lrpType:      lrpConfigTypeSvc,
+		frontendType: svcFrontendAll,
+		serviceID:    &svcID,
+	}
+	rpm.storePolicyConfig(*config)
+
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	rpm.getAndUpsertPolicySvcConfig(rpm.policyConfigs[config.id], &svcCache, podStore)
+
+	reason := rpm.policyConfigs[config.id].UnresolvedReason()
+	if !strings.Contains(reason, "type") {
+		t.Fatalf("expected an unresolved reason mentioning a type mismatch, got %q", reason)
+	}
+}
+
+// TestGetAndUpsertPolicySvcConfigClearsReasonOnceResolved verifies that a
+// previously unresolved policy's UnresolvedReason is cleared once its
+// backing service resolves to a frontend.
+func TestGetAndUpsertPolicySvcConfigClearsReasonOnceResolved(t *testing.T) {
+	svcCache := k8s.NewServiceCache(fakeDatapath.NewNodeAddressing())
+
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	svcID := k8s.ServiceID{Name: "foo", Namespace: "bar"}
+	config := &LRPConfig{
+		id:           policyID{Name: "lrp", Namespace: "bar"},
+		lrpType:      lrpConfigTypeSvc,
+		frontendType: svcFrontendAll,
+		serviceID:    &svcID,
+	}
+	rpm.storePolicyConfig(*config)
+
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	rpm.getAndUpsertPolicySvcConfig(rpm.policyConfigs[config.id], &svcCache, podStore)
+	if rpm.policyConfigs[config.id].UnresolvedReason() == "" {
+		t.Fatal("expected an unresolved reason before the service exists")
+	}
+
+	k8sSvc := &slimcorev1.Service{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "foo",
+			Namespace: "bar",
+		},
+		Spec: slimcorev1.ServiceSpec{
+			ClusterIP: "172.0.20.1",
+			Type:      slimcorev1.ServiceTypeClusterIP,
+			Ports: []slimcorev1.ServicePort{
+				{Port: 80, Protocol: slimcorev1.ProtocolTCP},
+			},
+		},
+	}
+	svcCache.UpdateService(k8sSvc, lock.NewStoppableWaitGroup())
+
+	rpm.getAndUpsertPolicySvcConfig(rpm.policyConfigs[config.id], &svcCache, podStore)
+	if reason := rpm.policyConfigs[config.id].UnresolvedReason(); reason != "" {
+		t.Fatalf("expected the unresolved reason to clear once the service resolved, got %q", reason)
+	}
+}
@@ -0,0 +1,174 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+func addTestPod(t testing.TB, podStore cache.Store, name, ip string, labels map[string]string) {
+	t.Helper()
+	if err := podStore.Add(&slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			Labels:    labels,
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP:  ip,
+			PodIPs: []slimcorev1.PodIP{{IP: ip}},
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}); err != nil {
+		t.Fatalf("failed to add pod %s: %v", name, err)
+	}
+}
+
+// TestAddRedirectPolicyNoOpDoesNotChurn re-submitting an identical config
+// for an existing policy must not touch the already-programmed LB maps.
+func TestAddRedirectPolicyNoOpDoesNotChurn(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	addTestPod(t, podStore, "foo-pod", "10.1.1.1", map[string]string{"app": "foo"})
+
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+	config := LRPConfig{
+		id:               policyID{Name: "test", Namespace: "default"},
+		lrpType:          lrpConfigTypeAddr,
+		frontendType:     addrFrontendSinglePort,
+		frontendMappings: []*feMapping{feM},
+		backendSelector:  selector,
+		backendPorts: []bePortInfo{
+			{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+		},
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("initial AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	if svc.upsertCount != 1 {
+		t.Fatalf("expected 1 upsert after initial add, got %d", svc.upsertCount)
+	}
+
+	// Re-submit the exact same config: must be recognized as a no-op.
+	if ok, err := rpm.AddRedirectPolicy(config, nil, podStore); !ok || err != nil {
+		t.Fatalf("repeat AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	if svc.upsertCount != 1 {
+		t.Fatalf("expected no-op update to skip reprogramming, got %d total upserts", svc.upsertCount)
+	}
+}
+
+// TestUpdateRedirectPolicySelectorChange verifies that changing a policy's
+// backend selector drops backends for pods that no longer match and adds
+// backends for pods that newly match.
+func TestUpdateRedirectPolicySelectorChange(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	addTestPod(t, podStore, "foo-pod", "10.1.1.1", map[string]string{"app": "foo"})
+	addTestPod(t, podStore, "bar-pod", "10.1.1.2", map[string]string{"app": "bar"})
+
+	id := policyID{Name: "test", Namespace: "default"}
+	newConfig := func(app string) LRPConfig {
+		return LRPConfig{
+			id:           id,
+			lrpType:      lrpConfigTypeAddr,
+			frontendType: addrFrontendSinglePort,
+			frontendMappings: []*feMapping{{
+				feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+			}},
+			backendSelector: api.NewESFromK8sLabelSelector("",
+				&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": app}}),
+			backendPorts: []bePortInfo{
+				{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}},
+			},
+		}
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(newConfig("foo"), nil, podStore); !ok || err != nil {
+		t.Fatalf("initial AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	fem := rpm.policyConfigs[id].frontendMappings[0]
+	if len(fem.backends) != 1 || !fem.backends[0].IP.Equal(net.ParseIP("10.1.1.1")) {
+		t.Fatalf("expected foo-pod's backend to be programmed, got %v", fem.backends)
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(newConfig("bar"), nil, podStore); !ok || err != nil {
+		t.Fatalf("update AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	fem = rpm.policyConfigs[id].frontendMappings[0]
+	if len(fem.backends) != 1 || !fem.backends[0].IP.Equal(net.ParseIP("10.1.1.2")) {
+		t.Fatalf("expected bar-pod's backend after selector change, got %v", fem.backends)
+	}
+}
+
+// TestUpdateRedirectPolicyPortChange verifies that changing a policy's
+// backend port reprograms backends on the new port.
+func TestUpdateRedirectPolicyPortChange(t *testing.T) {
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	addTestPod(t, podStore, "foo-pod", "10.1.1.1", map[string]string{"app": "foo"})
+
+	id := policyID{Name: "test", Namespace: "default"}
+	selector := api.NewESFromK8sLabelSelector("",
+		&slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "foo"}})
+	newConfig := func(port uint16) LRPConfig {
+		return LRPConfig{
+			id:           id,
+			lrpType:      lrpConfigTypeAddr,
+			frontendType: addrFrontendSinglePort,
+			frontendMappings: []*feMapping{{
+				feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+			}},
+			backendSelector: selector,
+			backendPorts: []bePortInfo{
+				{l4Addr: lb.L4Addr{Protocol: lb.TCP, Port: port}},
+			},
+		}
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(newConfig(8080), nil, podStore); !ok || err != nil {
+		t.Fatalf("initial AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	fem := rpm.policyConfigs[id].frontendMappings[0]
+	if len(fem.backends) != 1 || fem.backends[0].Port != 8080 {
+		t.Fatalf("expected a backend on port 8080, got %v", fem.backends)
+	}
+
+	if ok, err := rpm.AddRedirectPolicy(newConfig(9090), nil, podStore); !ok || err != nil {
+		t.Fatalf("update AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+	fem = rpm.policyConfigs[id].frontendMappings[0]
+	if len(fem.backends) != 1 || fem.backends[0].Port != 9090 {
+		t.Fatalf("expected the backend to move to port 9090 after the update, got %v", fem.backends)
+	}
+}
@@ -0,0 +1,81 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"testing"
+
+	"github.com/cilium/cilium/pkg/logging/logfields"
+
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/sirupsen/logrus"
+)
+
+// TestUpsertServiceWithBackendsLogsBackendSet verifies that upserting a
+// pod's backends logs the policy ID, the frontend address, and the list of
+// resolved backend IP:port:protocol tuples being installed.
+func TestUpsertServiceWithBackendsLogsBackendSet(t *testing.T) {
+	hook := &recordingHook{levels: []logrus.Level{logrus.DebugLevel}}
+	log.Logger.AddHook(hook)
+	origLevel := log.Logger.GetLevel()
+	log.Logger.SetLevel(logrus.DebugLevel)
+	defer func() {
+		log.Logger.SetLevel(origLevel)
+		hooks := log.Logger.Hooks[logrus.DebugLevel]
+		for i, h := range hooks {
+			if h == hook {
+				log.Logger.Hooks[logrus.DebugLevel] = append(hooks[:i], hooks[i+1:]...)
+				break
+			}
+		}
+	}()
+
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+
+	config := &LRPConfig{
+		id:      policyID{Name: "test", Namespace: "default"},
+		lrpType: lrpConfigTypeAddr,
+	}
+	feM := &feMapping{
+		feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal),
+	}
+	be := backend{IP: net.ParseIP("10.1.1.1"), L4Addr: lb.L4Addr{Protocol: lb.TCP, Port: 80}}
+
+	pod := podID{Name: "a", Namespace: "default"}
+	rpm.upsertServiceWithBackends(config, feM, pod, "uid-a", true, 0, "", "", []backend{be})
+
+	var entry *logrus.Entry
+	for _, e := range hook.entries {
+		if _, ok := e.Data[logfields.LRPBackends]; ok {
+			entry = e
+			break
+		}
+	}
+	if entry == nil {
+		t.Fatal("expected a debug log entry carrying the backend set")
+	}
+	if got := entry.Data[logfields.LRPName]; got != config.id.Name {
+		t.Fatalf("expected %s to be %q, got %v", logfields.LRPName, config.id.Name, got)
+	}
+	if got := entry.Data[logfields.LRPFrontend]; got != feM.feAddr.String() {
+		t.Fatalf("expected %s to be %q, got %v", logfields.LRPFrontend, feM.feAddr.String(), got)
+	}
+	backends, ok := entry.Data[logfields.LRPBackends].([]string)
+	if !ok || len(backends) != 1 || backends[0] != be.StringWithProtocol() {
+		t.Fatalf("expected %s to be [%q], got %v", logfields.LRPBackends, be.StringWithProtocol(), entry.Data[logfields.LRPBackends])
+	}
+}
@@ -0,0 +1,81 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"fmt"
+
+	"github.com/cilium/cilium/pkg/k8s"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+)
+
+// ValidatePolicy reports whether config would be accepted by
+// AddRedirectPolicy, without storing it or programming any LB state --
+// useful for an admission-webhook-style preflight that wants to reject a
+// bad CiliumLocalRedirectPolicy before it's ever added. It runs the same
+// isValidConfig checks AddRedirectPolicy does (duplicate or colliding
+// frontends, a conflicting service matcher), plus, for a service-type
+// policy, a read-only check that the backing service can currently be
+// resolved against svcCache at all.
+//
+// svcCache may be nil, in which case the service-existence check is skipped
+// -- the same tradeoff getAndUpsertPolicySvcConfig makes when the cache
+// isn't available yet.
+//
+// Unlike AddRedirectPolicy, ValidatePolicy never calls storePolicyConfig or
+// upsertConfig, so the manager's state is guaranteed unchanged regardless of
+// the outcome.
+func (rpm *Manager) ValidatePolicy(config LRPConfig, svcCache *k8s.ServiceCache) error {
+	rpm.mutex.RLock()
+	defer rpm.mutex.RUnlock()
+
+	if rpm.closed {
+		return errManagerClosed
+	}
+
+	if err := rpm.isValidConfig(config); err != nil {
+		return err
+	}
+
+	if config.lrpType == lrpConfigTypeSvc && svcCache != nil {
+		if err := validateServiceExists(svcCache, &config); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateServiceExists confirms, read-only, that config's backing service
+// can currently be resolved against svcCache: that it exists, is one of the
+// service types this policy's additionalFrontendTypes would redirect, and
+// has a ClusterIP assigned. It deliberately stops short of resolving actual
+// per-port frontends the way getAndUpsertPolicySvcConfig does, since that
+// also depends on backend/port matching this function has no part in --
+// out of scope for a pre-mutation validity check.
+func validateServiceExists(svcCache *k8s.ServiceCache, config *LRPConfig) error {
+	svcTypes := append([]lb.SVCType{lb.SVCTypeClusterIP}, config.additionalFrontendTypes...)
+
+	svc, found := svcCache.GetServiceByID(*config.serviceID)
+	switch {
+	case !found:
+		return fmt.Errorf("service %s not found", config.serviceID)
+	case !svcTypeIn(svc.Type, svcTypes):
+		return fmt.Errorf("service %s is of type %s, not one of %v required by this policy", config.serviceID, svc.Type, svcTypes)
+	case svc.FrontendIP == nil:
+		return fmt.Errorf("service %s has no ClusterIP (likely headless)", config.serviceID)
+	}
+	return nil
+}
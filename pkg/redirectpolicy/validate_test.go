@@ -0,0 +1,206 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	fakeDatapath "github.com/cilium/cilium/pkg/datapath/fake"
+	"github.com/cilium/cilium/pkg/k8s"
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	"github.com/cilium/cilium/pkg/lock"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+)
+
+// snapshotManagerState captures every piece of Manager state ValidatePolicy
+// must leave untouched, so tests can compare before and after.
+func snapshotManagerState(rpm *Manager) map[string]interface{} {
+	return map[string]interface{}{
+		"policyConfigs":         rpm.policyConfigs,
+		"policyFrontendsByHash": rpm.policyFrontendsByHash,
+		"policyServices":        rpm.policyServices,
+		"policyPods":            rpm.policyPods,
+	}
+}
+
+// TestValidatePolicyRejectsDuplicateFrontend verifies that ValidatePolicy
+// surfaces the same duplicate-frontend error AddRedirectPolicy would, without
+// storing anything.
+func TestValidatePolicyRejectsDuplicateFrontend(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	before := snapshotManagerState(rpm)
+
+	feAddr := lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal)
+	config := LRPConfig{
+		id:      policyID{Name: "test", Namespace: "default"},
+		lrpType: lrpConfigTypeAddr,
+		frontendMappings: []*feMapping{
+			{feAddr: feAddr.DeepCopy()},
+			{feAddr: feAddr.DeepCopy()},
+		},
+	}
+
+	if err := rpm.ValidatePolicy(config, nil); err == nil {
+		t.Fatal("expected ValidatePolicy to reject a duplicate frontend")
+	}
+
+	if after := snapshotManagerState(rpm); !reflect.DeepEqual(before, after) {
+		t.Fatalf("expected no manager state change, before=%+v after=%+v", before, after)
+	}
+}
+
+// TestValidatePolicyAcceptsValidAddrConfig verifies that ValidatePolicy
+// accepts a well-formed address-matcher config and still changes nothing.
+func TestValidatePolicyAcceptsValidAddrConfig(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	before := snapshotManagerState(rpm)
+
+	config := LRPConfig{
+		id:      policyID{Name: "test", Namespace: "default"},
+		lrpType: lrpConfigTypeAddr,
+		frontendMappings: []*feMapping{
+			{feAddr: lb.NewL3n4Addr(lb.TCP, net.ParseIP("10.0.0.1"), 80, lb.ScopeExternal)},
+		},
+	}
+
+	if err := rpm.ValidatePolicy(config, nil); err != nil {
+		t.Fatalf("expected a valid config to pass, got: %v", err)
+	}
+	if after := snapshotManagerState(rpm); !reflect.DeepEqual(before, after) {
+		t.Fatalf("expected no manager state change, before=%+v after=%+v", before, after)
+	}
+	if _, ok := rpm.policyConfigs[config.id]; ok {
+		t.Fatal("expected ValidatePolicy not to store the config")
+	}
+}
+
+// TestValidatePolicyRejectsMissingService verifies that ValidatePolicy
+// reports an error for a service-matcher policy whose backing service
+// doesn't exist in the provided svcCache, without storing anything or
+// mutating the cache.
+func TestValidatePolicyRejectsMissingService(t *testing.T) {
+	svcCache := k8s.NewServiceCache(fakeDatapath.NewNodeAddressing())
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	before := snapshotManagerState(rpm)
+
+	svcID := k8s.ServiceID{Name: "missing", Namespace: "bar"}
+	config := LRPConfig{
+		id:           policyID{Name: "lrp", Namespace: "bar"},
+		lrpType:      lrpConfigTypeSvc,
+		frontendType: svcFrontendAll,
+		serviceID:    &svcID,
+	}
+
+	err := rpm.ValidatePolicy(config, &svcCache)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected an error mentioning the service wasn't found, got: %v", err)
+	}
+	if after := snapshotManagerState(rpm); !reflect.DeepEqual(before, after) {
+		t.Fatalf("expected no manager state change, before=%+v after=%+v", before, after)
+	}
+}
+
+// TestValidatePolicyAcceptsExistingService verifies that ValidatePolicy
+// accepts a service-matcher policy whose backing ClusterIP service exists,
+// and still changes nothing.
+func TestValidatePolicyAcceptsExistingService(t *testing.T) {
+	svcCache := k8s.NewServiceCache(fakeDatapath.NewNodeAddressing())
+	k8sSvc := &slimcorev1.Service{
+		ObjectMeta: slim_metav1.ObjectMeta{Name: "foo", Namespace: "bar"},
+		Spec: slimcorev1.ServiceSpec{
+			ClusterIP: "172.0.20.1",
+			Type:      slimcorev1.ServiceTypeClusterIP,
+			Ports:     []slimcorev1.ServicePort{{Port: 80, Protocol: slimcorev1.ProtocolTCP}},
+		},
+	}
+	svcCache.UpdateService(k8sSvc, lock.NewStoppableWaitGroup())
+
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+	before := snapshotManagerState(rpm)
+
+	svcID := k8s.ServiceID{Name: "foo", Namespace: "bar"}
+	config := LRPConfig{
+		id:           policyID{Name: "lrp", Namespace: "bar"},
+		lrpType:      lrpConfigTypeSvc,
+		frontendType: svcFrontendAll,
+		serviceID:    &svcID,
+	}
+
+	if err := rpm.ValidatePolicy(config, &svcCache); err != nil {
+		t.Fatalf("expected an existing, matching service to validate, got: %v", err)
+	}
+	if after := snapshotManagerState(rpm); !reflect.DeepEqual(before, after) {
+		t.Fatalf("expected no manager state change, before=%+v after=%+v", before, after)
+	}
+}
+
+// TestValidatePolicySurvivesPolicyServicesDrift verifies that ValidatePolicy
+// doesn't panic when policyServices references a policy ID no longer
+// present in policyConfigs, and leaves the manager's state untouched either
+// way -- this is the same drift condition
+// TestIsValidConfigSurvivesPolicyServicesDrift exercises directly, reached
+// here through the read-only dry-run entry point instead.
+func TestValidatePolicySurvivesPolicyServicesDrift(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+
+	svcID := k8s.ServiceID{Name: "foo", Namespace: "bar"}
+	stale := &LRPConfig{
+		id:           policyID{Name: "stale-lrp", Namespace: "bar"},
+		lrpType:      lrpConfigTypeSvc,
+		frontendType: svcFrontendAll,
+		serviceID:    &svcID,
+	}
+	rpm.storePolicyConfig(*stale)
+	delete(rpm.policyConfigs, stale.id)
+
+	before := snapshotManagerState(rpm)
+
+	config := LRPConfig{
+		id:           policyID{Name: "new-lrp", Namespace: "bar"},
+		lrpType:      lrpConfigTypeSvc,
+		frontendType: svcFrontendAll,
+		serviceID:    &svcID,
+	}
+
+	if err := rpm.ValidatePolicy(config, nil); err != nil {
+		t.Fatalf("expected the stale entry to be ignored rather than rejected, got: %v", err)
+	}
+	if after := snapshotManagerState(rpm); !reflect.DeepEqual(before, after) {
+		t.Fatalf("expected no manager state change, before=%+v after=%+v", before, after)
+	}
+}
+
+// TestValidatePolicySkipsServiceCheckWithoutCache verifies that a nil
+// svcCache skips the service-existence check rather than panicking, mirroring
+// getAndUpsertPolicySvcConfig's handling of an unavailable cache.
+func TestValidatePolicySkipsServiceCheckWithoutCache(t *testing.T) {
+	rpm := NewRedirectPolicyManager(&fakeSvcManager{}, nil)
+
+	svcID := k8s.ServiceID{Name: "missing", Namespace: "bar"}
+	config := LRPConfig{
+		id:           policyID{Name: "lrp", Namespace: "bar"},
+		lrpType:      lrpConfigTypeSvc,
+		frontendType: svcFrontendAll,
+		serviceID:    &svcID,
+	}
+
+	if err := rpm.ValidatePolicy(config, nil); err != nil {
+		t.Fatalf("expected the service-existence check to be skipped without a cache, got: %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"testing"
+
+	v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/option"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	datapathOption "github.com/cilium/cilium/pkg/datapath/option"
+	"k8s.io/client-go/tools/cache"
+)
+
+// dnsStyleSpec returns a CiliumLocalRedirectPolicySpec for a DNS-like
+// workload: a single ANY frontend port that, per this request, should
+// expand into separate TCP and UDP mappings, backed by the workload's own
+// distinct TCP and UDP ports.
+func dnsStyleSpec() v2.CiliumLocalRedirectPolicySpec {
+	return v2.CiliumLocalRedirectPolicySpec{
+		RedirectFrontend: v2.RedirectFrontend{
+			AddressMatcher: &v2.Frontend{
+				IP: "169.254.169.254",
+				ToPorts: []v2.PortInfo{
+					{Port: "53", Protocol: api.ProtoAny},
+				},
+			},
+		},
+		RedirectBackend: v2.RedirectBackend{
+			LocalEndpointSelector: slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "dns"}},
+			ToPorts: []v2.PortInfo{
+				{Port: "53", Name: "dns-udp", Protocol: api.ProtoUDP},
+				{Port: "53", Name: "dns-tcp", Protocol: api.ProtoTCP},
+			},
+		},
+	}
+}
+
+// TestWildcardProtocolFrontendExpandsToTCPAndUDP verifies that an ANY
+// frontend port paired with distinct TCP and UDP backend ports is sanitized
+// into two concrete frontend mappings, and that it doesn't require the veth
+// datapath mode the single-backend-port wildcard still does.
+func TestWildcardProtocolFrontendExpandsToTCPAndUDP(t *testing.T) {
+	origMode := option.Config.DatapathMode
+	option.Config.DatapathMode = datapathOption.DatapathModeIpvlan
+	defer func() { option.Config.DatapathMode = origMode }()
+
+	config, err := getSanitizedLRPConfig("dns-lrp", "default", "", dnsStyleSpec())
+	if err != nil {
+		t.Fatalf("failed to sanitize config: %v", err)
+	}
+
+	if len(config.frontendMappings) != 2 {
+		t.Fatalf("expected the ANY frontend port to expand into 2 mappings, got %d", len(config.frontendMappings))
+	}
+
+	var gotTCP, gotUDP bool
+	for _, feM := range config.frontendMappings {
+		bePort := config.backendPorts[feM.bePortIndex]
+		if feM.feAddr.Protocol != bePort.l4Addr.Protocol {
+			t.Fatalf("expected mapping's frontend and paired backend port to share a protocol, got frontend %v paired with backend %v",
+				feM.feAddr.Protocol, bePort.l4Addr.Protocol)
+		}
+		switch feM.feAddr.Protocol {
+		case lb.TCP:
+			gotTCP = true
+		case lb.UDP:
+			gotUDP = true
+		}
+	}
+	if !gotTCP || !gotUDP {
+		t.Fatalf("expected one TCP and one UDP mapping, got TCP=%v UDP=%v", gotTCP, gotUDP)
+	}
+}
+
+// TestWildcardProtocolFrontendExpansionRequiresBothProtocols verifies that an
+// ANY frontend port paired with more than one backend port, but not exactly
+// one TCP and one UDP port, is rejected rather than silently picking one.
+func TestWildcardProtocolFrontendExpansionRequiresBothProtocols(t *testing.T) {
+	spec := dnsStyleSpec()
+	spec.RedirectBackend.ToPorts = []v2.PortInfo{
+		{Port: "53", Name: "dns-a", Protocol: api.ProtoUDP},
+		{Port: "54", Name: "dns-b", Protocol: api.ProtoUDP},
+	}
+
+	if _, err := getSanitizedLRPConfig("dns-lrp", "default", "", spec); err == nil {
+		t.Fatal("expected an error when the backend doesn't provide exactly one TCP and one UDP port")
+	}
+}
+
+// TestWildcardProtocolFrontendExpansionProgrammed verifies that a DNS-style
+// ANY frontend, once expanded, programs both the TCP and UDP frontends
+// against a matching pod, end to end through AddRedirectPolicy.
+func TestWildcardProtocolFrontendExpansionProgrammed(t *testing.T) {
+	origMode := option.Config.DatapathMode
+	option.Config.DatapathMode = datapathOption.DatapathModeIpvlan
+	defer func() { option.Config.DatapathMode = origMode }()
+
+	config, err := getSanitizedLRPConfig("dns-lrp", "default", "", dnsStyleSpec())
+	if err != nil {
+		t.Fatalf("failed to sanitize config: %v", err)
+	}
+
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "dns-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "dns"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP: "10.0.0.5",
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	podStore.Add(pod)
+
+	ok, err := rpm.AddRedirectPolicy(*config, nil, podStore)
+	if err != nil || !ok {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	if svc.upsertCount != 2 {
+		t.Fatalf("expected both the TCP and UDP frontends to be programmed, got %d upserts", svc.upsertCount)
+	}
+}
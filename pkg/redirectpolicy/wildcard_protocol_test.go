@@ -0,0 +1,115 @@
+//  Copyright 2020 Authors of Cilium
+//
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+package redirectpolicy
+
+import (
+	"testing"
+
+	v2 "github.com/cilium/cilium/pkg/k8s/apis/cilium.io/v2"
+	slimcorev1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/core/v1"
+	slim_metav1 "github.com/cilium/cilium/pkg/k8s/slim/k8s/apis/meta/v1"
+	lb "github.com/cilium/cilium/pkg/loadbalancer"
+	"github.com/cilium/cilium/pkg/option"
+	"github.com/cilium/cilium/pkg/policy/api"
+
+	datapathOption "github.com/cilium/cilium/pkg/datapath/option"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestWildcardProtocolFrontendProgrammed(t *testing.T) {
+	origMode := option.Config.DatapathMode
+	option.Config.DatapathMode = datapathOption.DatapathModeVeth
+	defer func() { option.Config.DatapathMode = origMode }()
+
+	spec := v2.CiliumLocalRedirectPolicySpec{
+		RedirectFrontend: v2.RedirectFrontend{
+			AddressMatcher: &v2.Frontend{
+				IP: "169.254.169.254",
+				ToPorts: []v2.PortInfo{
+					{Port: "53", Protocol: api.ProtoAny},
+				},
+			},
+		},
+		RedirectBackend: v2.RedirectBackend{
+			LocalEndpointSelector: slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "proxy"}},
+			ToPorts: []v2.PortInfo{
+				{Port: "53", Protocol: api.ProtoUDP},
+			},
+		},
+	}
+
+	config, err := getSanitizedLRPConfig("wildcard-lrp", "default", "", spec)
+	if err != nil {
+		t.Fatalf("failed to sanitize config: %v", err)
+	}
+	if config.frontendMappings[0].feAddr.Protocol != lb.NONE {
+		t.Fatalf("expected wildcard frontend protocol, got %v", config.frontendMappings[0].feAddr.Protocol)
+	}
+
+	svc := &fakeSvcManager{}
+	rpm := NewRedirectPolicyManager(svc, nil)
+
+	podStore := cache.NewStore(cache.MetaNamespaceKeyFunc)
+	pod := &slimcorev1.Pod{
+		ObjectMeta: slim_metav1.ObjectMeta{
+			Name:      "proxy-pod",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "proxy"},
+		},
+		Status: slimcorev1.PodStatus{
+			PodIP: "10.0.0.5",
+			Conditions: []slimcorev1.PodCondition{
+				{Type: slimcorev1.PodReady, Status: slimcorev1.ConditionTrue},
+			},
+		},
+	}
+	podStore.Add(pod)
+
+	ok, err := rpm.AddRedirectPolicy(*config, nil, podStore)
+	if err != nil || !ok {
+		t.Fatalf("AddRedirectPolicy failed: ok=%v err=%v", ok, err)
+	}
+
+	if svc.upsertCount != 1 {
+		t.Fatalf("expected the wildcard protocol frontend to be programmed, got %d upserts", svc.upsertCount)
+	}
+}
+
+func TestWildcardProtocolFrontendRequiresVethDatapath(t *testing.T) {
+	origMode := option.Config.DatapathMode
+	option.Config.DatapathMode = datapathOption.DatapathModeIpvlan
+	defer func() { option.Config.DatapathMode = origMode }()
+
+	spec := v2.CiliumLocalRedirectPolicySpec{
+		RedirectFrontend: v2.RedirectFrontend{
+			AddressMatcher: &v2.Frontend{
+				IP: "169.254.169.254",
+				ToPorts: []v2.PortInfo{
+					{Port: "53", Protocol: api.ProtoAny},
+				},
+			},
+		},
+		RedirectBackend: v2.RedirectBackend{
+			LocalEndpointSelector: slim_metav1.LabelSelector{MatchLabels: map[string]string{"app": "proxy"}},
+			ToPorts: []v2.PortInfo{
+				{Port: "53", Protocol: api.ProtoUDP},
+			},
+		},
+	}
+
+	if _, err := getSanitizedLRPConfig("wildcard-lrp", "default", "", spec); err == nil {
+		t.Fatal("expected an error when the datapath mode doesn't support a wildcard protocol frontend")
+	}
+}
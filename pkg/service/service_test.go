@@ -337,6 +337,53 @@ func (m *ManagerTestSuite) TestSyncWithK8sFinished(c *C) {
 	c.Assert(len(m.lbmap.AffinityMatch), Equals, 0)
 }
 
+// TestSyncWithK8sFinishedRetainsLocalRedirectUntilReconciled verifies the
+// restore/reconcile handshake local redirect policies rely on: a
+// SVCTypeLocalRedirect entry restored from the BPF maps must survive
+// SyncWithK8sFinished() as long as something (in practice, the redirect
+// policy manager) re-upserts it first, and must not survive otherwise.
+func (m *ManagerTestSuite) TestSyncWithK8sFinishedRetainsLocalRedirectUntilReconciled(c *C) {
+	p1 := &lb.SVC{
+		Frontend: frontend1,
+		Backends: backends1,
+		Type:     lb.SVCTypeLocalRedirect,
+	}
+	_, id1, err := m.svc.UpsertService(p1)
+	c.Assert(err, IsNil)
+	p2 := &lb.SVC{
+		Frontend: frontend2,
+		Backends: backends2,
+		Type:     lb.SVCTypeLocalRedirect,
+	}
+	_, id2, err := m.svc.UpsertService(p2)
+	c.Assert(err, IsNil)
+	c.Assert(len(m.svc.svcByID), Equals, 2)
+
+	// Restart the agent, but keep the lbmap to restore services from.
+	lbmap := m.svc.lbmap.(*lbmap.LBMockMap)
+	m.svc = NewService(nil)
+	m.svc.lbmap = lbmap
+	err = m.svc.RestoreServices()
+	c.Assert(err, IsNil)
+	c.Assert(len(m.svc.svcByID), Equals, 2)
+
+	// Only p2's local redirect policy has been reconciled by the time
+	// SyncWithK8sFinished() runs; p1's hasn't resolved yet.
+	_, gotID2, err := m.svc.UpsertService(p2)
+	c.Assert(err, IsNil)
+	c.Assert(gotID2, Equals, id2)
+
+	err = m.svc.SyncWithK8sFinished()
+	c.Assert(err, IsNil)
+
+	// p1's LB entry is gone since nothing confirmed it was still needed...
+	_, found := m.svc.svcByID[id1]
+	c.Assert(found, Equals, false)
+	// ...while p2's was retained because its reconciliation ran first.
+	_, found = m.svc.svcByID[id2]
+	c.Assert(found, Equals, true)
+}
+
 func (m *ManagerTestSuite) TestHealthCheckNodePort(c *C) {
 	// Create two frontends, one for LoadBalaner and one for ClusterIP.
 	// This is used to emulate how we get K8s services from the K8s watcher,